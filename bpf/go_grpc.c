@@ -151,7 +151,7 @@ int uprobe_server_handleStream_return(struct pt_regs *ctx) {
     void *stream_ptr = (void *)invocation->stream;
     bpf_dbg_printk("stream_ptr %lx, method pos %lx", stream_ptr, grpc_stream_method_ptr_pos);
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = reserve_event(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         goto done;
@@ -365,7 +365,7 @@ int uprobe_ClientConn_Invoke_return(struct pt_regs *ctx) {
         goto done;
     }
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = reserve_event(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         goto done;
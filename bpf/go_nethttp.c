@@ -36,6 +36,7 @@ typedef struct http_client_data {
     u8  path[PATH_MAX_LEN];
     u8  host[HOST_LEN];
     s64 content_length;
+    u64 req_ptr;
 
     pid_info pid;
 } http_client_data_t;
@@ -117,7 +118,15 @@ int uprobe_readRequestReturns(struct pt_regs *ctx) {
     return 0;
 }
 
-static __always_inline int writeHeaderHelper(struct pt_regs *ctx, u64 req_offset) {
+// returns the address of the http.Header field at the given offset, or null if the offset is unknown
+static __always_inline void *go_headers_addr(void *ptr, u64 offset) {
+    if (!ptr || offset == (u64)(-1)) {
+        return 0;
+    }
+    return ptr + offset;
+}
+
+static __always_inline int writeHeaderHelper(struct pt_regs *ctx, u64 req_offset, u64 resp_header_offset) {
         bpf_dbg_printk("=== uprobe/WriteHeader === ");
     void *goroutine_addr = GOROUTINE_PTR(ctx);
     bpf_dbg_printk("goroutine_addr %lx", goroutine_addr);    
@@ -137,7 +146,7 @@ static __always_inline int writeHeaderHelper(struct pt_regs *ctx, u64 req_offset
         }
     }    
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = reserve_event(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         goto done;
@@ -206,6 +215,11 @@ static __always_inline int writeHeaderHelper(struct pt_regs *ctx, u64 req_offset
 
     trace->status = (u16)(((u64)GO_PARAM2(ctx)) & 0x0ffff);
 
+    read_go_headers(go_headers_addr(req_ptr, req_header_ptr_pos),
+                    request_header_names, request_header_lens, trace->req_headers);
+    read_go_headers(go_headers_addr(resp_ptr, resp_header_offset),
+                    response_header_names, response_header_lens, trace->resp_headers);
+
     // submit the completed trace via ringbuffer
     bpf_ringbuf_submit(trace, get_flags());
 
@@ -217,7 +231,7 @@ done:
 
 SEC("uprobe/WriteHeader")
 int uprobe_WriteHeader(struct pt_regs *ctx) {
-    return writeHeaderHelper(ctx, resp_req_pos);
+    return writeHeaderHelper(ctx, resp_req_pos, resp_handler_header_ptr_pos);
 }
 
 #ifndef NO_HEADER_PROPAGATION
@@ -261,6 +275,7 @@ static __always_inline void roundTripStartHelper(struct pt_regs *ctx) {
     }
 
     bpf_probe_read(&trace.content_length, sizeof(trace.content_length), (void *)(req + content_length_ptr_pos));
+    trace.req_ptr = (u64)req;
 
     // Get path from Request.URL
     void *url_ptr = 0;
@@ -317,7 +332,7 @@ int uprobe_roundTripReturn(struct pt_regs *ctx) {
         goto done;
     }
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = reserve_event(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         goto done;
@@ -345,6 +360,11 @@ int uprobe_roundTripReturn(struct pt_regs *ctx) {
 
     bpf_dbg_printk("status %d, offset %d, resp_ptr %lx", trace->status, status_code_ptr_pos, (u64)resp_ptr);
 
+    read_go_headers(go_headers_addr((void *)data->req_ptr, req_header_ptr_pos),
+                    request_header_names, request_header_lens, trace->req_headers);
+    read_go_headers(go_headers_addr(resp_ptr, resp_header_ptr_pos),
+                    response_header_names, response_header_lens, trace->resp_headers);
+
     // submit the completed trace via ringbuffer
     bpf_ringbuf_submit(trace, get_flags());
 
@@ -426,7 +446,8 @@ SEC("uprobe/http2ResponseWriterStateWriteHeader")
 int uprobe_http2ResponseWriterStateWriteHeader(struct pt_regs *ctx) {
     bpf_dbg_printk("=== uprobe/proc http2 responseWriterState writeHeader === ");
 
-    return writeHeaderHelper(ctx, rws_req_pos);
+    // the response headers of the HTTP/2 server aren't captured
+    return writeHeaderHelper(ctx, rws_req_pos, (u64)(-1));
 }
 
 // HTTP 2.0 client support
@@ -811,7 +832,7 @@ int uprobe_queryDCReturn(struct pt_regs *ctx) {
     }
     bpf_map_delete_elem(&ongoing_sql_queries, &goroutine_addr);
 
-    sql_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(sql_request_trace), 0);
+    sql_request_trace *trace = reserve_event(sizeof(sql_request_trace));
     if (trace) {
         task_pid(&trace->pid);
         trace->type = EVENT_SQL_CLIENT;
@@ -14,6 +14,7 @@
 #define GO_NETHTTP_H
 
 #include "utils.h"
+#include "http_trace.h"
 
 // To be Injected from the user space during the eBPF program load & initialization
 
@@ -26,6 +27,8 @@ volatile const u64 remoteaddr_ptr_pos;
 volatile const u64 host_ptr_pos;
 volatile const u64 content_length_ptr_pos;
 volatile const u64 resp_req_pos;
+volatile const u64 resp_handler_header_ptr_pos;
+volatile const u64 resp_header_ptr_pos;
 volatile const u64 req_header_ptr_pos;
 volatile const u64 io_writer_buf_ptr_pos;
 volatile const u64 io_writer_n_pos;
@@ -42,4 +45,11 @@ volatile const u64 fd_raddr_pos;
 volatile const u64 tcp_addr_port_ptr_pos;
 volatile const u64 tcp_addr_ip_ptr_pos;
 
+// Lowercase names of the HTTP headers to capture, and their lengths. The lengths of the unused
+// positions are zero.
+volatile const u8 request_header_names[HEADERS_MAX_COUNT][HEADER_NAME_MAX_LEN];
+volatile const u8 request_header_lens[HEADERS_MAX_COUNT];
+volatile const u8 response_header_names[HEADERS_MAX_COUNT][HEADER_NAME_MAX_LEN];
+volatile const u8 response_header_lens[HEADERS_MAX_COUNT];
+
 #endif
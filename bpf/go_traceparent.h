@@ -18,6 +18,7 @@
 #include "stdbool.h"
 #include "bpf_dbg.h"
 #include "bpf_helpers.h"
+#include "http_trace.h"
 
 #define MAX_BUCKETS 8
 #define W3C_KEY_LENGTH 11
@@ -28,6 +29,8 @@
 
 #define OFFSET_OF_GO_RUNTIME_HMAP_FIELD_B 9
 #define OFFSET_OF_GO_RUNTIME_HMAP_FIELD_BUCKETS 16
+// tophash values below this one mark the empty or evacuated map cells
+#define GO_RUNTIME_HMAP_MIN_TOP_HASH 5
 
 struct go_string
 {
@@ -167,4 +170,133 @@ static __always_inline void *extract_traceparent_from_req_headers(void *headers_
     return NULL;
 }
 
+// compares the name of a Go map key with the provided lowercase header name. Header names are
+// compared case-insensitively, as the HTTP clients don't always canonicalize them.
+static __always_inline bool header_name_matches(char *key, const volatile u8 *name, u8 len)
+{
+    char current_header_key[HEADER_NAME_MAX_LEN] = {};
+    u32 size = len;
+    if (size > HEADER_NAME_MAX_LEN) {
+        return false;
+    }
+    if (bpf_probe_read(current_header_key, size, key) < 0) {
+        return false;
+    }
+    for (int i = 0; i < HEADER_NAME_MAX_LEN; i++)
+    {
+        if (i >= size)
+        {
+            break;
+        }
+        char c = current_header_key[i];
+        if (c >= 'A' && c <= 'Z')
+        {
+            c += 'a' - 'A';
+        }
+        if (c != name[i])
+        {
+            return false;
+        }
+    }
+    return true;
+}
+
+// Reads the Go http.Header map in headers_ptr_ptr and copies to values the first value of each of
+// the provided headers, truncated to HEADER_VAL_MAX_LEN - 1 bytes. The values of the headers that
+// aren't found are left empty.
+static __always_inline void read_go_headers(void *headers_ptr_ptr,
+                                            const volatile u8 names[HEADERS_MAX_COUNT][HEADER_NAME_MAX_LEN],
+                                            const volatile u8 lens[HEADERS_MAX_COUNT],
+                                            u8 values[HEADERS_MAX_COUNT][HEADER_VAL_MAX_LEN])
+{
+    for (int k = 0; k < HEADERS_MAX_COUNT; k++)
+    {
+        values[k][0] = 0;
+    }
+    if (!headers_ptr_ptr || lens[0] == 0)
+    {
+        return;
+    }
+    void *headers_ptr;
+    long res;
+    res = bpf_probe_read(&headers_ptr, sizeof(headers_ptr), headers_ptr_ptr);
+    if (res < 0 || !headers_ptr)
+    {
+        return;
+    }
+    u64 headers_count = 0;
+    res = bpf_probe_read(&headers_count, sizeof(headers_count), headers_ptr);
+    if (res < 0 || headers_count == 0)
+    {
+        return;
+    }
+    unsigned char log_2_bucket_count;
+    res = bpf_probe_read(&log_2_bucket_count, sizeof(log_2_bucket_count), headers_ptr + OFFSET_OF_GO_RUNTIME_HMAP_FIELD_B);
+    if (res < 0)
+    {
+        return;
+    }
+    u64 bucket_count = 1 << log_2_bucket_count;
+    void *header_buckets;
+    res = bpf_probe_read(&header_buckets, sizeof(header_buckets), headers_ptr + OFFSET_OF_GO_RUNTIME_HMAP_FIELD_BUCKETS);
+    if (res < 0)
+    {
+        return;
+    }
+    u32 map_id = 0;
+    struct map_bucket *map_value = (struct map_bucket *)bpf_map_lookup_elem(&golang_mapbucket_storage_map, &map_id);
+    if (!map_value)
+    {
+        return;
+    }
+
+    for (u64 j = 0; j < MAX_BUCKETS; j++)
+    {
+        if (j >= bucket_count)
+        {
+            break;
+        }
+        res = bpf_probe_read(map_value, sizeof(struct map_bucket), header_buckets + (j * sizeof(struct map_bucket)));
+        if (res < 0)
+        {
+            continue;
+        }
+        for (u64 i = 0; i < 8; i++)
+        {
+            if ((u8)map_value->tophash[i] < GO_RUNTIME_HMAP_MIN_TOP_HASH)
+            {
+                continue;
+            }
+            for (int k = 0; k < HEADERS_MAX_COUNT; k++)
+            {
+                if (lens[k] == 0 || map_value->keys[i].len != lens[k] || values[k][0] != 0)
+                {
+                    continue;
+                }
+                if (!header_name_matches(map_value->keys[i].str, names[k], lens[k]))
+                {
+                    continue;
+                }
+                if (map_value->values[i].len < 1)
+                {
+                    break;
+                }
+                struct go_string value;
+                res = bpf_probe_read(&value, sizeof(value), map_value->values[i].array);
+                if (res < 0)
+                {
+                    break;
+                }
+                u64 size = (u64)value.len < (HEADER_VAL_MAX_LEN - 1) ? (u64)value.len : (HEADER_VAL_MAX_LEN - 1);
+                if (bpf_probe_read(values[k], size, value.str) < 0)
+                {
+                    break;
+                }
+                values[k][size] = 0;
+                break;
+            }
+        }
+    }
+}
+
 #endif
@@ -39,6 +39,15 @@ struct {
     __type(value, recv_args_t);
 } active_recv_args SEC(".maps");
 
+// The socket filter can run while a kprobe is using http_info_mem in the same CPU,
+// so it keeps its own copy of the http info
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, http_info_t);
+    __uint(max_entries, 1);
+} fallback_http_info_mem SEC(".maps");
+
 // Used by accept to grab the sock details
 SEC("kretprobe/sock_alloc")
 int BPF_KRETPROBE(kretprobe_sock_alloc, struct socket *sock) {
@@ -358,18 +367,23 @@ int socket__http_filter(struct __sk_buff *skb) {
 
     u8 packet_type = 0;
     if (is_http(buf, len, &packet_type)) { // we must check tcp_close second, a packet can be a close and a response
-        http_info_t info = {0};
-        info.conn_info = conn;
+        int zero = 0;
+        http_info_t *info = bpf_map_lookup_elem(&fallback_http_info_mem, &zero);
+        if (!info) {
+            return 0;
+        }
+        bpf_memset(info, 0, sizeof(http_info_t));
+        info->conn_info = conn;
 
         if (packet_type == PACKET_TYPE_REQUEST) {
             u32 full_len = skb->len - tcp.hdr_len;
             if (full_len > FULL_BUF_SIZE) {
                 full_len = FULL_BUF_SIZE;
             }
-            read_skb_bytes(skb, tcp.hdr_len, info.buf, full_len);
+            read_skb_bytes(skb, tcp.hdr_len, info->buf, full_len);
             bpf_dbg_printk("=== http_filter len=%d %s ===", len, buf);
             //dbg_print_http_connection_info(&conn);
-            set_fallback_http_info(&info, &conn, skb->len - tcp.hdr_len);
+            set_fallback_http_info(info, &conn, skb->len - tcp.hdr_len);
         }
     }
 
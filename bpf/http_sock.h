@@ -209,7 +209,7 @@ static __always_inline http2_grpc_request_t* empty_http2_info() {
 
 static __always_inline void finish_http(http_info_t *info) {
     if (info->start_monotime_ns != 0 && info->status != 0 && info->pid.host_pid != 0) {
-        http_info_t *trace = bpf_ringbuf_reserve(&events, sizeof(http_info_t), 0);        
+        http_info_t *trace = reserve_event(sizeof(http_info_t));        
         if (trace) {
             bpf_dbg_printk("Sending trace %lx", info);
 
@@ -278,7 +278,7 @@ static __always_inline void process_http_response(http_info_t *info, unsigned ch
     info->status += (buf[RESPONSE_STATUS_POS + 2] - '0');
 }
 
-static __always_inline void handle_http_response(unsigned char *small_buf, pid_connection_info_t *pid_conn, http_info_t *info, int orig_len, u8 direction) {
+static __always_inline void handle_http_response(unsigned char *small_buf, pid_connection_info_t *pid_conn, http_info_t *info, int orig_len, u8 direction, void *u_buf) {
     http_connection_metadata_t *meta = bpf_map_lookup_elem(&filtered_connections, pid_conn);
     http_connection_metadata_t dummy_meta = {};
 
@@ -297,6 +297,7 @@ static __always_inline void handle_http_response(unsigned char *small_buf, pid_c
     }
 
     process_http_response(info, small_buf, meta, orig_len);
+    bpf_probe_read(info->rsp_buf, FULL_BUF_SIZE, u_buf);
     finish_http(info);
 }
 
@@ -338,7 +339,7 @@ static __always_inline void http2_grpc_end(http2_conn_stream_t *stream, http2_gr
     if (prev_info) {
         prev_info->end_monotime_ns = bpf_ktime_get_ns();
 
-        http2_grpc_request_t *trace = bpf_ringbuf_reserve(&events, sizeof(http2_grpc_request_t), 0);        
+        http2_grpc_request_t *trace = reserve_event(sizeof(http2_grpc_request_t));        
         if (trace) {
             bpf_memcpy(trace, prev_info, sizeof(http2_grpc_request_t));
             bpf_probe_read(trace->ret_data, KPROBES_HTTP2_RET_BUF_SIZE, u_buf);
@@ -487,7 +488,7 @@ static __always_inline void handle_buf_with_connection(pid_connection_info_t *pi
             bpf_probe_read(info->buf, FULL_BUF_SIZE, u_buf);
             process_http_request(info, bytes_len);
         } else if (packet_type == PACKET_TYPE_RESPONSE) {
-            handle_http_response(small_buf, pid_conn, info, bytes_len, direction);
+            handle_http_response(small_buf, pid_conn, info, bytes_len, direction, u_buf);
         } else if (still_reading(info)) {
             info->len += bytes_len;
         }     
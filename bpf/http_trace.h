@@ -23,6 +23,9 @@
 #define HOST_LEN 64 // can be a fully qualified DNS name
 #define TRACEPARENT_LEN 55
 #define SQL_MAX_LEN 500
+#define HEADERS_MAX_COUNT 4 // max number of request headers, and of response headers, captured by the Go tracers
+#define HEADER_NAME_MAX_LEN 32
+#define HEADER_VAL_MAX_LEN 64
 
 // Trace of an HTTP call invocation. It is instantiated by the return uprobe and forwarded to the
 // user space through the events ringbuffer.
@@ -43,6 +46,9 @@ typedef struct http_request_trace_t {
     tp_info_t tp;
 
     pid_info pid;
+    // values of the captured headers, null-terminated and in the order of the configured header names
+    u8  req_headers[HEADERS_MAX_COUNT][HEADER_VAL_MAX_LEN];
+    u8  resp_headers[HEADERS_MAX_COUNT][HEADER_VAL_MAX_LEN];
 } __attribute__((packed)) http_request_trace;
 
 typedef struct sql_request_trace_t {
@@ -63,6 +63,7 @@ typedef struct http_info {
     u64 start_monotime_ns;
     u64 end_monotime_ns;
     unsigned char buf[FULL_BUF_SIZE] __attribute__ ((aligned (8))); // ringbuffer memcpy complains unless this is 8 byte aligned
+    unsigned char rsp_buf[FULL_BUF_SIZE] __attribute__ ((aligned (8))); // start of the response, for the headers capture
     u32 len;
     u32 resp_len;
     u16 status;    
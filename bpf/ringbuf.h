@@ -27,6 +27,21 @@ struct {
     __uint(pinning, LIBBPF_PIN_BY_NAME);
 } events SEC(".maps");
 
+// Indexes of the beyla_stats counters. They need to line up with some Go identifiers:
+// statRingbufReserveFailed, statsCount
+#define STAT_RINGBUF_RESERVE_FAILED 0
+#define STATS_COUNT                 1
+
+// Per-CPU counters of the internal events of the eBPF programs (e.g. discarded events). They are
+// periodically read from the user space and reported as internal metrics.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, STATS_COUNT);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} beyla_stats SEC(".maps");
+
 // To be Injected from the user space during the eBPF program load & initialization
 volatile const u32 wakeup_data_bytes;
 
@@ -44,4 +59,24 @@ static __always_inline long get_flags()
 	return sz >= wakeup_data_bytes ? BPF_RB_FORCE_WAKEUP : BPF_RB_NO_WAKEUP;
 }
 
+static __always_inline void stats_inc(u32 stat)
+{
+    u64 *count = bpf_map_lookup_elem(&beyla_stats, &stat);
+    if (count) {
+        // per-CPU value, so no atomic increment is needed
+        (*count)++;
+    }
+}
+
+// reserve_event reserves space in the events ring buffer, accounting the
+// reservation failures when the ring buffer is full
+static __always_inline void *reserve_event(u64 size)
+{
+    void *event = bpf_ringbuf_reserve(&events, size, 0);
+    if (!event) {
+        stats_inc(STAT_RINGBUF_RESERVE_FAILED);
+    }
+    return event;
+}
+
 #endif
\ No newline at end of file
@@ -8,6 +8,8 @@
 char __license[] SEC("license") = "Dual MIT/GPL";
 
 #define WATCH_BIND 0x1
+#define WATCH_EXEC 0x2
+#define WATCH_EXIT 0x3
 
 typedef struct watch_info {
     u64 flags; // Must be fist we use it to tell what kind of packet we have on the ring buffer
@@ -48,4 +50,39 @@ int kprobe_sys_bind(struct pt_regs *ctx) {
     }
 
     return 0;
-}
\ No newline at end of file
+}
+
+static __always_inline void submit_process_event(u64 flags, u32 pid) {
+    watch_info_t *trace = bpf_ringbuf_reserve(&watch_events, sizeof(watch_info_t), 0);
+    if (trace) {
+        trace->flags = flags;
+        trace->payload = pid;
+        bpf_dbg_printk("Process event %d, pid %d", trace->flags, trace->payload);
+
+        bpf_ringbuf_submit(trace, 0);
+    }
+}
+
+SEC("tracepoint/sched/sched_process_exec")
+int tracepoint_sched_process_exec(void *ctx) {
+    u64 id = bpf_get_current_pid_tgid();
+
+    submit_process_event(WATCH_EXEC, id >> 32);
+
+    return 0;
+}
+
+SEC("tracepoint/sched/sched_process_exit")
+int tracepoint_sched_process_exit(void *ctx) {
+    u64 id = bpf_get_current_pid_tgid();
+    u32 pid = id >> 32;
+
+    // only the exit of the main thread means that the process has finished
+    if (pid != (u32)id) {
+        return 0;
+    }
+
+    submit_process_event(WATCH_EXIT, pid);
+
+    return 0;
+}
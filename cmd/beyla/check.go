@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/grafana/beyla/pkg/components"
+)
+
+// runCheck implements the "beyla check" command: it inspects the host environment and prints
+// which Beyla features would work, without loading any eBPF program.
+// It exits with an error code if any check fails.
+func runCheck(args []string) {
+	// the logs are sent to the standard error, to avoid mixing them with the printed report
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	})))
+
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the configuration file")
+	_ = flags.Parse(args)
+
+	if cfg := os.Getenv("BEYLA_CONFIG_PATH"); cfg != "" {
+		configPath = &cfg
+	}
+
+	config := loadConfig(configPath)
+
+	ok, err := components.PrintEnvironmentCheck(config, os.Stdout)
+	if err != nil {
+		slog.Error("can't print environment check", "error", err)
+		os.Exit(-1)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/grafana/beyla/pkg/components"
+)
+
+// runConfig implements the "beyla config" commands:
+//   - "beyla config print" prints the configuration resulting from the defaults, the configuration
+//     file and the environment variables, with the secret values masked.
+//   - "beyla config schema" prints the JSON Schema of the YAML configuration file.
+func runConfig(args []string) {
+	// the logs are sent to the standard error, to avoid mixing them with the printed documents
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	})))
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: beyla config print|schema [flags]")
+		os.Exit(-1)
+	}
+	switch args[0] {
+	case "print":
+		flags := flag.NewFlagSet("config print", flag.ExitOnError)
+		configPath := flags.String("config", "", "path to the configuration file")
+		_ = flags.Parse(args[1:])
+
+		if cfg := os.Getenv("BEYLA_CONFIG_PATH"); cfg != "" {
+			configPath = &cfg
+		}
+
+		if err := components.PrintConfig(loadConfig(configPath), os.Stdout); err != nil {
+			slog.Error("can't print configuration", "error", err)
+			os.Exit(-1)
+		}
+	case "schema":
+		flags := flag.NewFlagSet("config schema", flag.ExitOnError)
+		_ = flags.Parse(args[1:])
+
+		if err := components.PrintConfigSchema(os.Stdout); err != nil {
+			slog.Error("can't print configuration schema", "error", err)
+			os.Exit(-1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q. Usage: beyla config print|schema [flags]\n", args[0])
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/grafana/beyla/pkg/components"
+)
+
+// runDiscover implements the "beyla discover" command: it lists the running processes and prints
+// which of them would be instrumented with the provided configuration, without loading any eBPF program.
+func runDiscover(args []string) {
+	// the logs are sent to the standard error, to avoid mixing them with the printed table
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	})))
+
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the configuration file")
+	matchedOnly := flags.Bool("matched", false, "only list the processes that match any selection criteria")
+	_ = flags.Parse(args)
+
+	if cfg := os.Getenv("BEYLA_CONFIG_PATH"); cfg != "" {
+		configPath = &cfg
+	}
+
+	config := loadConfig(configPath)
+	// only the discovery configuration is validated, as no metrics nor traces are exported
+	if err := config.Discovery.Validate(); err != nil {
+		slog.Error("wrong Beyla configuration", "error", err)
+		os.Exit(-1)
+	}
+
+	if err := components.PrintDiscoveredProcesses(context.Background(), config, os.Stdout, *matchedOnly); err != nil {
+		slog.Error("can't print discovered processes", "error", err)
+		os.Exit(-1)
+	}
+}
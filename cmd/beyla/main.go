@@ -21,11 +21,21 @@ import (
 )
 
 func main() {
-	lvl := slog.LevelVar{}
-	lvl.Set(slog.LevelInfo)
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: &lvl,
-	})))
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "discover":
+			runDiscover(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		}
+	}
+
+	components.SetupLogger(os.Stdout)
 
 	slog.Info("Grafana Beyla", "Version", buildinfo.Version, "Revision", buildinfo.Revision, "OpenTelemetry SDK Version", otelsdk.Version())
 
@@ -47,8 +57,8 @@ func main() {
 		os.Exit(-1)
 	}
 
-	if err := lvl.UnmarshalText([]byte(config.LogLevel)); err != nil {
-		slog.Error("unknown log level specified, choices are [DEBUG, INFO, WARN, ERROR]", err)
+	if err := components.ConfigureLogger(config); err != nil {
+		slog.Error("can't configure the logger", "error", err)
 		os.Exit(-1)
 	}
 
@@ -65,7 +75,7 @@ func main() {
 	// child process isn't found.
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	components.RunBeyla(ctx, config)
+	components.RunBeylaWithReloads(ctx, config, reloadOnSIGHUP(ctx, *configPath))
 
 	if gc := os.Getenv("GOCOVERDIR"); gc != "" {
 		slog.Info("Waiting 1s to collect coverage data...")
@@ -74,19 +84,27 @@ func main() {
 }
 
 func loadConfig(configPath *string) *beyla.Config {
-	var configReader io.ReadCloser
-	if configPath != nil && *configPath != "" {
-		var err error
-		if configReader, err = os.Open(*configPath); err != nil {
-			slog.Error("can't open "+*configPath, err)
-			os.Exit(-1)
-		}
-		defer configReader.Close()
+	var path string
+	if configPath != nil {
+		path = *configPath
 	}
-	config, err := beyla.LoadConfig(configReader)
+	config, err := readConfig(path)
 	if err != nil {
-		slog.Error("wrong configuration", err)
+		slog.Error("wrong configuration", "error", err)
 		os.Exit(-1)
 	}
 	return config
 }
+
+// readConfig from the provided file path, if not empty, and the environment variables
+func readConfig(configPath string) (*beyla.Config, error) {
+	var configReader io.ReadCloser
+	if configPath != "" {
+		var err error
+		if configReader, err = os.Open(configPath); err != nil {
+			return nil, fmt.Errorf("can't open %s: %w", configPath, err)
+		}
+		defer configReader.Close()
+	}
+	return beyla.LoadConfig(configReader)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+// reloadOnSIGHUP reads again the configuration each time the process receives a SIGHUP signal,
+// and forwards it through the returned channel if it is valid.
+func reloadOnSIGHUP(ctx context.Context, configPath string) <-chan *beyla.Config {
+	log := slog.With("component", "beyla.ConfigReloader")
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	reloads := make(chan *beyla.Config, 1)
+	go func() {
+		defer signal.Stop(hups)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hups:
+				log.Info("received SIGHUP. Reloading configuration", "path", configPath)
+				config, err := readConfig(configPath)
+				if err == nil {
+					err = config.Validate()
+				}
+				if err != nil {
+					log.Error("can't reload configuration. Keeping the current one", "error", err)
+					continue
+				}
+				select {
+				case reloads <- config:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return reloads
+}
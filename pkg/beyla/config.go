@@ -3,17 +3,23 @@ package beyla
 import (
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/caarlos0/env/v9"
 	otelconsumer "go.opentelemetry.io/collector/consumer"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/beyla/pkg/internal/admin"
+	"github.com/grafana/beyla/pkg/internal/configschema"
+	"github.com/grafana/beyla/pkg/internal/docker"
 	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
 	"github.com/grafana/beyla/pkg/internal/export/debug"
 	"github.com/grafana/beyla/pkg/internal/export/otel"
 	"github.com/grafana/beyla/pkg/internal/export/prom"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/logging"
+	"github.com/grafana/beyla/pkg/internal/privsep"
 	"github.com/grafana/beyla/pkg/internal/traces"
 	"github.com/grafana/beyla/pkg/internal/transform"
 	"github.com/grafana/beyla/pkg/services"
@@ -32,10 +38,14 @@ const (
 var DefaultConfig = Config{
 	ChannelBufferLen: 10,
 	LogLevel:         "INFO",
+	LogFormat:        logging.FormatText,
 	EBPF: ebpfcommon.TracerConfig{
 		BatchLength:  100,
 		BatchTimeout: time.Second,
 		BpfBaseDir:   "/var/run/beyla",
+		HTTPHeaders: ebpfcommon.HTTPHeadersConfig{
+			MetricLabelValues: 10,
+		},
 	},
 	Grafana: otel.GrafanaConfig{
 		OTLP: otel.GrafanaOTLP{
@@ -79,9 +89,19 @@ var DefaultConfig = Config{
 			Enable:               transform.EnabledDefault,
 			InformersSyncTimeout: 30 * time.Second,
 		},
+		Container: docker.Config{
+			Runtime: docker.RuntimeDocker,
+			Timeout: docker.DefaultTimeout,
+		},
+	},
+	Admin: admin.Config{
+		StallThreshold: time.Minute,
 	},
 	Routes:       &transform.RoutesConfig{},
 	NetworkFlows: defaultNetworkConfig,
+	Discovery: services.DiscoveryConfig{
+		ServiceEnvPrecedence: services.EnvPrecedenceDisabled,
+	},
 }
 
 type Config struct {
@@ -96,7 +116,9 @@ type Config struct {
 
 	Attributes Attributes `yaml:"attributes"`
 	// Routes is an optional node. If not set, data will be directly forwarded to exporters.
-	Routes     *transform.RoutesConfig `yaml:"routes"`
+	Routes *transform.RoutesConfig `yaml:"routes"`
+	// Filters is an optional list of expressions to discard spans from the metrics and/or traces
+	Filters    transform.FiltersConfig `yaml:"filter"`
 	Metrics    otel.MetricsConfig      `yaml:"otel_metrics_export"`
 	Traces     otel.TracesConfig       `yaml:"otel_traces_export"`
 	Prometheus prom.PrometheusConfig   `yaml:"prometheus_export"`
@@ -119,6 +141,18 @@ type Config struct {
 	Discovery services.DiscoveryConfig `yaml:"discovery"`
 
 	LogLevel string `yaml:"log_level" env:"BEYLA_LOG_LEVEL"`
+	// LogFormat can be "text" or "json"
+	LogFormat string `yaml:"log_format" env:"BEYLA_LOG_FORMAT"`
+	// LogLevels overrides the LogLevel for the components whose name, as specified in
+	// the "component" log attribute, matches the map keys
+	LogLevels map[string]string `yaml:"log_levels" env:"BEYLA_LOG_LEVELS"`
+
+	// Admin HTTP API to inspect the state of the instrumented processes
+	Admin admin.Config `yaml:"admin"`
+
+	// PrivilegeSeparation splits Beyla in a privileged process that loads the eBPF programs, and
+	// an unprivileged process that reads and exports their events
+	PrivilegeSeparation privsep.Config `yaml:"privilege_separation"`
 
 	// From this comment, the properties below will remain undocumented, as they
 	// are useful for development purposes. They might be helpful for customer support.
@@ -149,6 +183,7 @@ func (t TracesReceiverConfig) Enabled() bool {
 type Attributes struct {
 	Kubernetes transform.KubernetesDecorator `yaml:"kubernetes"`
 	InstanceID traces.InstanceIDConfig       `yaml:"instance_id"`
+	Container  docker.Config                 `yaml:"container"`
 }
 
 type ConfigError string
@@ -159,8 +194,8 @@ func (e ConfigError) Error() string {
 
 // nolint:cyclop
 func (c *Config) Validate() error {
-	if err := c.Discovery.Services.Validate(); err != nil {
-		return ConfigError(fmt.Sprintf("error in services YAML property: %s", err.Error()))
+	if err := c.Discovery.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in discovery YAML section: %s", err.Error()))
 	}
 	if !c.Enabled(FeatureNetO11y) && !c.Enabled(FeatureAppO11y) {
 		return ConfigError("missing at least one of BEYLA_NETWORK_METRICS, BEYLA_EXECUTABLE_NAME or BEYLA_OPEN_PORT property")
@@ -168,9 +203,48 @@ func (c *Config) Validate() error {
 	if (c.Port.Len() > 0 || c.Exec.IsSet() || len(c.Discovery.Services) > 0) && c.Discovery.SystemWide {
 		return ConfigError("you can't use BEYLA_SYSTEM_WIDE if any of BEYLA_EXECUTABLE_NAME, BEYLA_OPEN_PORT or services (YAML) are set")
 	}
+	if err := c.Attributes.Container.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in attributes.container YAML section: %s", err.Error()))
+	}
+	if c.Discovery.K8sAnnotations && !c.Attributes.Kubernetes.Enabled() {
+		return ConfigError("discovery.k8s_annotations requires enabling the Kubernetes decoration (BEYLA_KUBE_METADATA_ENABLE)")
+	}
+	for i := range c.Discovery.Services {
+		profile := c.Discovery.Services[i].ExporterProfile
+		if profile == "" {
+			continue
+		}
+		_, inMetrics := c.Metrics.Profiles[profile]
+		_, inTraces := c.Traces.Profiles[profile]
+		if !inMetrics && !inTraces {
+			return ConfigError(fmt.Sprintf("discovery.services[%d] references an undefined exporter profile: %q."+
+				" It must be defined in the otel_metrics_export or otel_traces_export profiles sections", i, profile))
+		}
+	}
+	if c.LogFormat != logging.FormatText && c.LogFormat != logging.FormatJSON {
+		return ConfigError(fmt.Sprintf("invalid log_format (BEYLA_LOG_FORMAT) %q. Valid values are %q and %q",
+			c.LogFormat, logging.FormatText, logging.FormatJSON))
+	}
+	if _, _, err := logging.ParseLevels(c.LogLevel, c.LogLevels); err != nil {
+		return ConfigError(err.Error())
+	}
+	if err := c.PrivilegeSeparation.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in privilege_separation YAML section: %s", err.Error()))
+	}
+	if c.PrivilegeSeparation.Enabled() && c.Discovery.SystemWide {
+		return ConfigError("privilege separation (BEYLA_PRIVILEGE_SEPARATION_MODE) can't be used with" +
+			" system-wide instrumentation (BEYLA_SYSTEM_WIDE)")
+	}
+	if c.PrivilegeSeparation.Mode == privsep.ModeExporter && c.Enabled(FeatureNetO11y) {
+		return ConfigError("network metrics (BEYLA_NETWORK_METRICS) require eBPF privileges, so they must be" +
+			" enabled in the loader process instead of the exporter process")
+	}
 	if c.EBPF.BatchLength == 0 {
 		return ConfigError("BEYLA_BPF_BATCH_LENGTH must be at least 1")
 	}
+	if err := c.EBPF.HTTPHeaders.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in ebpf.http_headers YAML section: %s", err.Error()))
+	}
 
 	if c.Enabled(FeatureNetO11y) && !c.Grafana.OTLP.MetricsEnabled() && !c.Metrics.Enabled() && !c.NetworkFlows.Print {
 		return ConfigError("enabling network metrics requires to enable at least the OpenTelemetry" +
@@ -179,7 +253,8 @@ func (c *Config) Validate() error {
 			" purposes, you can also set BEYLA_NETWORK_PRINT_FLOWS=true")
 	}
 
-	if c.Enabled(FeatureAppO11y) && !c.Noop.Enabled() && !c.Printer.Enabled() &&
+	// the loader process hands over the events to the exporter process, so it doesn't need any exporter
+	if c.Enabled(FeatureAppO11y) && c.PrivilegeSeparation.Mode != privsep.ModeLoader && !c.Noop.Enabled() && !c.Printer.Enabled() &&
 		!c.Grafana.OTLP.MetricsEnabled() && !c.Grafana.OTLP.TracesEnabled() &&
 		!c.Metrics.Enabled() && !c.Traces.Enabled() &&
 		!c.Prometheus.Enabled() {
@@ -187,6 +262,23 @@ func (c *Config) Validate() error {
 			" grafana, otel_metrics_export, otel_traces_export or prometheus_export")
 	}
 
+	if c.Admin.Enabled() && c.Admin.StallThreshold <= c.EBPF.BatchTimeout {
+		return ConfigError("admin.stall_threshold (BEYLA_ADMIN_STALL_THRESHOLD) must be greater than" +
+			" ebpf.batch_timeout (BEYLA_BPF_BATCH_TIMEOUT)")
+	}
+
+	if c.InternalMetrics.OTEL.Enabled {
+		if c.InternalMetrics.Prometheus.Port != 0 {
+			return ConfigError("internal metrics can't be exported via both Prometheus and OpenTelemetry." +
+				" Please choose one of them")
+		}
+		if !c.Grafana.OTLP.MetricsEnabled() && !c.Metrics.Enabled() {
+			return ConfigError("exporting internal metrics via OpenTelemetry requires to enable the OpenTelemetry" +
+				" metrics exporter: grafana or otel_metrics_export sections in the YAML configuration file; or the" +
+				" OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_METRICS_ENDPOINT environment variables")
+		}
+	}
+
 	if c.Enabled(FeatureNetO11y) {
 		return c.NetworkFlows.Validate(c.Attributes.Kubernetes.Enabled())
 	}
@@ -200,11 +292,22 @@ func (c *Config) Enabled(feature Feature) bool {
 	case FeatureNetO11y:
 		return c.NetworkFlows.Enable
 	case FeatureAppO11y:
-		return c.Port.Len() > 0 || c.Exec.IsSet() || len(c.Discovery.Services) > 0 || c.Discovery.SystemWide
+		return c.Port.Len() > 0 || c.Exec.IsSet() || len(c.Discovery.Services) > 0 || c.Discovery.SystemWide ||
+			c.Discovery.K8sAnnotations
 	}
 	return false
 }
 
+var configSchema = sync.OnceValue(func() *configschema.Schema {
+	return configschema.Generate(Config{})
+})
+
+// ConfigSchema returns the JSON Schema of the YAML configuration. LoadConfig uses it to reject
+// the unknown YAML keys.
+func ConfigSchema() *configschema.Schema {
+	return configSchema()
+}
+
 // LoadConfig overrides configuration in the following order (from less to most priority)
 // 1 - Default configuration (defaultConfig variable)
 // 2 - Contents of the provided file reader (nillable)
@@ -216,9 +319,19 @@ func LoadConfig(file io.Reader) (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("reading YAML configuration: %w", err)
 		}
-		if err := yaml.Unmarshal(cfgBuf, &cfg); err != nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(cfgBuf, &doc); err != nil {
 			return nil, fmt.Errorf("parsing YAML configuration: %w", err)
 		}
+		if err := ConfigSchema().Validate(&doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML configuration: %w", err)
+		}
+		// an empty document has no nodes to decode
+		if len(doc.Content) > 0 {
+			if err := doc.Decode(&cfg); err != nil {
+				return nil, fmt.Errorf("parsing YAML configuration: %w", err)
+			}
+		}
 	}
 	if err := env.Parse(&cfg); err != nil {
 		return nil, fmt.Errorf("reading env vars: %w", err)
@@ -14,7 +14,12 @@ var kernelVersion = ebpfcommon.KernelVersion
 // CheckOSSupport returns an error if the running operating system does not support
 // the minimum required Beyla features.
 func CheckOSSupport() error {
-	major, minor := kernelVersion()
+	return CheckKernelVersion(kernelVersion())
+}
+
+// CheckKernelVersion returns an error if the provided kernel version is older than the
+// minimum version that is required by Beyla.
+func CheckKernelVersion(major, minor int) error {
 	if major < minKernMaj || (major == minKernMaj && minor < minKernMin) {
 		return fmt.Errorf("kernel version %d.%d not supported. Minimum required version is %d.%d",
 			major, minor, minKernMaj, minKernMin)
@@ -14,6 +14,12 @@ import (
 // RunBeyla in the foreground process. This is a blocking function and won't exit
 // until both the AppO11y and NetO11y components end
 func RunBeyla(ctx context.Context, cfg *beyla.Config) {
+	RunBeylaWithReloads(ctx, cfg, nil)
+}
+
+// RunBeylaWithReloads is like RunBeyla, but the discovery criteria of the AppO11y component are
+// replaced by the ones of each configuration received from the reloads channel
+func RunBeylaWithReloads(ctx context.Context, cfg *beyla.Config, reloads <-chan *beyla.Config) {
 	wg := sync.WaitGroup{}
 	app := cfg.Enabled(beyla.FeatureAppO11y)
 	if app {
@@ -27,7 +33,7 @@ func RunBeyla(ctx context.Context, cfg *beyla.Config) {
 	if app {
 		go func() {
 			defer wg.Done()
-			setupAppO11y(ctx, cfg)
+			setupAppO11y(ctx, cfg, reloads)
 		}()
 	}
 	if net {
@@ -39,14 +45,13 @@ func RunBeyla(ctx context.Context, cfg *beyla.Config) {
 	wg.Wait()
 }
 
-func setupAppO11y(ctx context.Context, config *beyla.Config) {
+func setupAppO11y(ctx context.Context, config *beyla.Config, reloads <-chan *beyla.Config) {
 	slog.Info("starting Beyla in Application Observability mode")
-	// TODO: when we split Beyla in two processes with different permissions, this code can be split:
-	// in two parts:
-	// 1st process (privileged) - Invoke FindTarget, which also mounts the BPF maps
-	// 2nd executable (unprivileged) - Invoke ReadAndForward, receiving the BPF map mountpoint as argument
+	// When the privilege separation is enabled, the work of the Instrumenter is split in two processes:
+	// loader (privileged) - FindAndInstrument loads the eBPF programs and hands over their events
+	// exporter (unprivileged) - FindAndInstrument receives the events, and ReadAndForward exports them
 
-	instr := appolly.New(config)
+	instr := appolly.New(config).WithDiscoveryReloads(reloads)
 	if err := instr.FindAndInstrument(ctx); err != nil {
 		slog.Error("Beyla couldn't find target process", "error", err)
 		os.Exit(-1)
@@ -0,0 +1,30 @@
+package components
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/envcheck"
+)
+
+// PrintEnvironmentCheck inspects the kernel version, BTF availability, lockdown mode, capabilities,
+// BPF filesystem and kernel probes that are required by Beyla, and prints a report describing which
+// features would work in the current environment. It returns false if any feature that is
+// required by the provided configuration won't work.
+func PrintEnvironmentCheck(cfg *beyla.Config, out io.Writer) (bool, error) {
+	report := envcheck.Run(cfg)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAILS")
+	for _, c := range report.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Details)
+	}
+	fmt.Fprintln(tw, "\t\t")
+	fmt.Fprintln(tw, "FEATURE\tSTATUS\tDETAILS")
+	for _, f := range report.Features {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Status, f.Details)
+	}
+	return !report.Failed(), tw.Flush()
+}
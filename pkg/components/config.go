@@ -0,0 +1,34 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+// PrintConfig prints, in YAML format, the provided configuration, with the values of the
+// secret properties masked.
+func PrintConfig(cfg *beyla.Config, out io.Writer) error {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return fmt.Errorf("encoding configuration: %w", err)
+	}
+	beyla.ConfigSchema().Mask(&doc)
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("printing configuration: %w", err)
+	}
+	return enc.Close()
+}
+
+// PrintConfigSchema prints the JSON Schema of the YAML configuration
+func PrintConfigSchema(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(beyla.ConfigSchema())
+}
@@ -0,0 +1,59 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/discover"
+)
+
+// PrintDiscoveredProcesses lists the running processes and prints a table describing which of them
+// would be instrumented with the provided configuration, without loading any eBPF program.
+// If matchedOnly is true, the processes that do not match any selection criteria are not printed.
+func PrintDiscoveredProcesses(ctx context.Context, cfg *beyla.Config, out io.Writer, matchedOnly bool) error {
+	results, err := discover.DryRun(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("can't discover processes: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PID\tEXECUTABLE\tOPEN PORTS\tCRITERIA\tSERVICE\tTYPE\tREASON")
+	for i := range results {
+		r := &results[i]
+		if matchedOnly && r.CriteriaIndex < 0 && !r.Instrumented {
+			continue
+		}
+		criteria, service, instrType := "-", "-", "-"
+		if r.CriteriaIndex >= 0 {
+			criteria = strconv.Itoa(r.CriteriaIndex)
+		}
+		if r.Instrumented {
+			service = r.ServiceName
+			instrType = r.Type.String()
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.PID, r.ExePath, portsString(r.OpenPorts), criteria, service, instrType, r.Reason)
+	}
+	return tw.Flush()
+}
+
+func portsString(ports []uint32) string {
+	if len(ports) == 0 {
+		return "-"
+	}
+	// the same port might be reported for multiple connections
+	ports = slices.Clone(ports)
+	slices.Sort(ports)
+	ports = slices.Compact(ports)
+	strs := make([]string, 0, len(ports))
+	for _, p := range ports {
+		strs = append(strs, strconv.Itoa(int(p)))
+	}
+	return strings.Join(strs, ",")
+}
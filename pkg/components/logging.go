@@ -0,0 +1,35 @@
+package components
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/logging"
+)
+
+// SetupLogger sets, as the default slog logger, a logger that writes into the provided output
+// in text format and INFO level, until ConfigureLogger is invoked.
+func SetupLogger(out io.Writer) {
+	slog.SetDefault(slog.New(logging.New(out)))
+}
+
+// ConfigureLogger sets the log format, as well as the global and per-component log levels,
+// of the logger that was previously set by SetupLogger. The changes also apply to the
+// loggers that were already derived from it.
+func ConfigureLogger(cfg *beyla.Config) error {
+	handler := logging.Default()
+	if handler == nil {
+		return errors.New("the default logger has not been set up by SetupLogger")
+	}
+	global, components, err := logging.ParseLevels(cfg.LogLevel, cfg.LogLevels)
+	if err != nil {
+		return err
+	}
+	if err := handler.SetFormat(cfg.LogFormat); err != nil {
+		return err
+	}
+	handler.SetLevels(global, components)
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package admin provides an HTTP API to inspect the internal state of a running Beyla instance
+package admin
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/route"
+)
+
+const (
+	// ProcessesPath is the path of the admin HTTP API that lists the instrumented processes
+	ProcessesPath = "/api/processes"
+	// RoutesPath is the path of the admin HTTP API that lists the routes learned by the
+	// adaptive routes decorator
+	RoutesPath = "/api/routes"
+)
+
+// Config of the admin HTTP API
+type Config struct {
+	// Port where the admin HTTP API is listening. It can be the same port as the Prometheus exporter
+	// or the internal metrics. Zero means that the admin HTTP API is disabled.
+	Port int `yaml:"port" env:"BEYLA_ADMIN_PORT"`
+	// StallThreshold is the time after which the liveness endpoint fails if the eBPF events
+	// forwarding stalls. It must be greater than the eBPF batch timeout.
+	StallThreshold time.Duration `yaml:"stall_threshold" env:"BEYLA_ADMIN_STALL_THRESHOLD"`
+	// DebugEndpoint allows changing the debug settings through the debug endpoint. The admin HTTP
+	// API isn't authenticated, so it is disabled by default and the debug endpoint is read-only.
+	DebugEndpoint bool `yaml:"debug_endpoint" env:"BEYLA_ADMIN_DEBUG_ENDPOINT"`
+}
+
+// Enabled returns whether the admin HTTP API must be exposed
+func (c *Config) Enabled() bool {
+	return c.Port != 0
+}
+
+func alog() *slog.Logger {
+	return slog.With("component", "admin.Registry")
+}
+
+// Process describes an instrumented process
+type Process struct {
+	PID       int32    `json:"pid"`
+	ChildPIDs []uint32 `json:"child_pids,omitempty"`
+	ExePath   string   `json:"exe_path"`
+	Service   Service  `json:"service"`
+	Type      string   `json:"type"`
+	// MatchedBy describes the discovery criteria that selected the process
+	MatchedBy string            `json:"matched_by"`
+	Tracer    ebpf.TracerStatus `json:"tracer"`
+}
+
+// Service is the JSON representation of the svc.ID of an instrumented process
+type Service struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	Instance        string            `json:"instance,omitempty"`
+	AutoName        bool              `json:"auto_name"`
+	ExporterProfile string            `json:"exporter_profile,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// Status of the instrumented processes, as returned by the admin HTTP API
+type Status struct {
+	Processes []Process `json:"processes"`
+	// PIDsFilter contains the PIDs whose traces are currently forwarded, grouped by PID namespace.
+	// It is empty in system-wide mode, as all the PIDs are accepted.
+	PIDsFilter PIDsFilter `json:"pids_filter"`
+}
+
+// PIDsFilter contents, as the service name of each PID, grouped by the namespace of the PID
+type PIDsFilter struct {
+	KProbes map[uint32]map[uint32]string `json:"kprobes"`
+	Go      map[uint32]map[uint32]string `json:"go"`
+}
+
+type instrumented struct {
+	process Process
+	tracer  *ebpf.ProcessTracer
+}
+
+// Routes learned from the observed traffic, as returned by the admin HTTP API
+type Routes struct {
+	Learned []route.LearnedRoutes `json:"learned"`
+}
+
+// Registry keeps track of the instrumented processes. It is safe for concurrent use.
+// A nil Registry ignores all the invocations, so it can be used when the admin HTTP API is disabled.
+type Registry struct {
+	mux        sync.RWMutex
+	processes  map[int32]instrumented
+	pidsFilter ebpfcommon.ServiceFilter
+	// learnedRoutes is nil until the routes decorator is created
+	learnedRoutes func() []route.LearnedRoutes
+}
+
+// NewRegistry for the instrumented processes, whose HTTP API will also report the contents of the
+// provided PIDs filter
+func NewRegistry(pidsFilter ebpfcommon.ServiceFilter) *Registry {
+	return &Registry{
+		processes:  map[int32]instrumented{},
+		pidsFilter: pidsFilter,
+	}
+}
+
+// Instrumented records a process that is instrumented by the provided ProcessTracer
+func (r *Registry) Instrumented(p *Process, tracer *ebpf.ProcessTracer) {
+	if r == nil {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.processes[p.PID] = instrumented{process: *p, tracer: tracer}
+}
+
+// Removed forgets a process that is not instrumented anymore
+func (r *Registry) Removed(pid int32) {
+	if r == nil {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	delete(r.processes, pid)
+}
+
+// LearnedRoutes sets the source of the routes that are learned from the observed traffic
+func (r *Registry) LearnedRoutes(source func() []route.LearnedRoutes) {
+	if r == nil {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.learnedRoutes = source
+}
+
+// Routes returns the routes that are currently learned from the observed traffic
+func (r *Registry) Routes() Routes {
+	r.mux.RLock()
+	source := r.learnedRoutes
+	r.mux.RUnlock()
+	routes := Routes{Learned: []route.LearnedRoutes{}}
+	if source != nil {
+		routes.Learned = source()
+	}
+	return routes
+}
+
+// Status returns the current state of the instrumented processes, sorted by PID
+func (r *Registry) Status() Status {
+	r.mux.RLock()
+	processes := make([]Process, 0, len(r.processes))
+	for _, inst := range r.processes {
+		p := inst.process
+		if inst.tracer != nil {
+			p.Tracer = inst.tracer.Status()
+		}
+		processes = append(processes, p)
+	}
+	r.mux.RUnlock()
+	slices.SortFunc(processes, func(a, b Process) int {
+		return cmp.Compare(a.PID, b.PID)
+	})
+	st := Status{Processes: processes}
+	if r.pidsFilter != nil {
+		st.PIDsFilter.KProbes = serviceNames(r.pidsFilter.CurrentPIDs(ebpfcommon.PIDTypeKProbes))
+		st.PIDsFilter.Go = serviceNames(r.pidsFilter.CurrentPIDs(ebpfcommon.PIDTypeGo))
+	}
+	return st
+}
+
+func serviceNames(pids map[uint32]map[uint32]svc.ID) map[uint32]map[uint32]string {
+	names := map[uint32]map[uint32]string{}
+	for ns, nsPids := range pids {
+		if len(nsPids) == 0 {
+			continue
+		}
+		nsNames := make(map[uint32]string, len(nsPids))
+		for pid, id := range nsPids {
+			nsNames[pid] = id.String()
+		}
+		names[ns] = nsNames
+	}
+	return names
+}
+
+// ServeHTTP returns the Status of the instrumented processes as JSON
+func (r *Registry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	serveJSON(rw, req, func() any { return r.Status() })
+}
+
+// RoutesHandler serves the learned Routes as JSON
+func (r *Registry) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		serveJSON(rw, req, func() any { return r.Routes() })
+	})
+}
+
+func serveJSON(rw http.ResponseWriter, req *http.Request, document func() any) {
+	if req.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(document()); err != nil {
+		alog().Debug("can't write admin HTTP response", "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/route"
+)
+
+type fakePIDsFilter struct {
+	ebpfcommon.ServiceFilter
+}
+
+func (f fakePIDsFilter) CurrentPIDs(t ebpfcommon.PIDType) map[uint32]map[uint32]svc.ID {
+	if t == ebpfcommon.PIDTypeGo {
+		return map[uint32]map[uint32]svc.ID{
+			1234: {20: {Name: "server", Namespace: "foo"}},
+			4321: {},
+		}
+	}
+	return map[uint32]map[uint32]svc.ID{1234: {10: {Name: "client"}}}
+}
+
+func TestRegistry_HTTP(t *testing.T) {
+	reg := NewRegistry(fakePIDsFilter{})
+	reg.Instrumented(&Process{PID: 20, ExePath: "/bin/server", Type: "go", MatchedBy: "services[1]",
+		Service: Service{Name: "server", Namespace: "foo"}}, &ebpf.ProcessTracer{})
+	reg.Instrumented(&Process{PID: 10, ChildPIDs: []uint32{11}, ExePath: "/bin/client", Type: "python",
+		MatchedBy: "services[0]", Service: Service{Name: "client", AutoName: true}}, nil)
+	reg.Instrumented(&Process{PID: 30, ExePath: "/bin/deleted"}, nil)
+	reg.Removed(30)
+
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	resp, err := http.Get(server.URL + ProcessesPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	status := Status{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, Status{
+		Processes: []Process{{
+			PID: 10, ChildPIDs: []uint32{11}, ExePath: "/bin/client", Type: "python", MatchedBy: "services[0]",
+			Service: Service{Name: "client", AutoName: true},
+		}, {
+			PID: 20, ExePath: "/bin/server", Type: "go", MatchedBy: "services[1]",
+			Service: Service{Name: "server", Namespace: "foo"},
+		}},
+		PIDsFilter: PIDsFilter{
+			KProbes: map[uint32]map[uint32]string{1234: {10: "client"}},
+			Go:      map[uint32]map[uint32]string{1234: {20: "foo/server"}},
+		},
+	}, status)
+
+	resp, err = http.Post(server.URL+ProcessesPath, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestRegistry_Routes(t *testing.T) {
+	reg := NewRegistry(nil)
+	server := httptest.NewServer(reg.RoutesHandler())
+	defer server.Close()
+
+	getRoutes := func() Routes {
+		resp, err := http.Get(server.URL + RoutesPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		routes := Routes{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&routes))
+		return routes
+	}
+	// no routes are learned until the routes decorator is created
+	assert.Equal(t, Routes{Learned: []route.LearnedRoutes{}}, getRoutes())
+
+	learner := route.NewLearner(2, 10)
+	reg.LearnedRoutes(learner.Patterns)
+	learner.Learn("svc", route.KindServer, "/users/1")
+	learner.Learn("svc", route.KindClient, "/items/1")
+	assert.Equal(t, Routes{Learned: []route.LearnedRoutes{
+		{Service: "svc", Kind: route.KindClient, Patterns: []string{"/items/1"}},
+		{Service: "svc", Kind: route.KindServer, Patterns: []string{"/users/1"}},
+	}}, getRoutes())
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var reg *Registry
+	assert.NotPanics(t, func() {
+		reg.Instrumented(&Process{PID: 1}, nil)
+		reg.Removed(1)
+		reg.LearnedRoutes(nil)
+	})
+}
@@ -0,0 +1,220 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/export/debug"
+	"github.com/grafana/beyla/pkg/internal/logging"
+)
+
+// DebugPath is the path of the admin HTTP API that changes the log levels and the debug
+// printers at runtime
+const DebugPath = "/api/debug"
+
+// DefaultDebugDuration is the time after which the debug settings are reverted, if the change
+// request doesn't specify it
+const DefaultDebugDuration = 10 * time.Minute
+
+// DebugSettings is the JSON document that is returned by the debug endpoint, as well as the
+// body of the change requests. The omitted fields of a change request are not changed.
+type DebugSettings struct {
+	LogLevel  *string           `json:"log_level,omitempty"`
+	LogLevels map[string]string `json:"log_levels,omitempty"`
+	// PrintTraces enables or disables the printing of the traces in the standard output
+	PrintTraces *bool `json:"print_traces,omitempty"`
+	// PrintFlows enables or disables the printing of the network flows in the standard output
+	PrintFlows *bool `json:"print_flows,omitempty"`
+	// Duration of the change (e.g. "5m"), after which the original settings are restored.
+	// Only used in change requests.
+	Duration string `json:"duration,omitempty"`
+	// RevertAt is the time when the original settings will be restored. Only used in responses,
+	// if the settings have been changed.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+type debugState struct {
+	level       slog.Level
+	levels      map[string]slog.Level
+	printTraces bool
+	printFlows  bool
+}
+
+// Debug serves the debug endpoint, which allows changing at runtime the log levels and enabling the
+// traces and network flows printers, for a limited time. It is safe for concurrent use.
+type Debug struct {
+	mux sync.Mutex
+	// nil if the log levels can't be changed
+	logs          *logging.Handler
+	tracesPrinter *debug.Toggle
+	flowsPrinter  *debug.Toggle
+	// if false, the settings can only be read
+	allowChanges bool
+	// original settings to restore, or nil if the settings haven't been changed
+	original *debugState
+	revertAt time.Time
+	timer    *time.Timer
+	// incremented on each change, to ignore the expiration of the timers of former changes
+	generation int
+}
+
+// NewDebug returns a Debug endpoint that changes the levels of the provided log handler, which can
+// be nil, and the provided printer toggles. If allowChanges is false, the endpoint rejects the
+// change requests and only returns the current settings.
+func NewDebug(logs *logging.Handler, tracesPrinter, flowsPrinter *debug.Toggle, allowChanges bool) *Debug {
+	return &Debug{logs: logs, tracesPrinter: tracesPrinter, flowsPrinter: flowsPrinter, allowChanges: allowChanges}
+}
+
+func (d *Debug) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !d.allowChanges && (req.Method == http.MethodPut || req.Method == http.MethodDelete) {
+		http.Error(rw, "changing the debug settings is disabled. Set admin.debug_endpoint to enable it",
+			http.StatusForbidden)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var settings DebugSettings
+		if err := json.NewDecoder(req.Body).Decode(&settings); err != nil {
+			http.Error(rw, "invalid debug settings: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := d.change(&settings); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		d.revert(-1)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(d.Settings()); err != nil {
+		alog().Debug("can't write debug settings", "error", err)
+	}
+}
+
+// Settings returns the current debug settings
+func (d *Debug) Settings() DebugSettings {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	st := d.current()
+	printTraces, printFlows := st.printTraces, st.printFlows
+	settings := DebugSettings{PrintTraces: &printTraces, PrintFlows: &printFlows}
+	if d.logs != nil {
+		level := st.level.String()
+		settings.LogLevel = &level
+		settings.LogLevels = map[string]string{}
+		for c, l := range st.levels {
+			settings.LogLevels[c] = l.String()
+		}
+	}
+	if d.original != nil {
+		revertAt := d.revertAt
+		settings.RevertAt = &revertAt
+	}
+	return settings
+}
+
+func (d *Debug) change(settings *DebugSettings) error {
+	duration := DefaultDebugDuration
+	if settings.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(settings.Duration); err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		if duration <= 0 {
+			return errors.New("duration must be positive")
+		}
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	st := d.current()
+	if settings.LogLevel != nil || settings.LogLevels != nil {
+		if d.logs == nil {
+			return errors.New("the log levels can't be changed")
+		}
+		level := st.level.String()
+		if settings.LogLevel != nil {
+			level = *settings.LogLevel
+		}
+		levels := map[string]string{}
+		for c, l := range st.levels {
+			levels[c] = l.String()
+		}
+		if settings.LogLevels != nil {
+			levels = settings.LogLevels
+		}
+		var err error
+		if st.level, st.levels, err = logging.ParseLevels(level, levels); err != nil {
+			return err
+		}
+	}
+	if settings.PrintTraces != nil {
+		st.printTraces = *settings.PrintTraces
+	}
+	if settings.PrintFlows != nil {
+		st.printFlows = *settings.PrintFlows
+	}
+
+	// successive changes keep the settings that were before the first change
+	if d.original == nil {
+		original := d.current()
+		d.original = &original
+	}
+	d.apply(&st)
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.generation++
+	generation := d.generation
+	d.revertAt = time.Now().Add(duration)
+	d.timer = time.AfterFunc(duration, func() { d.revert(generation) })
+	alog().Info("debug settings changed", "revertAt", d.revertAt)
+	return nil
+}
+
+// revert restores the original settings if the generation of the change matches the provided
+// generation. A negative generation reverts any change.
+func (d *Debug) revert(generation int) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.original == nil || (generation >= 0 && generation != d.generation) {
+		return
+	}
+	d.apply(d.original)
+	d.original = nil
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	alog().Info("debug settings reverted")
+}
+
+func (d *Debug) current() debugState {
+	st := debugState{
+		printTraces: d.tracesPrinter.On(),
+		printFlows:  d.flowsPrinter.On(),
+	}
+	if d.logs != nil {
+		st.level, st.levels = d.logs.Levels()
+	}
+	return st
+}
+
+func (d *Debug) apply(st *debugState) {
+	if d.logs != nil {
+		d.logs.SetLevels(st.level, st.levels)
+	}
+	d.tracesPrinter.Set(st.printTraces)
+	d.flowsPrinter.Set(st.printFlows)
+}
@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/export/debug"
+	"github.com/grafana/beyla/pkg/internal/logging"
+)
+
+func TestDebug_HTTP(t *testing.T) {
+	logs := logging.New(io.Discard)
+	logs.SetLevels(slog.LevelInfo, map[string]slog.Level{"discover": slog.LevelWarn})
+	traces, flows := &debug.Toggle{}, &debug.Toggle{}
+	flows.Set(true)
+	server := httptest.NewServer(NewDebug(logs, traces, flows, true))
+	defer server.Close()
+
+	settings := debugRequest(t, http.MethodGet, server.URL, "", http.StatusOK)
+	assert.Equal(t, "INFO", *settings.LogLevel)
+	assert.Equal(t, map[string]string{"discover": "WARN"}, settings.LogLevels)
+	assert.False(t, *settings.PrintTraces)
+	assert.True(t, *settings.PrintFlows)
+	assert.Nil(t, settings.RevertAt)
+
+	// the omitted fields are not changed
+	settings = debugRequest(t, http.MethodPut, server.URL,
+		`{"log_level":"debug","print_traces":true,"duration":"1h"}`, http.StatusOK)
+	assert.Equal(t, "DEBUG", *settings.LogLevel)
+	assert.Equal(t, map[string]string{"discover": "WARN"}, settings.LogLevels)
+	assert.True(t, *settings.PrintTraces)
+	assert.True(t, *settings.PrintFlows)
+	require.NotNil(t, settings.RevertAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *settings.RevertAt, time.Minute)
+	assert.True(t, traces.On())
+	level, _ := logs.Levels()
+	assert.Equal(t, slog.LevelDebug, level)
+
+	// successive changes are reverted to the settings before the first change
+	debugRequest(t, http.MethodPut, server.URL, `{"log_levels":{},"print_flows":false}`, http.StatusOK)
+	assert.False(t, flows.On())
+	settings = debugRequest(t, http.MethodDelete, server.URL, "", http.StatusOK)
+	assert.Equal(t, "INFO", *settings.LogLevel)
+	assert.Equal(t, map[string]string{"discover": "WARN"}, settings.LogLevels)
+	assert.False(t, *settings.PrintTraces)
+	assert.True(t, *settings.PrintFlows)
+	assert.Nil(t, settings.RevertAt)
+
+	// wrong requests don't change anything
+	debugRequest(t, http.MethodPut, server.URL, `{"log_level":"verbose","print_traces":true}`, http.StatusBadRequest)
+	debugRequest(t, http.MethodPut, server.URL, `{"print_traces":true,"duration":"-1m"}`, http.StatusBadRequest)
+	debugRequest(t, http.MethodPut, server.URL, `{"print_traces":`, http.StatusBadRequest)
+	assert.False(t, traces.On())
+	debugRequest(t, http.MethodPost, server.URL, `{"print_traces":true}`, http.StatusMethodNotAllowed)
+}
+
+func TestDebug_ReadOnly(t *testing.T) {
+	traces, flows := &debug.Toggle{}, &debug.Toggle{}
+	server := httptest.NewServer(NewDebug(nil, traces, flows, false))
+	defer server.Close()
+
+	settings := debugRequest(t, http.MethodGet, server.URL, "", http.StatusOK)
+	assert.False(t, *settings.PrintTraces)
+
+	// the settings can't be changed unless the debug endpoint is enabled
+	debugRequest(t, http.MethodPut, server.URL, `{"print_traces":true}`, http.StatusForbidden)
+	debugRequest(t, http.MethodDelete, server.URL, "", http.StatusForbidden)
+	assert.False(t, traces.On())
+}
+
+func TestDebug_Expiration(t *testing.T) {
+	traces, flows := &debug.Toggle{}, &debug.Toggle{}
+	dbg := NewDebug(nil, traces, flows, true)
+
+	// the log levels can't be changed without a log handler
+	require.Error(t, dbg.change(&DebugSettings{LogLevel: ptr("DEBUG")}))
+	assert.Nil(t, dbg.Settings().LogLevel)
+
+	require.NoError(t, dbg.change(&DebugSettings{PrintTraces: ptr(true), Duration: "10ms"}))
+	assert.True(t, traces.On())
+	// extending the change resets its expiration
+	require.NoError(t, dbg.change(&DebugSettings{PrintFlows: ptr(true), Duration: "100ms"}))
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, traces.On())
+	assert.True(t, flows.On())
+
+	assert.Eventually(t, func() bool {
+		return !traces.On() && !flows.On() && dbg.Settings().RevertAt == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func debugRequest(t *testing.T, method, url, body string, expectedStatus int) DebugSettings {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, expectedStatus, resp.StatusCode)
+	var settings DebugSettings
+	if expectedStatus == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&settings))
+	}
+	return settings
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
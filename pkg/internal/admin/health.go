@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+const (
+	// LivenessPath is the path of the admin HTTP API that reports whether Beyla is alive
+	LivenessPath = "/healthz"
+	// ReadinessPath is the path of the admin HTTP API that reports whether Beyla is ready
+	ReadinessPath = "/readyz"
+)
+
+const checkOK = "ok"
+
+// Check returns an error if the checked component is not healthy, or not ready
+type Check func() error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// HealthStatus is the JSON document returned by the liveness and readiness endpoints
+type HealthStatus struct {
+	Status string `json:"status"`
+	// Checks contains the result of each check: "ok" or the description of the failure
+	Checks map[string]string `json:"checks"`
+}
+
+// Health groups the liveness and readiness checks of the different Beyla components.
+// It is safe for concurrent use.
+type Health struct {
+	mux       sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// AddLiveness registers a check that makes the liveness endpoint to fail when it returns an error
+func (h *Health) AddLiveness(name string, check Check) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.liveness = append(h.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadiness registers a check that makes the readiness endpoint to fail when it returns an error
+func (h *Health) AddReadiness(name string, check Check) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.readiness = append(h.readiness, namedCheck{name: name, check: check})
+}
+
+// LivenessHandler serves the result of the liveness checks
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		h.serve(rw, req, func() []namedCheck { return h.liveness })
+	})
+}
+
+// ReadinessHandler serves the result of the readiness checks
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		h.serve(rw, req, func() []namedCheck { return h.readiness })
+	})
+}
+
+func (h *Health) serve(rw http.ResponseWriter, req *http.Request, checks func() []namedCheck) {
+	if req.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h.mux.RLock()
+	status := run(checks())
+	h.mux.RUnlock()
+
+	rw.Header().Set("Content-Type", "application/json")
+	if status.Status != checkOK {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(rw).Encode(status); err != nil {
+		alog().Debug("can't write health status", "error", err)
+	}
+}
+
+func run(checks []namedCheck) HealthStatus {
+	status := HealthStatus{Status: checkOK, Checks: map[string]string{}}
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			status.Status = "failed"
+			status.Checks[c.name] = err.Error()
+		} else {
+			status.Checks[c.name] = checkOK
+		}
+	}
+	return status
+}
+
+// endpointDialTimeout bounds the time that the readiness check waits for each exporter endpoint
+const endpointDialTimeout = 500 * time.Millisecond
+
+// ExportTracker wraps an internal metrics Reporter to keep track of whether any OTEL exporter
+// has successfully submitted data to its endpoint.
+type ExportTracker struct {
+	imetrics.Reporter
+	exported atomic.Bool
+	// reachable is true since any exporter endpoint has accepted a connection
+	reachable atomic.Bool
+}
+
+func NewExportTracker(reporter imetrics.Reporter) *ExportTracker {
+	return &ExportTracker{Reporter: reporter}
+}
+
+// Exported returns whether any OTEL metrics or traces export has succeeded
+func (et *ExportTracker) Exported() bool {
+	return et.exported.Load()
+}
+
+// ConnectedCheck returns a readiness check that succeeds since any OTEL export has succeeded or
+// any of the provided endpoint addresses (host:port) has accepted a TCP connection. As it doesn't
+// wait for the first export, Beyla gets ready in the nodes without any traffic to instrument.
+func (et *ExportTracker) ConnectedCheck(addrs []string) Check {
+	return func() error {
+		if et.exported.Load() || et.reachable.Load() {
+			return nil
+		}
+		var errs []error
+		for _, addr := range addrs {
+			conn, err := net.DialTimeout("tcp", addr, endpointDialTimeout)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			_ = conn.Close()
+			et.reachable.Store(true)
+			return nil
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("no exporter endpoint is reachable: %w", errors.Join(errs...))
+		}
+		return errors.New("no exporter has successfully submitted data yet")
+	}
+}
+
+func (et *ExportTracker) OTELMetricExport(len int) {
+	et.exported.Store(true)
+	et.Reporter.OTELMetricExport(len)
+}
+
+func (et *ExportTracker) OTELTraceExport(len int) {
+	et.exported.Store(true)
+	et.Reporter.OTELTraceExport(len)
+}
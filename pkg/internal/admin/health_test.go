@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+func TestHealth_HTTP(t *testing.T) {
+	var stalled, synced bool
+	health := NewHealth()
+	health.AddLiveness("ebpf_events", func() error {
+		if stalled {
+			return errors.New("stalled")
+		}
+		return nil
+	})
+	health.AddReadiness("kubernetes", func() error {
+		if !synced {
+			return errors.New("not synced")
+		}
+		return nil
+	})
+	health.AddReadiness("ebpf", func() error { return nil })
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, health.LivenessHandler())
+	mux.Handle(ReadinessPath, health.ReadinessHandler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// initially alive but not ready
+	assertHealth(t, server.URL+LivenessPath, http.StatusOK,
+		HealthStatus{Status: "ok", Checks: map[string]string{"ebpf_events": "ok"}})
+	assertHealth(t, server.URL+ReadinessPath, http.StatusServiceUnavailable,
+		HealthStatus{Status: "failed", Checks: map[string]string{"kubernetes": "not synced", "ebpf": "ok"}})
+
+	// ready after all the readiness checks succeed
+	synced = true
+	assertHealth(t, server.URL+ReadinessPath, http.StatusOK,
+		HealthStatus{Status: "ok", Checks: map[string]string{"kubernetes": "ok", "ebpf": "ok"}})
+
+	// not alive after a liveness check fails
+	stalled = true
+	assertHealth(t, server.URL+LivenessPath, http.StatusServiceUnavailable,
+		HealthStatus{Status: "failed", Checks: map[string]string{"ebpf_events": "stalled"}})
+
+	resp, err := http.Post(server.URL+LivenessPath, "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestExportTracker(t *testing.T) {
+	tracker := NewExportTracker(imetrics.NoopReporter{})
+	assert.False(t, tracker.Exported())
+	tracker.OTELMetricExportError(errors.New("connection refused"))
+	assert.False(t, tracker.Exported())
+	tracker.OTELTraceExport(3)
+	assert.True(t, tracker.Exported())
+}
+
+func TestExportTracker_ConnectedCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+
+	// no exports are required while any endpoint is reachable
+	tracker := NewExportTracker(imetrics.NoopReporter{})
+	require.NoError(t, tracker.ConnectedCheck([]string{closed.Addr().String(), listener.Addr().String()})())
+
+	tracker = NewExportTracker(imetrics.NoopReporter{})
+	check := tracker.ConnectedCheck([]string{closed.Addr().String()})
+	assert.Error(t, check())
+	tracker.OTELMetricExport(1)
+	assert.NoError(t, check())
+
+	assert.Error(t, NewExportTracker(imetrics.NoopReporter{}).ConnectedCheck(nil)())
+}
+
+func assertHealth(t *testing.T, url string, expectedCode int, expected HealthStatus) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, expectedCode, resp.StatusCode)
+	var status HealthStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, expected, status)
+}
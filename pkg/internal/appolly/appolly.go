@@ -4,18 +4,26 @@ package appolly
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/admin"
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/discover"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/export/debug"
+	"github.com/grafana/beyla/pkg/internal/export/otel"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	kube2 "github.com/grafana/beyla/pkg/internal/kube"
+	"github.com/grafana/beyla/pkg/internal/logging"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/privsep"
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/transform"
 	"github.com/grafana/beyla/pkg/internal/transform/kube"
@@ -32,10 +40,16 @@ type Instrumenter struct {
 	ctxInfo *global.ContextInfo
 
 	// tracesInput is used to communicate the found traces between the ProcessFinder and
-	// the ProcessTracer.
-	// TODO: When we split beyla into two executables, probably the BPF map
-	// should be the traces' communication mechanism instead of a native channel
+	// the ProcessTracer. When the privilege separation is enabled, the traces are read in the exporter
+	// process from the BPF ring buffer that is handed over by the loader process.
 	tracesInput chan []request.Span
+
+	// discoveryReloads provides new configurations whose discovery criteria replace the current ones
+	discoveryReloads <-chan *beyla.Config
+
+	// handoverErrs receives the result of the handover from the loader process, when the privilege
+	// separation is enabled and this process is the exporter
+	handoverErrs chan error
 }
 
 // New Instrumenter, given a Config
@@ -47,14 +61,33 @@ func New(config *beyla.Config) *Instrumenter {
 	}
 }
 
+// WithDiscoveryReloads makes the Instrumenter replace its discovery criteria by the ones of each
+// configuration received from the reloads channel. It must be invoked before FindAndInstrument.
+func (i *Instrumenter) WithDiscoveryReloads(reloads <-chan *beyla.Config) *Instrumenter {
+	i.discoveryReloads = reloads
+	return i
+}
+
 // FindAndInstrument searches in background for any new executable matching the
 // selection criteria.
+// If this is the exporter process of the privilege separation, it receives instead the events of
+// the processes that are instrumented by the loader process.
 func (i *Instrumenter) FindAndInstrument(ctx context.Context) error {
-	finder := discover.NewProcessFinder(ctx, i.config, i.ctxInfo)
+	if i.config.PrivilegeSeparation.Mode == privsep.ModeExporter {
+		i.receiveEvents(ctx)
+		return nil
+	}
+	finder := discover.NewProcessFinder(ctx, i.config, i.ctxInfo, i.discoveryReloads)
 	foundProcesses, deletedProcesses, err := finder.Start(i.config)
 	if err != nil {
 		return fmt.Errorf("couldn't start Process Finder: %w", err)
 	}
+	if i.config.PrivilegeSeparation.Mode == privsep.ModeLoader {
+		// the process finder has already mounted the BPF pin path, and no tracer has been loaded yet
+		if err := i.handOverEvents(ctx); err != nil {
+			return fmt.Errorf("couldn't hand over the eBPF events: %w", err)
+		}
+	}
 	// In background, listen indefinitely for each new process and run its
 	// associated ebpf.ProcessTracer once it is found.
 	go func() {
@@ -93,17 +126,42 @@ func (i *Instrumenter) FindAndInstrument(ctx context.Context) error {
 }
 
 // ReadAndForward keeps listening for traces in the BPF map, then reads,
-// processes and forwards them
+// processes and forwards them.
+// If this is the loader process of the privilege separation, it just waits until the context is
+// done, as the exporter process reads and forwards the traces.
 func (i *Instrumenter) ReadAndForward(ctx context.Context) error {
 	log := log()
+	if i.config.PrivilegeSeparation.Mode == privsep.ModeLoader {
+		if i.config.Admin.Enabled() {
+			go i.ctxInfo.Prometheus.StartHTTP(ctx)
+		}
+		go i.ctxInfo.Metrics.Start(ctx)
+		log.Info("handing over the traces to the exporter process")
+		<-ctx.Done()
+		return nil
+	}
+	if i.handoverErrs != nil {
+		// the pipeline is stopped if the exporter loses the connection with the loader process
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		go func() {
+			if err := <-i.handoverErrs; err != nil {
+				cancel(err)
+			}
+		}()
+	}
 	log.Debug("creating instrumentation pipeline")
 
-	// TODO: when we split the executable, tracer should be reconstructed somehow
-	// from this instance
 	bp, err := pipe.Build(ctx, i.config, i.ctxInfo, i.tracesInput)
 	if err != nil {
 		return fmt.Errorf("can't instantiate instrumentation pipeline: %w", err)
 	}
+	if i.config.Admin.Enabled() {
+		// the admin HTTP API might not share its port with any Prometheus endpoint, so its listener
+		// is explicitly started once all the Prometheus collectors have been registered
+		go i.ctxInfo.Prometheus.StartHTTP(ctx)
+	}
 
 	log.Info("Starting main node")
 
@@ -111,6 +169,9 @@ func (i *Instrumenter) ReadAndForward(ctx context.Context) error {
 
 	log.Info("exiting auto-instrumenter")
 
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
 	return nil
 }
 
@@ -123,23 +184,107 @@ func buildContextInfo(config *beyla.Config) *global.ContextInfo {
 		ReportRoutes: config.Routes != nil,
 		Prometheus:   promMgr,
 		K8sEnabled:   k8sCfg.Enabled(),
+		MetricHeaders: request.NewMetricHeaders(
+			config.EBPF.HTTPHeaders.MetricRequestHeaders(),
+			config.EBPF.HTTPHeaders.MetricResponseHeaders(),
+			config.EBPF.HTTPHeaders.MetricLabelValues),
 	}
 	if ctxInfo.K8sEnabled {
 		setupKubernetes(k8sCfg, ctxInfo)
 	}
+	if config.Admin.Enabled() {
+		ctxInfo.AdminRegistry = admin.NewRegistry(ebpfcommon.CommonPIDsFilter(config.Discovery.SystemWide))
+		promMgr.RegisterHandler(config.Admin.Port, admin.ProcessesPath, ctxInfo.AdminRegistry)
+		promMgr.RegisterHandler(config.Admin.Port, admin.RoutesPath, ctxInfo.AdminRegistry.RoutesHandler())
+		promMgr.RegisterHandler(config.Admin.Port, admin.DebugPath,
+			admin.NewDebug(logging.Default(), debug.TracesPrinter, debug.FlowsPrinter, config.Admin.DebugEndpoint))
+	}
 	if config.InternalMetrics.Prometheus.Port != 0 {
 		slog.Debug("reporting internal metrics as Prometheus")
 		ctxInfo.Metrics = imetrics.NewPrometheusReporter(&config.InternalMetrics.Prometheus, promMgr)
 		// Prometheus manager also has its own internal metrics, so we need to pass the imetrics reporter
 		// TODO: remove this dependency cycle and let prommgr to create and return the PrometheusReporter
 		promMgr.InstrumentWith(ctxInfo.Metrics)
+	} else if config.InternalMetrics.OTEL.Enabled {
+		slog.Debug("reporting internal metrics as OpenTelemetry")
+		ctxInfo.Metrics = otelInternalMetrics(config)
 	} else {
 		slog.Debug("not reporting internal metrics")
 		ctxInfo.Metrics = imetrics.NoopReporter{}
 	}
+	if config.Admin.Enabled() {
+		setupHealth(config, ctxInfo, promMgr)
+	}
 	return ctxInfo
 }
 
+// setupHealth registers the liveness and readiness endpoints in the admin HTTP API
+func setupHealth(config *beyla.Config, ctxInfo *global.ContextInfo, promMgr *connector.PrometheusManager) {
+	health := newHealth(config, ctxInfo)
+	promMgr.RegisterHandler(config.Admin.Port, admin.LivenessPath, health.LivenessHandler())
+	promMgr.RegisterHandler(config.Admin.Port, admin.ReadinessPath, health.ReadinessHandler())
+}
+
+// newHealth creates the liveness and readiness checks. The readiness checks don't depend on any
+// process being instrumented nor any data being exported, so Beyla gets ready in idle nodes.
+func newHealth(config *beyla.Config, ctxInfo *global.ContextInfo) *admin.Health {
+	health := admin.NewHealth()
+
+	health.AddLiveness("ebpf_events", func() error {
+		heartbeat, ok := ebpfcommon.SharedRingbufHeartbeat()
+		if !ok {
+			return nil
+		}
+		if stalled := time.Since(heartbeat); stalled > config.Admin.StallThreshold {
+			return fmt.Errorf("eBPF events forwarding is stalled since %s", stalled.Round(time.Second))
+		}
+		return nil
+	})
+
+	if ctxInfo.K8sInformer != nil {
+		health.AddReadiness("kubernetes", func() error {
+			if !ctxInfo.K8sInformer.HasSynced() {
+				return errors.New("kubernetes informers are not synced")
+			}
+			return nil
+		})
+	}
+	if config.Enabled(beyla.FeatureAppO11y) {
+		health.AddReadiness("ebpf", func() error {
+			if !ebpfcommon.Loaded() {
+				return errors.New("no eBPF programs are loaded yet")
+			}
+			return nil
+		})
+	}
+	// Prometheus and the debug exporters don't need to connect anywhere, so they are ready
+	// since the beginning. The OTEL exporters are ready since their endpoint is reachable, or
+	// after their first successful export.
+	// The loader process of the privilege separation doesn't export anything.
+	if config.PrivilegeSeparation.Mode != privsep.ModeLoader &&
+		!config.Prometheus.Enabled() && !config.Printer.Enabled() && !config.Noop.Enabled() {
+		exports := admin.NewExportTracker(ctxInfo.Metrics)
+		ctxInfo.Metrics = exports
+		metricsCfg, tracesCfg := config.Metrics, config.Traces
+		metricsCfg.Grafana, tracesCfg.Grafana = &config.Grafana.OTLP, &config.Grafana.OTLP
+		health.AddReadiness("exporter", exports.ConnectedCheck(otel.EndpointAddresses(&metricsCfg, &tracesCfg)))
+	}
+	return health
+}
+
+// otelInternalMetrics instantiates an internal metrics reporter that pushes the metrics to the
+// OTEL metrics endpoint. If it fails, the internal metrics aren't reported.
+func otelInternalMetrics(config *beyla.Config) imetrics.Reporter {
+	metricsCfg := config.Metrics
+	metricsCfg.Grafana = &config.Grafana.OTLP
+	reporter, err := otel.NewInternalMetricsReporter(context.Background(), &metricsCfg, &config.InternalMetrics.OTEL)
+	if err != nil {
+		slog.Error("can't instantiate OTEL internal metrics reporter. Internal metrics won't be reported", "error", err)
+		return imetrics.NoopReporter{}
+	}
+	return reporter
+}
+
 // setupKubernetes sets up common Kubernetes database and API clients that need to be accessed
 // from different stages in the Beyla pipeline
 func setupKubernetes(k8sCfg *transform.KubernetesDecorator, ctxInfo *global.ContextInfo) {
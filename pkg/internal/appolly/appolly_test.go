@@ -0,0 +1,51 @@
+package appolly
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/admin"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+)
+
+func TestHealth_IdleNode(t *testing.T) {
+	collector, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer collector.Close()
+
+	config := beyla.DefaultConfig
+	config.Admin.Port = 6060
+	config.Discovery.SystemWide = true
+	config.Traces.TracesEndpoint = "http://" + collector.Addr().String()
+	health := newHealth(&config, &global.ContextInfo{Metrics: imetrics.NoopReporter{}})
+
+	// the exporter is ready although nothing has been exported yet
+	status := readiness(t, health)
+	assert.Equal(t, "ok", status.Checks["exporter"])
+	assert.NotEqual(t, "ok", status.Checks["ebpf"])
+
+	// no process needs to be instrumented to get ready, only the eBPF tracers being loaded
+	ebpfcommon.MarkLoaded()
+	assert.Equal(t, admin.HealthStatus{
+		Status: "ok",
+		Checks: map[string]string{"ebpf": "ok", "exporter": "ok"},
+	}, readiness(t, health))
+}
+
+func readiness(t *testing.T, health *admin.Health) admin.HealthStatus {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	health.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, admin.ReadinessPath, nil))
+	var status admin.HealthStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	return status
+}
@@ -0,0 +1,58 @@
+package appolly
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/grafana/beyla/pkg/internal/discover"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/privsep"
+)
+
+// handOverEvents makes the tracers not read their events, and serves in background the events
+// ring buffer to the exporter process that connects to the privilege separation socket
+func (i *Instrumenter) handOverEvents(ctx context.Context) error {
+	pids, ok := ebpfcommon.CommonPIDsFilter(i.config.Discovery.SystemWide).(*ebpfcommon.PIDsFilter)
+	if !ok {
+		return errors.New("the privilege separation requires filtering the PIDs of the tracers")
+	}
+	ringbuf, err := ebpfcommon.HandOverSharedRingbuf(ctx, &i.config.EBPF, discover.BuildPinPath(i.config))
+	if err != nil {
+		return err
+	}
+	cfg := &i.config.PrivilegeSeparation
+	go func() {
+		if err := privsep.NewLoader(ringbuf, pids).
+			Serve(ctx, cfg.Socket(i.config.EBPF.BpfBaseDir), cfg.SocketOwner); err != nil {
+			log().Error("can't hand over the eBPF events to the exporter process", "error", err)
+		}
+	}()
+	return nil
+}
+
+// receiveEvents connects in background to the loader process, and forwards the events of the
+// ring buffer that it hands over. The result of the handover is sent to the handoverErrs channel.
+func (i *Instrumenter) receiveEvents(ctx context.Context) {
+	i.handoverErrs = make(chan error, 1)
+	go func() {
+		i.handoverErrs <- i.forwardHandover(ctx)
+	}()
+}
+
+func (i *Instrumenter) forwardHandover(ctx context.Context) error {
+	cfg := &i.config.PrivilegeSeparation
+	handover, err := privsep.Connect(ctx, cfg.Socket(i.config.EBPF.BpfBaseDir))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer handover.Close()
+	// the PIDs are discovered by the loader process
+	pids := ebpfcommon.NewPIDsFilter(slog.With("component", "privsep.PIDsFilter"))
+	go ebpfcommon.SharedRingbufFromFD(&i.config.EBPF, pids,
+		handover.RingbufFD, handover.RingbufSize, i.ctxInfo.Metrics)(ctx, i.tracesInput)
+	return handover.ForwardPIDs(ctx, pids)
+}
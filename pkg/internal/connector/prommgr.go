@@ -25,6 +25,8 @@ type PrometheusManager struct {
 	started atomic.Bool
 	// key 1: port. Key 2: path
 	registries map[int]map[string]*prometheus.Registry
+	// other HTTP handlers sharing the listeners of the Prometheus metrics. Key 1: port. Key 2: path
+	handlers map[int]map[string]http.Handler
 
 	metrics internalIntrumenter
 }
@@ -58,6 +60,22 @@ func (pm *PrometheusManager) Register(port int, path string, collectors ...prome
 	reg.MustRegister(collectors...)
 }
 
+// RegisterHandler makes an HTTP handler accessible through an HTTP port/path, which can be shared
+// with the Prometheus metrics (e.g. the admin HTTP API).
+// This method is not thread-safe
+func (pm *PrometheusManager) RegisterHandler(port int, path string, handler http.Handler) {
+	log().Debug("registering HTTP handler", "port", port, "path", path)
+	if pm.handlers == nil {
+		pm.handlers = map[int]map[string]http.Handler{}
+	}
+	paths, ok := pm.handlers[port]
+	if !ok {
+		paths = map[string]http.Handler{}
+		pm.handlers[port] = paths
+	}
+	paths[path] = handler
+}
+
 // StartHTTP serves metrics in background. Its invocation won't have effect if it has been invoked previously,
 // so invoke it only after you are sure that all the collectors have been registered via the Register method.
 func (pm *PrometheusManager) StartHTTP(ctx context.Context) {
@@ -66,8 +84,17 @@ func (pm *PrometheusManager) StartHTTP(ctx context.Context) {
 	}
 	log := log()
 	// Creating a serve mux for each port
+	muxes := map[int]*http.ServeMux{}
+	portMux := func(port int) *http.ServeMux {
+		mux, ok := muxes[port]
+		if !ok {
+			mux = http.NewServeMux()
+			muxes[port] = mux
+		}
+		return mux
+	}
 	for port, paths := range pm.registries {
-		mux := http.NewServeMux()
+		mux := portMux(port)
 		for path, registry := range paths {
 			log.With("port", port, "path", path).Info("opening prometheus scrape endpoint")
 			promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
@@ -75,6 +102,15 @@ func (pm *PrometheusManager) StartHTTP(ctx context.Context) {
 			promHandler = wrapInstrumentedHandler(pm.metrics, port, path, promHandler)
 			mux.Handle(path, promHandler)
 		}
+	}
+	for port, paths := range pm.handlers {
+		mux := portMux(port)
+		for path, handler := range paths {
+			log.With("port", port, "path", path).Info("opening HTTP endpoint")
+			mux.Handle(path, handler)
+		}
+	}
+	for port, mux := range muxes {
 		pm.listenAndServe(ctx, port, mux)
 	}
 }
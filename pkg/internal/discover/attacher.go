@@ -2,22 +2,33 @@ package discover
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path"
+	"time"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/mariomac/pipes/pkg/node"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/admin"
 	"github.com/grafana/beyla/pkg/internal/ebpf"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
 	"github.com/grafana/beyla/pkg/internal/goexec"
 	"github.com/grafana/beyla/pkg/internal/helpers"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
+// bpfStatsPeriod is the frequency of the collection of internal metrics from the pinned eBPF maps
+const bpfStatsPeriod = 10 * time.Second
+
+// staleStatePeriod is the time that a restarted Beyla waits for the instrumented processes of the
+// former Beyla process to be discovered again, before unpinning the probes that haven't been adopted
+var staleStatePeriod = time.Minute
+
 // TraceAttacher creates the available trace.Tracer implementations (Go HTTP tracer, GRPC tracer, Generic tracer...)
 // for each received Instrumentable process and forwards an ebpf.ProcessTracer instance ready to run and start
 // instrumenting the executable
@@ -28,7 +39,11 @@ type TraceAttacher struct {
 	DiscoveredTracers chan *ebpf.ProcessTracer
 	DeleteTracers     chan *Instrumentable
 	Metrics           imetrics.Reporter
-	pinPath           string
+	// Registry of the instrumented processes for the admin HTTP API. It is nil if the admin HTTP API is disabled
+	Registry *admin.Registry
+	pinPath  string
+	// state of the eBPF programs that survives restarts. It is nil if the persistent state is disabled
+	state *ebpf.PersistentState
 
 	// processInstances keeps track of the instances of each process. This will help making sure
 	// that we don't remove the BPF resources of an executable until all their instances are removed
@@ -51,6 +66,19 @@ func TraceAttacherProvider(ta TraceAttacher) (node.TerminalFunc[[]Event[Instrume
 		ta.log.Error("cant start process tracer. Stopping it", "error", err)
 		return nil, err
 	}
+	if ta.Cfg.EBPF.PersistentState {
+		state, err := ta.openPersistentState()
+		if err != nil {
+			ta.log.Error("can't open the persistent eBPF state. Stopping process tracer", "error", err)
+			return nil, err
+		}
+		ta.state = state
+		go ta.removeStaleState()
+	}
+	ebpfcommon.MarkLoaded()
+	if ta.Cfg.InternalMetrics.Enabled() {
+		go ebpfcommon.NewStatsCollector(ta.pinPath, ta.Metrics).Run(ta.Ctx, bpfStatsPeriod)
+	}
 
 	return func(in <-chan []Event[Instrumentable]) {
 	mainLoop:
@@ -91,6 +119,7 @@ func (ta *TraceAttacher) getTracer(ie *Instrumentable) (*ebpf.ProcessTracer, boo
 		if tracer.Type == ebpf.Generic {
 			monitorPIDs(ta.reusableTracer, ie)
 		}
+		ta.register(ie, tracer)
 		ta.log.Debug(".done")
 		return nil, false
 	}
@@ -149,6 +178,7 @@ func (ta *TraceAttacher) getTracer(ie *Instrumentable) (*ebpf.ProcessTracer, boo
 		Goffsets:   ie.Offsets,
 		Exe:        exe,
 		PinPath:    BuildPinPath(ta.Cfg),
+		State:      ta.state,
 		SystemWide: ta.Cfg.Discovery.SystemWide,
 		Type:       tracerType,
 	}
@@ -158,6 +188,7 @@ func (ta *TraceAttacher) getTracer(ie *Instrumentable) (*ebpf.ProcessTracer, boo
 		"exec", ie.FileInfo.CmdExePath)
 	// allowing the tracer to forward traces from the discovered PID and its children processes
 	monitorPIDs(tracer, ie)
+	ta.register(ie, tracer)
 	ta.existingTracers[ie.FileInfo.Ino] = tracer
 	if tracer.Type == ebpf.Generic {
 		if ta.reusableTracer != nil {
@@ -189,13 +220,65 @@ func monitorPIDs(tracer *ebpf.ProcessTracer, ie *Instrumentable) {
 	}
 }
 
+// register the instrumented process, to be listed by the admin HTTP API
+func (ta *TraceAttacher) register(ie *Instrumentable, tracer *ebpf.ProcessTracer) {
+	if ta.Registry == nil {
+		return
+	}
+	id := &ie.FileInfo.Service
+	ta.Registry.Instrumented(&admin.Process{
+		PID:       ie.FileInfo.Pid,
+		ChildPIDs: ie.ChildPids,
+		ExePath:   ie.FileInfo.CmdExePath,
+		Service: admin.Service{
+			Name:            id.Name,
+			Namespace:       id.Namespace,
+			Instance:        id.Instance,
+			AutoName:        id.AutoName,
+			ExporterProfile: id.ExporterProfile,
+			Metadata:        id.Metadata,
+		},
+		Type:      ie.Type.String(),
+		MatchedBy: ie.MatchedBy,
+	}, tracer)
+}
+
 // BuildPinPath pinpath must be unique for a given executable group
 // it will be:
 //   - current beyla PID
+//   - a fixed name if the persistent state is enabled, so a restarted Beyla finds the former state
 func BuildPinPath(cfg *beyla.Config) string {
+	if cfg.EBPF.PersistentState {
+		return path.Join(cfg.EBPF.BpfBaseDir, "beyla")
+	}
 	return path.Join(cfg.EBPF.BpfBaseDir, fmt.Sprintf("beyla-%d", os.Getpid()))
 }
 
+// openPersistentState whose fingerprint identifies the eBPF programs and configuration of this
+// Beyla process, so the pinned state is discarded if a restarted Beyla can't reuse it
+func (ta *TraceAttacher) openPersistentState() (*ebpf.PersistentState, error) {
+	ebpfCfg, err := json.Marshal(ta.Cfg.EBPF)
+	if err != nil {
+		return nil, fmt.Errorf("encoding eBPF configuration: %w", err)
+	}
+	tracers := append(newGoTracersGroup(ta.Cfg, ta.Metrics), newNonGoTracersGroup(ta.Cfg, ta.Metrics)...)
+	fingerprint, err := ebpf.StateFingerprint(tracers, ebpfCfg)
+	if err != nil {
+		return nil, err
+	}
+	return ebpf.OpenPersistentState(ta.pinPath, fingerprint)
+}
+
+// removeStaleState unpins the probes of the former Beyla process that haven't been adopted after
+// some time, e.g. because their instrumented processes have finished
+func (ta *TraceAttacher) removeStaleState() {
+	select {
+	case <-ta.Ctx.Done():
+	case <-time.After(staleStatePeriod):
+		ta.state.RemoveStale()
+	}
+}
+
 func (ta *TraceAttacher) notifyProcessDeletion(ie *Instrumentable) {
 	if tracer, ok := ta.existingTracers[ie.FileInfo.Ino]; ok {
 		ta.log.Info("process ended for already instrumented executable",
@@ -205,12 +288,14 @@ func (ta *TraceAttacher) notifyProcessDeletion(ie *Instrumentable) {
 		// to avoid that a new process reusing this PID could send traces
 		// unless explicitly allowed
 		tracer.BlockPID(uint32(ie.FileInfo.Pid))
+		ta.Registry.Removed(ie.FileInfo.Pid)
 
 		// if there are no more trace instances for a Go program, we need to notify that
 		// the tracer needs to be stopped and deleted.
 		// We don't remove kernel-based traces as there is only one tracer per host
 		if tracer.Type != ebpf.Generic && ta.processInstances.Dec(ie.FileInfo.Ino) == 0 {
 			delete(ta.existingTracers, ie.FileInfo.Ino)
+			ta.state.RemoveExecutable(ie.FileInfo.Ino)
 			ta.DeleteTracers <- ie
 		}
 	}
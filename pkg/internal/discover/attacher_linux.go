@@ -9,6 +9,13 @@ import (
 )
 
 func (ta *TraceAttacher) close() {
+	if ta.Cfg.EBPF.PersistentState {
+		ta.log.Debug("keeping the pinned eBPF state for the next Beyla process", "path", ta.pinPath)
+		if err := ta.state.Close(); err != nil {
+			ta.log.Debug("can't release the pinned eBPF state", "error", err)
+		}
+		return
+	}
 	ta.unmountBpfPinPath()
 }
 
@@ -23,10 +30,21 @@ func (ta *TraceAttacher) mountBpfPinPath() error {
 			return fmt.Errorf("creating directory %s: %w", ta.pinPath, err)
 		}
 	}
+	// the pin path of the persistent state might be already mounted by a former Beyla process,
+	// or be part of a BPF file system that is mounted by the host
+	if ta.Cfg.EBPF.PersistentState && isBpfFS(ta.pinPath) {
+		ta.log.Debug("BPF file system is already mounted", "path", ta.pinPath)
+		return nil
+	}
 
 	return bpfMount(ta.pinPath)
 }
 
+func isBpfFS(dir string) bool {
+	var st unix.Statfs_t
+	return unix.Statfs(dir, &st) == nil && st.Type == unix.BPF_FS_MAGIC
+}
+
 func (ta *TraceAttacher) unmountBpfPinPath() {
 	if err := unix.Unmount(ta.pinPath, unix.MNT_FORCE); err != nil {
 		ta.log.Warn("can't unmount pinned root. Try unmounting and removing it manually", err)
@@ -0,0 +1,115 @@
+package discover
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/docker"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// DryRunResult describes whether a running process would be instrumented with the
+// current discovery configuration, and how.
+type DryRunResult struct {
+	PID       int32
+	ExePath   string
+	OpenPorts []uint32
+	// CriteriaIndex of the matched discovery.services entry. It is -1 if the process is not selected
+	// or if it is selected by other means (e.g. Pod annotations or parent process)
+	CriteriaIndex int
+	// Instrumented is true if the process would be instrumented
+	Instrumented bool
+	// ServiceName that would be reported for the instrumented process
+	ServiceName string
+	// Type of the instrumented executable (Go, Java, Python...)
+	Type svc.InstrumentableType
+	// Reason why the process would not be instrumented
+	Reason string
+}
+
+// DryRun lists the running processes once and evaluates them as the ProcessWatcher, CriteriaMatcher
+// and ExecTyper nodes of the ProcessFinder pipeline would do, but without loading any eBPF program.
+// It returns the results sorted by PID.
+// The Kubernetes metadata is not available in dry-run mode, so the processes can't be selected
+// by any Kubernetes selector.
+func DryRun(ctx context.Context, cfg *beyla.Config) ([]DryRunResult, error) {
+	procs, err := fetchProcessPorts(true)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event[processAttrs], 0, len(procs))
+	for _, proc := range procs {
+		events = append(events, Event[processAttrs]{Type: EventCreated, Obj: proc})
+	}
+	// parent processes are evaluated first, so their children can be selected by parent PID
+	slices.SortFunc(events, func(a, b Event[processAttrs]) int {
+		return cmp.Compare(a.Obj.pid, b.Obj.pid)
+	})
+	if cfg.Attributes.Container.Enable {
+		inspector, err := docker.NewInspector(&cfg.Attributes.Container)
+		if err != nil {
+			return nil, fmt.Errorf("can't instantiate container runtime client: %w", err)
+		}
+		enricher, err := newContainerEnricher(&ContainerEnricher{Ctx: ctx, Client: inspector})
+		if err != nil {
+			return nil, fmt.Errorf("can't instantiate container enricher: %w", err)
+		}
+		events = enricher.enrich(events)
+	}
+	return dryRun(cfg, events), nil
+}
+
+func dryRun(cfg *beyla.Config, events []Event[processAttrs]) []DryRunResult {
+	m := newMatcher(&CriteriaMatcher{Cfg: cfg})
+	results := make([]DryRunResult, 0, len(events))
+	var matches []Event[ProcessMatch]
+	for i := range events {
+		obj := &events[i].Obj
+		result := DryRunResult{PID: int32(obj.pid), OpenPorts: obj.openPorts, CriteriaIndex: -1}
+		proc, err := processInfo(*obj, m.processAttributes)
+		if err != nil {
+			result.Reason = "can't get process information: " + err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.ExePath = proc.ExePath
+		match, mr := m.evaluate(obj, proc)
+		result.CriteriaIndex = mr.criteriaIndex
+		result.Reason = mr.reason
+		if mr.reason == "" {
+			matches = append(matches, match)
+		}
+		results = append(results, result)
+	}
+
+	byPID := make(map[int32]*DryRunResult, len(results))
+	for i := range results {
+		byPID[results[i].PID] = &results[i]
+	}
+	t := newTyper(&ExecTyper{Cfg: cfg, Metrics: imetrics.NoopReporter{}})
+	for _, ev := range t.FilterClassify(matches) {
+		inst := &ev.Obj
+		serviceName := inst.FileInfo.Service.Name
+		if serviceName == "" {
+			serviceName = inst.FileInfo.ExecutableName()
+		}
+		// child processes are instrumented through their parent process
+		for _, pid := range append([]uint32{uint32(inst.FileInfo.Pid)}, inst.ChildPids...) {
+			if result, ok := byPID[int32(pid)]; ok {
+				result.Instrumented = true
+				result.ServiceName = serviceName
+				result.Type = inst.Type
+			}
+		}
+	}
+	for _, ev := range matches {
+		if result := byPID[ev.Obj.Process.Pid]; !result.Instrumented {
+			result.Reason = "can't inspect the executable file"
+		}
+	}
+	return results
+}
@@ -0,0 +1,74 @@
+package discover
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+func TestDryRun(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  skip_go_specific_tracers: true
+  services:
+  - name: server
+    exe_path: server
+  - open_ports: 8080
+  exclude_services:
+  - exe_path: excluded
+`), &pipeConfig))
+
+	// the executable of the test process is the only one that can be inspected
+	selfPID := PID(os.Getpid())
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		switch pp.pid {
+		case selfPID:
+			return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: "/bin/server", OpenPorts: pp.openPorts}, nil
+		case 1:
+			return &services.ProcessInfo{Pid: 1, ExePath: "/bin/excluded-server", OpenPorts: pp.openPorts}, nil
+		case 2:
+			return &services.ProcessInfo{Pid: 2, ExePath: "/bin/client", OpenPorts: pp.openPorts}, nil
+		case 3:
+			return &services.ProcessInfo{Pid: 3, ExePath: "/bin/proxy", OpenPorts: pp.openPorts}, nil
+		}
+		return nil, errors.New("process not found")
+	}
+
+	results := dryRun(&pipeConfig, []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}},
+		{Type: EventCreated, Obj: processAttrs{pid: 2, openPorts: []uint32{443}}},
+		{Type: EventCreated, Obj: processAttrs{pid: 3, openPorts: []uint32{8080}}},
+		{Type: EventCreated, Obj: processAttrs{pid: 4}},
+		{Type: EventCreated, Obj: processAttrs{pid: selfPID}},
+	})
+	require.Len(t, results, 5)
+
+	assert.False(t, results[0].Instrumented)
+	assert.Equal(t, 0, results[0].CriteriaIndex)
+	assert.Equal(t, "excluded by exclude_services[0]", results[0].Reason)
+
+	assert.False(t, results[1].Instrumented)
+	assert.Equal(t, -1, results[1].CriteriaIndex)
+	assert.Equal(t, "not matching services[0].exe_path, services[1].open_ports", results[1].Reason)
+
+	// the process is matched, but its executable file can't be inspected
+	assert.False(t, results[2].Instrumented)
+	assert.Equal(t, 1, results[2].CriteriaIndex)
+	assert.Equal(t, "can't inspect the executable file", results[2].Reason)
+
+	assert.False(t, results[3].Instrumented)
+	assert.Contains(t, results[3].Reason, "process not found")
+
+	assert.True(t, results[4].Instrumented)
+	assert.Equal(t, 0, results[4].CriteriaIndex)
+	assert.Equal(t, "/bin/server", results[4].ExePath)
+	assert.Equal(t, "server", results[4].ServiceName)
+	assert.Empty(t, results[4].Reason)
+}
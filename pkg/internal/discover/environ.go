@@ -0,0 +1,136 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+const (
+	envServiceName        = "OTEL_SERVICE_NAME"
+	envResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+
+	resourceServiceName       = "service.name"
+	resourceServiceNamespace  = "service.namespace"
+	resourceServiceInstanceID = "service.instance.id"
+)
+
+// replaceable function to allow unit tests with faked process environments
+var procEnviron = func(pid int32) ([]string, error) {
+	content, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+	var environ []string
+	for _, entry := range bytes.Split(content, []byte{0}) {
+		if len(entry) > 0 {
+			environ = append(environ, string(entry))
+		}
+	}
+	return environ, nil
+}
+
+// envServiceInfo contains the service information that is defined in the OTEL_SERVICE_NAME
+// and OTEL_RESOURCE_ATTRIBUTES variables of the process environment
+type envServiceInfo struct {
+	name      string
+	namespace string
+	// attributes contains the rest of resource attributes
+	attributes map[string]string
+}
+
+func parseEnvServiceInfo(environ []string) envServiceInfo {
+	info := envServiceInfo{}
+	var serviceName string
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case envServiceName:
+			serviceName = value
+		case envResourceAttributes:
+			info.parseResourceAttributes(value)
+		}
+	}
+	// as in the OpenTelemetry SDKs, OTEL_SERVICE_NAME takes precedence over the
+	// service.name resource attribute
+	if serviceName != "" {
+		info.name = serviceName
+	}
+	return info
+}
+
+// parseResourceAttributes from a comma-separated list of key=value pairs, whose values
+// can be percent-encoded
+func (info *envServiceInfo) parseResourceAttributes(attrs string) {
+	for _, attr := range strings.Split(attrs, ",") {
+		key, value, ok := strings.Cut(attr, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		switch key {
+		case resourceServiceName:
+			info.name = value
+		case resourceServiceNamespace:
+			info.namespace = value
+		case resourceServiceInstanceID:
+			// ignoring it, as Beyla sets its own service instance ID
+		default:
+			if info.attributes == nil {
+				info.attributes = map[string]string{}
+			}
+			info.attributes[key] = value
+		}
+	}
+}
+
+// decorate the service ID with the information from the environment, according to the precedence
+// of the environment with respect to the discovery configuration and Kubernetes metadata.
+func (info *envServiceInfo) decorate(id *svc.ID, precedence services.EnvPrecedence) {
+	switch precedence {
+	case services.EnvPrecedenceHighest:
+		if info.name != "" {
+			id.Name = info.name
+		}
+		if info.namespace != "" {
+			id.Namespace = info.namespace
+		}
+	case services.EnvPrecedenceOverKubernetes:
+		if id.Name == "" {
+			id.Name = info.name
+		}
+		if id.Namespace == "" {
+			id.Namespace = info.namespace
+		}
+	case services.EnvPrecedenceLowest:
+		// the Kubernetes metadata decorator will override the values that are automatically set
+		if id.Name == "" && info.name != "" {
+			id.Name = info.name
+			id.AutoName = true
+		}
+		if id.Namespace == "" && info.namespace != "" {
+			id.Namespace = info.namespace
+			id.AutoNamespace = true
+		}
+	default:
+		return
+	}
+	if len(info.attributes) > 0 {
+		metadata := make(map[string]string, len(id.Metadata)+len(info.attributes))
+		maps.Copy(metadata, id.Metadata)
+		maps.Copy(metadata, info.attributes)
+		id.Metadata = metadata
+	}
+}
@@ -0,0 +1,77 @@
+package discover
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+func TestParseEnvServiceInfo(t *testing.T) {
+	info := parseEnvServiceInfo([]string{
+		"PATH=/bin:/usr/bin",
+		"OTEL_RESOURCE_ATTRIBUTES=service.name=from-attrs, service.namespace=shop,service.instance.id=foo," +
+			"deployment.environment=prod,team=checkout%20and%20payments,invalid",
+		"OTEL_SERVICE_NAME=checkout",
+	})
+	assert.Equal(t, envServiceInfo{
+		name:      "checkout",
+		namespace: "shop",
+		attributes: map[string]string{
+			"deployment.environment": "prod",
+			"team":                   "checkout and payments",
+		},
+	}, info)
+
+	info = parseEnvServiceInfo([]string{"OTEL_RESOURCE_ATTRIBUTES=service.name=from-attrs"})
+	assert.Equal(t, envServiceInfo{name: "from-attrs"}, info)
+
+	assert.Equal(t, envServiceInfo{}, parseEnvServiceInfo([]string{"HOME=/root"}))
+}
+
+func TestEnvServiceInfo_Decorate(t *testing.T) {
+	info := envServiceInfo{name: "env-name", namespace: "env-ns", attributes: map[string]string{"foo": "bar"}}
+	type testCase struct {
+		precedence services.EnvPrecedence
+		input      svc.ID
+		expected   svc.ID
+	}
+	for _, tc := range []testCase{{
+		precedence: services.EnvPrecedenceHighest,
+		input:      svc.ID{Name: "cfg-name", Namespace: "cfg-ns"},
+		expected:   svc.ID{Name: "env-name", Namespace: "env-ns", Metadata: info.attributes},
+	}, {
+		precedence: services.EnvPrecedenceOverKubernetes,
+		input:      svc.ID{Name: "cfg-name"},
+		expected:   svc.ID{Name: "cfg-name", Namespace: "env-ns", Metadata: info.attributes},
+	}, {
+		precedence: services.EnvPrecedenceLowest,
+		input:      svc.ID{Namespace: "cfg-ns"},
+		expected: svc.ID{Name: "env-name", AutoName: true, Namespace: "cfg-ns",
+			Metadata: info.attributes},
+	}, {
+		precedence: services.EnvPrecedenceDisabled,
+		input:      svc.ID{Name: "cfg-name"},
+		expected:   svc.ID{Name: "cfg-name"},
+	}} {
+		t.Run(string(tc.precedence), func(t *testing.T) {
+			id := tc.input
+			info.decorate(&id, tc.precedence)
+			assert.Equal(t, tc.expected, id)
+		})
+	}
+}
+
+func TestEnvServiceInfo_DecorateDoesNotModifySharedMetadata(t *testing.T) {
+	info := envServiceInfo{attributes: map[string]string{"foo": "bar"}}
+	shared := map[string]string{"baz": "bae"}
+	id := svc.ID{Name: "cfg-name", Metadata: shared}
+	info.decorate(&id, services.EnvPrecedenceOverKubernetes)
+	assert.Equal(t, map[string]string{"foo": "bar", "baz": "bae"}, id.Metadata)
+	assert.Equal(t, map[string]string{"baz": "bae"}, shared)
+
+	id.Metadata["other"] = "value"
+	assert.Equal(t, map[string]string{"foo": "bar"}, info.attributes)
+}
@@ -3,11 +3,13 @@ package discover
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/mariomac/pipes/pkg/graph"
 	"github.com/mariomac/pipes/pkg/node"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/docker"
 	"github.com/grafana/beyla/pkg/internal/ebpf"
 	"github.com/grafana/beyla/pkg/internal/ebpf/goruntime"
 	"github.com/grafana/beyla/pkg/internal/ebpf/grpc"
@@ -22,20 +24,24 @@ import (
 // Nodes tagged as "forwardTo" are optional nodes that might not be instantiated. In that case, any
 // information directed to them will be automatically forwarded to the next pipeline stage.
 // For example WatcherKubeEnricher and ContainerDBUpdater will be only enabled
-// (non-nil values) if Kubernetes decoration is enabled
+// (non-nil values) if Kubernetes decoration is enabled, and ContainerEnricher will be only
+// enabled if the container metadata decoration is enabled
 type ProcessFinder struct {
 	ProcessWatcher       `sendTo:"WatcherKubeEnricher"`
-	*WatcherKubeEnricher `forwardTo:"CriteriaMatcher"`
+	*WatcherKubeEnricher `forwardTo:"ContainerEnricher"`
+	*ContainerEnricher   `forwardTo:"CriteriaMatcher"`
 	CriteriaMatcher      `sendTo:"ExecTyper"`
 	ExecTyper            `sendTo:"ContainerDBUpdater"`
 	*ContainerDBUpdater  `forwardTo:"TraceAttacher"`
 	TraceAttacher
 }
 
-func NewProcessFinder(ctx context.Context, cfg *beyla.Config, ctxInfo *global.ContextInfo) *ProcessFinder {
+// NewProcessFinder instantiates the ProcessFinder pipeline. If the reloads channel is not nil, the discovery
+// criteria are replaced by the ones of each configuration received from it.
+func NewProcessFinder(ctx context.Context, cfg *beyla.Config, ctxInfo *global.ContextInfo, reloads <-chan *beyla.Config) *ProcessFinder {
 	processFinder := ProcessFinder{
 		ProcessWatcher:  ProcessWatcher{Ctx: ctx, Cfg: cfg},
-		CriteriaMatcher: CriteriaMatcher{Cfg: cfg},
+		CriteriaMatcher: CriteriaMatcher{Cfg: cfg, Reloads: reloads},
 		ExecTyper:       ExecTyper{Cfg: cfg, Metrics: ctxInfo.Metrics},
 		TraceAttacher: TraceAttacher{
 			Cfg:               cfg,
@@ -43,12 +49,21 @@ func NewProcessFinder(ctx context.Context, cfg *beyla.Config, ctxInfo *global.Co
 			DiscoveredTracers: make(chan *ebpf.ProcessTracer),
 			DeleteTracers:     make(chan *Instrumentable),
 			Metrics:           ctxInfo.Metrics,
+			Registry:          ctxInfo.AdminRegistry,
 		},
 	}
 	if ctxInfo.K8sEnabled {
 		processFinder.ContainerDBUpdater = &ContainerDBUpdater{DB: ctxInfo.K8sDatabase}
 		processFinder.WatcherKubeEnricher = &WatcherKubeEnricher{Informer: ctxInfo.K8sInformer}
 	}
+	if cfg.Attributes.Container.Enable {
+		if inspector, err := docker.NewInspector(&cfg.Attributes.Container); err != nil {
+			slog.With("component", "discover.ProcessFinder").
+				Warn("can't create the container runtime client. Container metadata won't be added", "error", err)
+		} else {
+			processFinder.ContainerEnricher = &ContainerEnricher{Ctx: ctx, Client: inspector}
+		}
+	}
 	return &processFinder
 }
 
@@ -58,6 +73,7 @@ func (pf *ProcessFinder) Start(cfg *beyla.Config) (<-chan *ebpf.ProcessTracer, <
 	gb := graph.NewBuilder(node.ChannelBufferLen(cfg.ChannelBufferLen))
 	graph.RegisterStart(gb, ProcessWatcherProvider)
 	graph.RegisterMiddle(gb, WatcherKubeEnricherProvider)
+	graph.RegisterMiddle(gb, ContainerEnricherProvider)
 	graph.RegisterMiddle(gb, CriteriaMatcherProvider)
 	graph.RegisterMiddle(gb, ExecTyperProvider)
 	graph.RegisterMiddle(gb, ContainerDBUpdaterProvider)
@@ -0,0 +1,28 @@
+package discover
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+func TestTracers_AccountDroppedEvents(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		cfg := beyla.DefaultConfig
+		cfg.EBPF.BpfDebug = debug
+		tracers := append(newGoTracersGroup(&cfg, imetrics.NoopReporter{}),
+			newNonGoTracersGroup(&cfg, imetrics.NoopReporter{})...)
+		for _, tracer := range tracers {
+			spec, err := tracer.Load()
+			require.NoError(t, err)
+			// the programs sending events to the ring buffer must account the events that are dropped
+			if _, ok := spec.Maps["events"]; ok {
+				assert.Containsf(t, spec.Maps, "beyla_stats", "tracer %T (debug: %v)", tracer, debug)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package discover
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/kube"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+// Pod annotations (or labels) that allow application owners to drive the discovery
+// of their services without modifying the Beyla configuration
+const (
+	hintInstrument       = kube.AnnotationPrefix + "instrument"
+	hintServiceName      = kube.AnnotationPrefix + "service-name"
+	hintServiceNamespace = kube.AnnotationPrefix + "service-namespace"
+	hintRoutes           = kube.AnnotationPrefix + "routes"
+	hintIgnoredRoutes    = kube.AnnotationPrefix + "ignored-routes"
+)
+
+// kubeHints contains the discovery information provided by the annotations and labels of a Pod
+type kubeHints struct {
+	// instrument is nil if the Pod does not explicitly opt in or out of the instrumentation
+	instrument *bool
+	name       string
+	namespace  string
+	routes     *svc.RouteHints
+}
+
+// kubeHintsFrom reads the discovery hints from the process' Pod annotations and labels.
+// Annotations take precedence over labels with the same key.
+func kubeHintsFrom(obj *processAttrs) kubeHints {
+	hint := func(key string) string {
+		if val, ok := obj.podAnnotations[key]; ok {
+			return val
+		}
+		return obj.podLabels[key]
+	}
+	hints := kubeHints{
+		name:      hint(hintServiceName),
+		namespace: hint(hintServiceNamespace),
+	}
+	if instrument, err := strconv.ParseBool(hint(hintInstrument)); err == nil {
+		hints.instrument = &instrument
+	}
+	patterns, ignored := splitPatterns(hint(hintRoutes)), splitPatterns(hint(hintIgnoredRoutes))
+	if len(patterns) > 0 || len(ignored) > 0 {
+		hints.routes = &svc.RouteHints{Patterns: patterns, IgnorePatterns: ignored}
+	}
+	return hints
+}
+
+func (h *kubeHints) optedOut() bool {
+	return h.instrument != nil && !*h.instrument
+}
+
+func (h *kubeHints) optedIn() bool {
+	return h.instrument != nil && *h.instrument
+}
+
+// withCriteria returns the selection criteria whose name and namespace are overridden by the hints, if any
+func (h *kubeHints) withCriteria(criteria *services.Attributes) *services.Attributes {
+	if h.name == "" && h.namespace == "" {
+		return criteria
+	}
+	merged := *criteria
+	if h.name != "" {
+		merged.Name = h.name
+	}
+	if h.namespace != "" {
+		merged.Namespace = h.namespace
+	}
+	return &merged
+}
+
+// splitPatterns from a comma-separated list of route patterns
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
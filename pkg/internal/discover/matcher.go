@@ -4,54 +4,120 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"reflect"
 	"regexp"
 	"slices"
+	"strings"
 
 	"github.com/mariomac/pipes/pkg/node"
 	"github.com/shirou/gopsutil/process"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/helpers/systemd"
+	"github.com/grafana/beyla/pkg/internal/svc"
 	"github.com/grafana/beyla/pkg/services"
 )
 
+// attrSystemdUnit is the resource attribute containing the systemd unit of the process
+const attrSystemdUnit = "systemd.unit"
+
 // CriteriaMatcher filters the processes that match the discovery criteria.
 type CriteriaMatcher struct {
 	Cfg *beyla.Config
+	// Reloads, if not nil, provides new configurations whose discovery criteria replace the current ones
+	Reloads <-chan *beyla.Config
 }
 
 func CriteriaMatcherProvider(cm CriteriaMatcher) (node.MiddleFunc[[]Event[processAttrs], []Event[ProcessMatch]], error) {
+	return newMatcher(&cm).run, nil
+}
+
+func newMatcher(cm *CriteriaMatcher) *matcher {
 	m := &matcher{
 		log:            slog.With("component", "discover.CriteriaMatcher"),
-		criteria:       FindingCriteria(cm.Cfg),
-		processHistory: map[PID]*services.ProcessInfo{},
+		processHistory: map[PID]ProcessMatch{},
+		processes:      map[PID]processAttrs{},
+		kubeHints:      cm.Cfg.Discovery.K8sAnnotations,
+		reloads:        cm.Reloads,
 	}
-	return m.run, nil
+	m.setCriteria(cm.Cfg)
+	if cm.Cfg.Discovery.SystemWide {
+		m.systemWide = true
+		m.systemWideFilter = ebpfcommon.CommonPIDsFilter(true)
+		m.excludedPIDs = map[PID]struct{}{}
+	}
+	return m
 }
 
 type matcher struct {
-	log      *slog.Logger
-	criteria services.DefinitionCriteria
+	log       *slog.Logger
+	criteria  services.DefinitionCriteria
+	exclusion services.DefinitionCriteria
+	// processAttributes is true if any selection or exclusion criteria requires reading the
+	// command line, user or parent executable of the processes
+	processAttributes bool
 	// processHistory keeps track of the processes that have been already matched and submitted for
 	// instrumentation.
 	// This avoids keep inspecting again and again client processes each time they open a new connection port
-	processHistory map[PID]*services.ProcessInfo
+	processHistory map[PID]ProcessMatch
+	// processes that are currently running, whether they are matched or not. They are evaluated again
+	// when the discovery criteria are reloaded
+	processes map[PID]processAttrs
+
+	// in system-wide mode, a single process needs to be submitted to create the system-wide
+	// instrumenter. Excluded processes are later discarded by blocking them in the
+	// system-wide PIDs filter.
+	systemWide        bool
+	systemWideFilter  ebpfcommon.ServiceFilter
+	systemWideStarted bool
+	excludedPIDs      map[PID]struct{}
+
+	// kubeHints enables the selection of processes from their Pod annotations and labels
+	kubeHints bool
+
+	reloads <-chan *beyla.Config
 }
 
 // ProcessMatch matches a found process with the first selection criteria it fulfilled.
 type ProcessMatch struct {
 	Criteria *services.Attributes
 	Process  *services.ProcessInfo
+	// RouteHints, if not nil, override the routes configuration for the matched process
+	RouteHints *svc.RouteHints
+	// ResourceAttributes that will be added to the metadata of the service (e.g. container.name)
+	ResourceAttributes map[string]string
+	// MatchedBy describes what selected the process: a discovery.services entry (e.g. "services[0]"),
+	// the Pod annotations or the parent process
+	MatchedBy string
 }
 
+const (
+	matchedByAnnotations = "pod annotations"
+	matchedByParent      = "parent process"
+)
+
 func (m *matcher) run(in <-chan []Event[processAttrs], out chan<- []Event[ProcessMatch]) {
 	m.log.Debug("starting criteria matcher node")
-	for i := range in {
-		m.log.Debug("filtering processes", "len", len(i))
-		o := m.filter(i)
-		m.log.Debug("processes matching selection criteria", "len", len(o))
-		if len(o) > 0 {
-			out <- o
+	for {
+		select {
+		case i, ok := <-in:
+			if !ok {
+				return
+			}
+			m.log.Debug("filtering processes", "len", len(i))
+			o := m.filter(i)
+			m.log.Debug("processes matching selection criteria", "len", len(o))
+			if len(o) > 0 {
+				out <- o
+			}
+		case cfg := <-m.reloads:
+			m.log.Info("reloading discovery criteria")
+			if o := m.reload(cfg); len(o) > 0 {
+				out <- o
+			}
 		}
 	}
 }
@@ -69,50 +135,239 @@ func (m *matcher) filter(events []Event[processAttrs]) []Event[ProcessMatch] {
 			}
 		}
 	}
+	if m.systemWide {
+		return m.systemWideMatches(matches)
+	}
 	return matches
 }
 
+// systemWideMatches only forwards the first matching process, which is required to
+// create the single system-wide instrumenter
+func (m *matcher) systemWideMatches(matches []Event[ProcessMatch]) []Event[ProcessMatch] {
+	if m.systemWideStarted {
+		return nil
+	}
+	for _, ev := range matches {
+		if ev.Type == EventCreated {
+			m.systemWideStarted = true
+			return []Event[ProcessMatch]{ev}
+		}
+	}
+	return nil
+}
+
 func (m *matcher) filterCreated(obj processAttrs) (Event[ProcessMatch], bool) {
+	m.processes[obj.pid] = obj
 	if _, ok := m.processHistory[obj.pid]; ok {
 		// this was already matched and submitted for inspection. Ignoring!
 		return Event[ProcessMatch]{}, false
 	}
-	proc, err := processInfo(obj)
+	proc, err := processInfo(obj, m.processAttributes)
 	if err != nil {
 		m.log.Debug("can't get information for process", "pid", obj.pid, "error", err)
 		return Event[ProcessMatch]{}, false
 	}
+	ev, result := m.evaluate(&obj, proc)
+	return ev, result.reason == ""
+}
+
+func (m *matcher) setCriteria(cfg *beyla.Config) {
+	m.criteria = FindingCriteria(cfg)
+	m.exclusion = ExclusionCriteria(cfg)
+	m.processAttributes = false
+	for _, criteria := range []services.DefinitionCriteria{m.criteria, m.exclusion} {
+		for i := range criteria {
+			if criteria[i].HasProcessAttributes() {
+				m.processAttributes = true
+			}
+		}
+	}
+}
+
+// matchResult describes the outcome of evaluating a process against the selection criteria
+type matchResult struct {
+	// criteriaIndex of the matched discovery.services entry. It is -1 if the process is not selected
+	// or if it is selected by other means (e.g. Pod annotations or parent process)
+	criteriaIndex int
+	// reason why the process is not selected. Empty if the process is selected
+	reason string
+}
+
+// evaluate the process against the selection and exclusion criteria. If the process is selected,
+// it is returned as a matched event.
+func (m *matcher) evaluate(obj *processAttrs, proc *services.ProcessInfo) (Event[ProcessMatch], matchResult) {
+	var hints kubeHints
+	if m.kubeHints {
+		hints = kubeHintsFrom(obj)
+		if hints.optedOut() {
+			m.log.Debug("process opted out by its Pod annotations", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata)
+			m.blockSystemWide(obj.pid)
+			return Event[ProcessMatch]{}, matchResult{criteriaIndex: -1, reason: "opted out by the Pod annotations"}
+		}
+	}
+	unmatched := make([]string, 0, len(m.criteria))
 	for i := range m.criteria {
-		if m.matchProcess(&obj, proc, &m.criteria[i]) {
-			m.log.Debug("found process", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata, "podLabels", obj.podLabels)
-			m.processHistory[obj.pid] = proc
-			return Event[ProcessMatch]{
-				Type: EventCreated,
-				Obj:  ProcessMatch{Criteria: &m.criteria[i], Process: proc},
-			}, true
+		if selector := m.unmatchedSelector(obj, proc, &m.criteria[i]); selector != "" {
+			unmatched = append(unmatched, fmt.Sprintf("services[%d].%s", i, selector))
+			continue
+		}
+		if excl := m.excludedBy(obj, proc); excl >= 0 {
+			return Event[ProcessMatch]{}, matchResult{criteriaIndex: i, reason: fmt.Sprintf("excluded by exclude_services[%d]", excl)}
+		}
+		m.log.Debug("found process", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata, "podLabels", obj.podLabels)
+		return m.matched(obj, proc, hints.withCriteria(&m.criteria[i]), hints.routes, fmt.Sprintf("services[%d]", i)),
+			matchResult{criteriaIndex: i}
+	}
+
+	if hints.optedIn() {
+		if excl := m.excludedBy(obj, proc); excl >= 0 {
+			return Event[ProcessMatch]{}, matchResult{criteriaIndex: -1, reason: fmt.Sprintf("excluded by exclude_services[%d]", excl)}
 		}
+		m.log.Debug("found process by its Pod annotations", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata)
+		return m.matched(obj, proc, hints.withCriteria(&services.Attributes{}), hints.routes, matchedByAnnotations), matchResult{criteriaIndex: -1}
 	}
 
 	// We didn't match the process, but let's see if the parent PID is tracked, it might be the child hasn't opened the port yet
 	if _, ok := m.processHistory[PID(proc.PPid)]; ok {
+		if excl := m.excludedBy(obj, proc); excl >= 0 {
+			return Event[ProcessMatch]{}, matchResult{criteriaIndex: -1, reason: fmt.Sprintf("excluded by exclude_services[%d]", excl)}
+		}
 		m.log.Debug("found process by matching the process parent id", "pid", proc.Pid, "ppid", proc.PPid, "comm", proc.ExePath, "metadata", obj.metadata)
-		m.processHistory[obj.pid] = proc
-		return Event[ProcessMatch]{
-			Type: EventCreated,
-			Obj:  ProcessMatch{Criteria: &m.criteria[0], Process: proc},
-		}, true
+		// the selection criteria might be empty if the parent was selected by its Pod annotations
+		criteria := &services.Attributes{}
+		if len(m.criteria) > 0 {
+			criteria = &m.criteria[0]
+		}
+		return m.matched(obj, proc, hints.withCriteria(criteria), hints.routes, matchedByParent), matchResult{criteriaIndex: -1}
+	}
+
+	if len(unmatched) == 0 {
+		return Event[ProcessMatch]{}, matchResult{criteriaIndex: -1, reason: "no selection criteria defined"}
 	}
+	return Event[ProcessMatch]{}, matchResult{criteriaIndex: -1, reason: "not matching " + strings.Join(unmatched, ", ")}
+}
 
-	return Event[ProcessMatch]{}, false
+func (m *matcher) matched(obj *processAttrs, proc *services.ProcessInfo, criteria *services.Attributes, routes *svc.RouteHints, matchedBy string) Event[ProcessMatch] {
+	resourceAttributes := obj.resourceAttributes
+	if proc.SystemdUnit != "" {
+		resourceAttributes = make(map[string]string, len(obj.resourceAttributes)+1)
+		maps.Copy(resourceAttributes, obj.resourceAttributes)
+		resourceAttributes[attrSystemdUnit] = proc.SystemdUnit
+	}
+	match := ProcessMatch{
+		Criteria:           criteria,
+		Process:            proc,
+		RouteHints:         routes,
+		ResourceAttributes: resourceAttributes,
+		MatchedBy:          matchedBy,
+	}
+	m.processHistory[obj.pid] = match
+	return Event[ProcessMatch]{Type: EventCreated, Obj: match}
+}
+
+// sameService returns true if both matches would instrument the process with the same
+// service properties (name, namespace, route hints...)
+func sameService(a, b *ProcessMatch) bool {
+	return a.Criteria.Name == b.Criteria.Name &&
+		a.Criteria.Namespace == b.Criteria.Namespace &&
+		a.Criteria.ExporterProfile == b.Criteria.ExporterProfile &&
+		a.MatchedBy == b.MatchedBy &&
+		reflect.DeepEqual(a.RouteHints, b.RouteHints) &&
+		maps.Equal(a.ResourceAttributes, b.ResourceAttributes)
+}
+
+// excludedBy returns the index of the first exclusion criteria that the process matches, or -1
+// if the process is not excluded. In system-wide mode, the excluded processes are blocked in the
+// system-wide PIDs filter
+func (m *matcher) excludedBy(obj *processAttrs, proc *services.ProcessInfo) int {
+	for i := range m.exclusion {
+		if m.matchProcess(obj, proc, &m.exclusion[i]) {
+			m.log.Debug("excluding process", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata)
+			m.blockSystemWide(obj.pid)
+			return i
+		}
+	}
+	return -1
+}
+
+// reload replaces the selection and exclusion criteria by the ones in the provided configuration, and
+// evaluates again all the running processes. It returns creation events for the processes that are
+// matched by the new criteria, and deletion events for the processes that are not matched anymore.
+// The processes that are still matched are only notified again, as a deletion followed by a creation,
+// if the new criteria change their service properties (e.g. the service name).
+func (m *matcher) reload(cfg *beyla.Config) []Event[ProcessMatch] {
+	if m.systemWide || cfg.Discovery.SystemWide {
+		m.log.Warn("discovery criteria can't be reloaded in system-wide mode. Ignoring")
+		return nil
+	}
+	attributesRead := m.processAttributes
+	m.setCriteria(cfg)
+	m.kubeHints = cfg.Discovery.K8sAnnotations
+
+	previous := m.processHistory
+	m.processHistory = make(map[PID]ProcessMatch, len(previous))
+	// sorting by PID to evaluate the parent processes before their children
+	pids := make([]PID, 0, len(m.processes))
+	for pid := range m.processes {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+	var events []Event[ProcessMatch]
+	for _, pid := range pids {
+		obj := m.processes[pid]
+		// the process information is read again if the process wasn't matched, or if the new
+		// criteria require process attributes that weren't read before
+		match, ok := previous[pid]
+		proc := match.Process
+		if !ok {
+			var err error
+			if proc, err = processInfo(obj, m.processAttributes); err != nil {
+				m.log.Debug("can't get information for process", "pid", pid, "error", err)
+				continue
+			}
+		} else if m.processAttributes && !attributesRead {
+			if current, err := processInfo(obj, true); err == nil {
+				proc = current
+			}
+		}
+		ev, result := m.evaluate(&obj, proc)
+		switch {
+		case result.reason == "" && !ok:
+			events = append(events, ev)
+		case result.reason != "" && ok:
+			m.log.Debug("process does not match the new criteria", "pid", pid, "comm", proc.ExePath, "reason", result.reason)
+			events = append(events, Event[ProcessMatch]{Type: EventDeleted, Obj: ProcessMatch{Process: proc}})
+		case result.reason == "" && !sameService(&match, &ev.Obj):
+			// the process is instrumented again, to update its service properties
+			m.log.Debug("process matches the new criteria with a different service", "pid", pid, "comm", proc.ExePath)
+			events = append(events, Event[ProcessMatch]{Type: EventDeleted, Obj: ProcessMatch{Process: proc}}, ev)
+		}
+	}
+	return events
+}
+
+// blockSystemWide discards the spans of an excluded process, if running in system-wide mode
+func (m *matcher) blockSystemWide(pid PID) {
+	if m.systemWide {
+		m.excludedPIDs[pid] = struct{}{}
+		m.systemWideFilter.BlockPID(uint32(pid))
+	}
 }
 
 func (m *matcher) filterDeleted(obj processAttrs) (Event[ProcessMatch], bool) {
-	proc, ok := m.processHistory[obj.pid]
+	delete(m.processes, obj.pid)
+	if _, ok := m.excludedPIDs[obj.pid]; ok {
+		// the PID might be reused by another process, so we stop blocking it
+		delete(m.excludedPIDs, obj.pid)
+		m.systemWideFilter.AllowPID(uint32(obj.pid), svc.ID{}, ebpfcommon.PIDTypeKProbes)
+	}
+	match, ok := m.processHistory[obj.pid]
 	if !ok {
 		m.log.Debug("deleted untracked process. Ignoring", "pid", obj.pid)
 		return Event[ProcessMatch]{}, false
 	}
 	delete(m.processHistory, obj.pid)
+	proc := match.Process
 	m.log.Debug("stopped process", "pid", proc.Pid, "comm", proc.ExePath)
 	return Event[ProcessMatch]{
 		Type: EventDeleted,
@@ -121,19 +376,31 @@ func (m *matcher) filterDeleted(obj processAttrs) (Event[ProcessMatch], bool) {
 }
 
 func (m *matcher) matchProcess(obj *processAttrs, p *services.ProcessInfo, a *services.Attributes) bool {
+	return m.unmatchedSelector(obj, p, a) == ""
+}
+
+// unmatchedSelector returns the name of the first selector property of the criteria that
+// the process does not fulfill, or an empty string if the process matches the criteria
+func (m *matcher) unmatchedSelector(obj *processAttrs, p *services.ProcessInfo, a *services.Attributes) string {
 	if !a.Path.IsSet() && a.OpenPorts.Len() == 0 {
-		return false
+		return "exe_path"
 	}
 	if (a.Path.IsSet() || a.PathRegexp.IsSet()) && !m.matchByExecutable(p, a) {
-		return false
+		if a.Path.IsSet() {
+			return "exe_path"
+		}
+		return "exe_path_regexp"
 	}
 	if a.OpenPorts.Len() > 0 && !m.matchByPort(p, a) {
-		return false
+		return "open_ports"
+	}
+	if selector := m.unmatchedProcessAttribute(p, a); selector != "" {
+		return selector
 	}
 	// after matching by process basic information, we check if it matches
 	// by metadata.
-	// If there is no metadata, this will return true.
-	return m.matchByAttributes(obj, a)
+	// If there is no metadata, this will return an empty string.
+	return m.unmatchedAttribute(obj, a)
 }
 
 func (m *matcher) matchByPort(p *services.ProcessInfo, a *services.Attributes) bool {
@@ -152,28 +419,47 @@ func (m *matcher) matchByExecutable(p *services.ProcessInfo, a *services.Attribu
 	return a.PathRegexp.MatchString(p.ExePath)
 }
 
-func (m *matcher) matchByAttributes(actual *processAttrs, required *services.Attributes) bool {
+func (m *matcher) unmatchedProcessAttribute(p *services.ProcessInfo, a *services.Attributes) string {
+	if a.CmdArgs.IsSet() && !a.CmdArgs.MatchString(p.CmdLine) {
+		return "cmd_args"
+	}
+	if a.User.IsSet() && !a.User.MatchString(p.User) {
+		return "user"
+	}
+	if a.UID != nil && *a.UID != p.UID {
+		return "uid"
+	}
+	if a.ParentExe.IsSet() && !a.ParentExe.MatchString(p.ParentExe) {
+		return "parent_exe"
+	}
+	if a.SystemdUnit.IsSet() && !a.SystemdUnit.MatchString(p.SystemdUnit) {
+		return "systemd_unit"
+	}
+	return ""
+}
+
+func (m *matcher) unmatchedAttribute(actual *processAttrs, required *services.Attributes) string {
 	if required == nil {
-		return true
+		return ""
 	}
 	if actual == nil {
-		return false
+		return "metadata"
 	}
 
 	// match metadata
 	for attrName, criteriaRegexp := range required.Metadata {
 		if attrValue, ok := actual.metadata[attrName]; !ok || !criteriaRegexp.MatchString(attrValue) {
-			return false
+			return attrName
 		}
 	}
 
 	// match pod labels
 	for labelName, criteriaRegexp := range required.PodLabels {
 		if actualPodLabelValue, ok := actual.podLabels[labelName]; !ok || !criteriaRegexp.MatchString(actualPodLabelValue) {
-			return false
+			return "k8s_pod_labels." + labelName
 		}
 	}
-	return true
+	return ""
 }
 
 func FindingCriteria(cfg *beyla.Config) services.DefinitionCriteria {
@@ -198,29 +484,58 @@ func FindingCriteria(cfg *beyla.Config) services.DefinitionCriteria {
 			OpenPorts: cfg.Port,
 		})
 	}
-	// normalize criteria that only define metadata (e.g. k8s)
-	// but do neither define executable name nor port: configure them to match
-	// any executable in the matched k8s entities
-	for i := range finderCriteria {
-		fc := &finderCriteria[i]
-		if !fc.Path.IsSet() && fc.OpenPorts.Len() == 0 && (len(fc.Metadata) > 0 || len(fc.PodLabels) > 0) {
+	normalizeMetadataCriteria(finderCriteria)
+	return finderCriteria
+}
+
+// ExclusionCriteria returns the criteria of the processes that must not be instrumented
+func ExclusionCriteria(cfg *beyla.Config) services.DefinitionCriteria {
+	exclusionCriteria := slices.Clone(cfg.Discovery.ExcludeServices)
+	normalizeMetadataCriteria(exclusionCriteria)
+	return exclusionCriteria
+}
+
+// normalize criteria that only define metadata (e.g. k8s) or other process attributes (e.g. user)
+// but do neither define executable name nor port: configure them to match
+// any executable in the matched k8s entities
+func normalizeMetadataCriteria(criteria services.DefinitionCriteria) {
+	for i := range criteria {
+		fc := &criteria[i]
+		if !fc.Path.IsSet() && fc.OpenPorts.Len() == 0 &&
+			(len(fc.Metadata) > 0 || len(fc.PodLabels) > 0 || fc.HasProcessAttributes()) {
 			// match any executable path
 			if err := fc.Path.UnmarshalText([]byte(".")); err != nil {
 				panic("bug! " + err.Error())
 			}
 		}
 	}
-
-	return finderCriteria
 }
 
-// replaceable function to allow unit tests with faked processes
-var processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+// processInfo returns the information of a process. The command line, user and parent executable
+// are only read if withAttributes is true, as they are only needed by some selection criteria.
+// It is a replaceable function to allow unit tests with faked processes
+var processInfo = func(pp processAttrs, withAttributes bool) (*services.ProcessInfo, error) {
 	proc, err := process.NewProcess(int32(pp.pid))
 	if err != nil {
 		return nil, fmt.Errorf("can't read process: %w", err)
 	}
 	ppid, _ := proc.Ppid()
+	// the following attributes are only informative, so we ignore any error reading them
+	var cmdLine, userName, parentExe string
+	var uid int32
+	if withAttributes {
+		cmdLine, _ = proc.Cmdline()
+		// the user name is resolved from the /etc/passwd file that is visible to Beyla, which
+		// might not be the same as the one of processes running in other containers
+		userName, _ = proc.Username()
+		if uids, _ := proc.Uids(); len(uids) > 0 {
+			uid = uids[0]
+		}
+		if parent, err := process.NewProcess(ppid); err == nil {
+			parentExe, _ = parent.Exe()
+		}
+	}
+	systemdUnit, _ := systemd.UnitForPID(uint32(pp.pid))
 	exePath, err := proc.Exe()
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -232,9 +547,14 @@ var processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
 		}
 	}
 	return &services.ProcessInfo{
-		Pid:       proc.Pid,
-		PPid:      ppid,
-		ExePath:   exePath,
-		OpenPorts: pp.openPorts,
+		Pid:         proc.Pid,
+		PPid:        ppid,
+		ExePath:     exePath,
+		OpenPorts:   pp.openPorts,
+		CmdLine:     cmdLine,
+		User:        userName,
+		UID:         uid,
+		ParentExe:   parentExe,
+		SystemdUnit: systemdUnit,
 	}, nil
 }
@@ -1,13 +1,17 @@
 package discover
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/testutil"
 	"github.com/grafana/beyla/pkg/services"
 )
@@ -34,7 +38,7 @@ func TestCriteriaMatcher(t *testing.T) {
 	defer close(discoveredProcesses)
 
 	// it will filter unmatching processes and return a ProcessMatch for these that match
-	processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
 		exePath := map[PID]string{
 			1: "/bin/weird33", 2: "/bin/weird33", 3: "server",
 			4: "/bin/something", 5: "server", 6: "/bin/clientweird99"}[pp.pid]
@@ -55,10 +59,12 @@ func TestCriteriaMatcher(t *testing.T) {
 	assert.Equal(t, EventCreated, m.Type)
 	assert.Equal(t, "exec-only", m.Obj.Criteria.Name)
 	assert.Equal(t, "", m.Obj.Criteria.Namespace)
+	assert.Equal(t, "services[1]", m.Obj.MatchedBy)
 	assert.Equal(t, services.ProcessInfo{Pid: 1, ExePath: "/bin/weird33", OpenPorts: []uint32{1, 2, 3}}, *m.Obj.Process)
 	m = matches[1]
 	assert.Equal(t, EventCreated, m.Type)
 	assert.Equal(t, "port-only", m.Obj.Criteria.Name)
+	assert.Equal(t, "services[0]", m.Obj.MatchedBy)
 	assert.Equal(t, "foo", m.Obj.Criteria.Namespace)
 	assert.Equal(t, services.ProcessInfo{Pid: 4, ExePath: "/bin/something", OpenPorts: []uint32{8083}}, *m.Obj.Process)
 	m = matches[2]
@@ -94,7 +100,7 @@ func TestCriteriaMatcher_MustMatchAllAttributes(t *testing.T) {
 	go matcherFunc(discoveredProcesses, filteredProcesses)
 	defer close(discoveredProcesses)
 
-	processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
 		exePath := map[PID]string{
 			1: "/bin/foo", 2: "/bin/faa", 3: "foo",
 			4: "foool", 5: "thefoool", 6: "foo"}[pp.pid]
@@ -151,7 +157,7 @@ func TestCriteriaMatcherMissingPort(t *testing.T) {
 	defer close(discoveredProcesses)
 
 	// it will filter unmatching processes and return a ProcessMatch for these that match
-	processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
 		proc := map[PID]struct {
 			Exe  string
 			PPid int32
@@ -178,3 +184,363 @@ func TestCriteriaMatcherMissingPort(t *testing.T) {
 	assert.Equal(t, "foo", m.Obj.Criteria.Namespace)
 	assert.Equal(t, services.ProcessInfo{Pid: 3, ExePath: "/bin/weird33", OpenPorts: []uint32{}, PPid: 1}, *m.Obj.Process)
 }
+
+func TestCriteriaMatcher_Exclusion(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - k8s_namespace: prod
+  - name: ports
+    open_ports: 8000-8999
+  exclude_services:
+  - exe_path: istio-proxy
+  - open_ports: 8099
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		exePath := map[PID]string{
+			1: "/bin/server", 2: "/usr/local/bin/istio-proxy", 3: "/bin/server", 4: "/bin/exporter"}[pp.pid]
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: exePath, OpenPorts: pp.openPorts}, nil
+	}
+	prodMeta := map[string]string{"k8s_namespace": "prod"}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1, metadata: prodMeta}},                   // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 2, metadata: prodMeta}},                   // filter: excluded sidecar
+		{Type: EventCreated, Obj: processAttrs{pid: 3, openPorts: []uint32{8080}}},            // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 4, openPorts: []uint32{8099}}},            // filter: excluded port
+		{Type: EventCreated, Obj: processAttrs{pid: 5, openPorts: []uint32{7000}}},            // filter: not included
+		{Type: EventDeleted, Obj: processAttrs{pid: 2, openPorts: []uint32{}, metadata: nil}}, // filter: never tracked
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 2)
+	assert.Equal(t, services.ProcessInfo{Pid: 1, ExePath: "/bin/server"}, *matches[0].Obj.Process)
+	assert.Equal(t, services.ProcessInfo{Pid: 3, ExePath: "/bin/server", OpenPorts: []uint32{8080}}, *matches[1].Obj.Process)
+}
+
+func TestCriteriaMatcher_SystemWideExclusion(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  system_wide: true
+  exclude_services:
+  - exe_path: istio-proxy
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		exePath := map[PID]string{
+			4000001: "/usr/local/bin/istio-proxy", 4000002: "/bin/server", 4000003: "/bin/other"}[pp.pid]
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: exePath, OpenPorts: pp.openPorts}, nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 4000001}},
+		{Type: EventCreated, Obj: processAttrs{pid: 4000002}},
+		{Type: EventCreated, Obj: processAttrs{pid: 4000003}},
+	}
+
+	// only the first non-excluded process is forwarded, to create the system-wide instrumenter
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.Equal(t, services.ProcessInfo{Pid: 4000002, ExePath: "/bin/server"}, *matches[0].Obj.Process)
+
+	// spans from the excluded process are discarded by the system-wide filter
+	filter := ebpfcommon.CommonPIDsFilter(true)
+	require.Eventually(t, func() bool {
+		spans := filter.Filter([]request.Span{{Pid: request.PidInfo{HostPID: 4000001}}, {Pid: request.PidInfo{HostPID: 4000002}}})
+		return len(spans) == 1 && spans[0].Pid.HostPID == 4000002
+	}, testTimeout, 10*time.Millisecond)
+
+	// after the excluded process finishes, its PID is not blocked anymore
+	discoveredProcesses <- []Event[processAttrs]{{Type: EventDeleted, Obj: processAttrs{pid: 4000001}}}
+	require.Eventually(t, func() bool {
+		return len(filter.Filter([]request.Span{{Pid: request.PidInfo{HostPID: 4000001}}})) == 1
+	}, testTimeout, 10*time.Millisecond)
+}
+
+func TestCriteriaMatcher_ProcessAttributes(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: orders
+    exe_path: java
+    cmd_args: -jar .*orders\.jar
+  - name: root-worker
+    uid: 0
+    cmd_args: worker
+  - name: app-user
+    user: ^app$
+  - name: supervised
+    parent_exe: supervisord
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, withAttributes bool) (*services.ProcessInfo, error) {
+		// the process attributes are read, as they are required by the selection criteria
+		assert.True(t, withAttributes)
+		return map[PID]*services.ProcessInfo{
+			1: {Pid: 1, ExePath: "/usr/bin/java", CmdLine: "java -jar /app/orders.jar", User: "nobody", UID: 65534},
+			2: {Pid: 2, ExePath: "/usr/bin/java", CmdLine: "java -jar /app/payments.jar", User: "nobody", UID: 65534},
+			3: {Pid: 3, ExePath: "/usr/bin/python3", CmdLine: "python3 worker.py", User: "root", UID: 0},
+			4: {Pid: 4, ExePath: "/usr/bin/python3", CmdLine: "python3 worker.py", User: "application", UID: 1000},
+			5: {Pid: 5, ExePath: "/usr/bin/node", CmdLine: "node index.js", User: "app", UID: 1001},
+			6: {Pid: 6, ExePath: "/usr/bin/ruby", CmdLine: "ruby app.rb", ParentExe: "/usr/bin/supervisord", UID: 1002},
+		}[pp.pid], nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}}, // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 2}}, // filter: cmd args don't match
+		{Type: EventCreated, Obj: processAttrs{pid: 3}}, // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 4}}, // filter: uid and user don't match
+		{Type: EventCreated, Obj: processAttrs{pid: 5}}, // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 6}}, // pass
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 4)
+	assert.Equal(t, "orders", matches[0].Obj.Criteria.Name)
+	assert.EqualValues(t, 1, matches[0].Obj.Process.Pid)
+	assert.Equal(t, "root-worker", matches[1].Obj.Criteria.Name)
+	assert.EqualValues(t, 3, matches[1].Obj.Process.Pid)
+	assert.Equal(t, "app-user", matches[2].Obj.Criteria.Name)
+	assert.EqualValues(t, 5, matches[2].Obj.Process.Pid)
+	assert.Equal(t, "supervised", matches[3].Obj.Criteria.Name)
+	assert.EqualValues(t, 6, matches[3].Obj.Process.Pid)
+}
+
+func TestCriteriaMatcher_SystemdUnit(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - namespace: systemd
+    systemd_unit: ^(nginx|postgresql)\.service$
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		return map[PID]*services.ProcessInfo{
+			1: {Pid: 1, ExePath: "/usr/sbin/nginx", SystemdUnit: "nginx.service"},
+			2: {Pid: 2, ExePath: "/usr/sbin/sshd", SystemdUnit: "ssh.service"},
+			3: {Pid: 3, ExePath: "/usr/sbin/nginx"},
+		}[pp.pid], nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}}, // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 2}}, // filter: unit does not match
+		{Type: EventCreated, Obj: processAttrs{pid: 3}}, // filter: not in a systemd unit
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.EqualValues(t, 1, matches[0].Obj.Process.Pid)
+	assert.Equal(t, map[string]string{"systemd.unit": "nginx.service"}, matches[0].Obj.ResourceAttributes)
+}
+
+func TestCriteriaMatcher_Reload(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: server
+    exe_path: server
+  - name: batch
+    exe_path: reports
+`), &pipeConfig))
+	reloadedConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: server
+    exe_path: server
+  - name: worker
+    exe_path: worker
+  - name: reports
+    exe_path: reports
+  exclude_services:
+  - exe_path: old-server
+`), &reloadedConfig))
+
+	reloads := make(chan *beyla.Config)
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig, Reloads: reloads})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		return map[PID]*services.ProcessInfo{
+			1: {Pid: 1, ExePath: "/bin/server"},
+			2: {Pid: 2, ExePath: "/bin/old-server"},
+			3: {Pid: 3, ExePath: "/bin/worker"},
+			4: {Pid: 4, ExePath: "/bin/reports"},
+		}[pp.pid], nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}},
+		{Type: EventCreated, Obj: processAttrs{pid: 2}},
+		{Type: EventCreated, Obj: processAttrs{pid: 3}},
+		{Type: EventCreated, Obj: processAttrs{pid: 4}},
+	}
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 3)
+	assert.EqualValues(t, 1, matches[0].Obj.Process.Pid)
+	assert.EqualValues(t, 2, matches[1].Obj.Process.Pid)
+	assert.EqualValues(t, 4, matches[2].Obj.Process.Pid)
+	assert.Equal(t, "batch", matches[2].Obj.Criteria.Name)
+
+	reloads <- &reloadedConfig
+
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 4)
+	// the excluded process is detached
+	assert.Equal(t, EventDeleted, matches[0].Type)
+	assert.EqualValues(t, 2, matches[0].Obj.Process.Pid)
+	// the new matching process is attached
+	assert.Equal(t, EventCreated, matches[1].Type)
+	assert.EqualValues(t, 3, matches[1].Obj.Process.Pid)
+	assert.Equal(t, "worker", matches[1].Obj.Criteria.Name)
+	// the process whose service name changed is attached again with the new name
+	assert.Equal(t, EventDeleted, matches[2].Type)
+	assert.EqualValues(t, 4, matches[2].Obj.Process.Pid)
+	assert.Equal(t, EventCreated, matches[3].Type)
+	assert.EqualValues(t, 4, matches[3].Obj.Process.Pid)
+	assert.Equal(t, "reports", matches[3].Obj.Criteria.Name)
+}
+
+func TestCriteriaMatcher_ReloadProcessAttributes(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - exe_path: server
+`), &pipeConfig))
+	reloadedConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - exe_path: server
+  exclude_services:
+  - cmd_args: --dry-run
+`), &reloadedConfig))
+
+	reloads := make(chan *beyla.Config)
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig, Reloads: reloads})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	// the command line is only provided if it is requested
+	var mt sync.Mutex
+	attributesRead := 0
+	processInfo = func(pp processAttrs, withAttributes bool) (*services.ProcessInfo, error) {
+		proc := &services.ProcessInfo{Pid: int32(pp.pid), ExePath: "/bin/server"}
+		if withAttributes {
+			mt.Lock()
+			attributesRead++
+			mt.Unlock()
+			proc.CmdLine = map[PID]string{1: "server --port 80", 2: "server --dry-run"}[pp.pid]
+		}
+		return proc, nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}},
+		{Type: EventCreated, Obj: processAttrs{pid: 2}},
+	}
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 2)
+	mt.Lock()
+	assert.Zero(t, attributesRead, "no criteria requires the process attributes")
+	mt.Unlock()
+
+	// the process attributes of the already matched processes are read, as the new criteria require them
+	reloads <- &reloadedConfig
+
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.Equal(t, EventDeleted, matches[0].Type)
+	assert.EqualValues(t, 2, matches[0].Obj.Process.Pid)
+	mt.Lock()
+	assert.Equal(t, 2, attributesRead)
+	mt.Unlock()
+}
+
+func TestCriteriaMatcher_KubeAnnotations(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  k8s_annotations: true
+  services:
+  - name: prod-service
+    k8s_namespace: prod
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: "/bin/server"}, nil
+	}
+	prodMeta := map[string]string{"k8s_namespace": "prod"}
+	devMeta := map[string]string{"k8s_namespace": "dev"}
+	discoveredProcesses <- []Event[processAttrs]{
+		// pass: matches the configured criteria
+		{Type: EventCreated, Obj: processAttrs{pid: 1, metadata: prodMeta}},
+		// filter: opted out
+		{Type: EventCreated, Obj: processAttrs{pid: 2, metadata: prodMeta,
+			podAnnotations: map[string]string{"beyla.grafana.com/instrument": "false"}}},
+		// pass: opted in by annotation, with service name and route hints
+		{Type: EventCreated, Obj: processAttrs{pid: 3, metadata: devMeta,
+			podAnnotations: map[string]string{
+				"beyla.grafana.com/instrument":   "true",
+				"beyla.grafana.com/service-name": "annotated",
+				"beyla.grafana.com/routes":       "/users/{id}, /orders/{id}",
+			}}},
+		// pass: opted in by label
+		{Type: EventCreated, Obj: processAttrs{pid: 4, metadata: devMeta,
+			podLabels: map[string]string{"beyla.grafana.com/instrument": "true"}}},
+		// filter: annotations take precedence over labels
+		{Type: EventCreated, Obj: processAttrs{pid: 5, metadata: devMeta,
+			podAnnotations: map[string]string{"beyla.grafana.com/instrument": "false"},
+			podLabels:      map[string]string{"beyla.grafana.com/instrument": "true"}}},
+		// filter: not opted in
+		{Type: EventCreated, Obj: processAttrs{pid: 6, metadata: devMeta}},
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 3)
+	assert.EqualValues(t, 1, matches[0].Obj.Process.Pid)
+	assert.Equal(t, "prod-service", matches[0].Obj.Criteria.Name)
+	assert.Nil(t, matches[0].Obj.RouteHints)
+	assert.EqualValues(t, 3, matches[1].Obj.Process.Pid)
+	assert.Equal(t, "annotated", matches[1].Obj.Criteria.Name)
+	require.NotNil(t, matches[1].Obj.RouteHints)
+	assert.Equal(t, []string{"/users/{id}", "/orders/{id}"}, matches[1].Obj.RouteHints.Patterns)
+	assert.EqualValues(t, 4, matches[2].Obj.Process.Pid)
+	assert.Empty(t, matches[2].Obj.Criteria.Name)
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/grafana/beyla/pkg/beyla"
 	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/goexec"
+	"github.com/grafana/beyla/pkg/internal/helpers/systemd"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
 )
 
 // ExecTyper classifies the discovered executables according to the
@@ -31,24 +33,33 @@ type Instrumentable struct {
 
 	FileInfo *exec.FileInfo
 	Offsets  *goexec.Offsets
+
+	// MatchedBy describes the discovery criteria that selected the process
+	MatchedBy string
 }
 
 func ExecTyperProvider(ecfg ExecTyper) (node.MiddleFunc[[]Event[ProcessMatch], []Event[Instrumentable]], error) {
+	t := newTyper(&ecfg)
+	return func(in <-chan []Event[ProcessMatch], out chan<- []Event[Instrumentable]) {
+		for i := range in {
+			out <- t.FilterClassify(i)
+		}
+	}, nil
+}
+
+func newTyper(ecfg *ExecTyper) *typer {
 	t := typer{
 		cfg:         ecfg.Cfg,
 		metrics:     ecfg.Metrics,
 		log:         slog.With("component", "discover.ExecTyper"),
 		currentPids: map[int32]*exec.FileInfo{},
+		matchedBy:   map[int32]string{},
 	}
 	// TODO: do it per executable
 	if !ecfg.Cfg.Discovery.SkipGoSpecificTracers {
 		t.loadAllGoFunctionNames()
 	}
-	return func(in <-chan []Event[ProcessMatch], out chan<- []Event[Instrumentable]) {
-		for i := range in {
-			out <- t.FilterClassify(i)
-		}
-	}, nil
+	return &t
 }
 
 type typer struct {
@@ -56,6 +67,7 @@ type typer struct {
 	metrics        imetrics.Reporter
 	log            *slog.Logger
 	currentPids    map[int32]*exec.FileInfo
+	matchedBy      map[int32]string
 	allGoFunctions []string
 }
 
@@ -72,16 +84,29 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 		ev := &evs[i]
 		switch evs[i].Type {
 		case EventCreated:
-			svcID := svc.ID{Name: ev.Obj.Criteria.Name, Namespace: ev.Obj.Criteria.Namespace}
+			svcID := svc.ID{
+				Name:       ev.Obj.Criteria.Name,
+				Namespace:  ev.Obj.Criteria.Namespace,
+				RouteHints: ev.Obj.RouteHints,
+				Metadata:   ev.Obj.ResourceAttributes,
+
+				ExporterProfile: ev.Obj.Criteria.ExporterProfile,
+			}
+			t.decorateFromEnv(ev.Obj.Process.Pid, &svcID)
+			if svcID.Name == "" && t.cfg.Discovery.SystemdServiceName && ev.Obj.Process.SystemdUnit != "" {
+				svcID.Name = systemd.ServiceName(ev.Obj.Process.SystemdUnit)
+			}
 			if elfFile, err := exec.FindExecELF(ev.Obj.Process, svcID); err != nil {
 				t.log.Warn("error finding process ELF. Ignoring", "error", err)
 			} else {
 				t.currentPids[ev.Obj.Process.Pid] = elfFile
+				t.matchedBy[ev.Obj.Process.Pid] = ev.Obj.MatchedBy
 				elfs = append(elfs, elfFile)
 			}
 		case EventDeleted:
 			if fInfo, ok := t.currentPids[ev.Obj.Process.Pid]; ok {
 				delete(t.currentPids, ev.Obj.Process.Pid)
+				delete(t.matchedBy, ev.Obj.Process.Pid)
 				out = append(out, Event[Instrumentable]{
 					Type: EventDeleted,
 					Obj:  Instrumentable{FileInfo: fInfo},
@@ -92,6 +117,7 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 
 	for i := range elfs {
 		inst := t.asInstrumentable(elfs[i])
+		inst.MatchedBy = t.matchedBy[inst.FileInfo.Pid]
 		t.log.Debug(
 			"found an instrumentable process",
 			"type", inst.Type.String(),
@@ -101,6 +127,22 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 	return out
 }
 
+// decorateFromEnv sets the service name, namespace and resource attributes that might be
+// defined in the OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES environment variables
+func (t *typer) decorateFromEnv(pid int32, svcID *svc.ID) {
+	precedence := t.cfg.Discovery.ServiceEnvPrecedence
+	if precedence == "" || precedence == services.EnvPrecedenceDisabled {
+		return
+	}
+	environ, err := procEnviron(pid)
+	if err != nil {
+		t.log.Debug("can't read process environment. Ignoring", "pid", pid, "error", err)
+		return
+	}
+	info := parseEnvServiceInfo(environ)
+	info.decorate(svcID, precedence)
+}
+
 // asInstrumentable classifies the type of executable (Go, generic...) and,
 // in case of belonging to a forked process, returns its parent.
 func (t *typer) asInstrumentable(execElf *exec.FileInfo) Instrumentable {
@@ -0,0 +1,126 @@
+package discover
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/mariomac/pipes/pkg/node"
+
+	"github.com/grafana/beyla/pkg/internal/docker"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+const (
+	containerCacheSize = 1024
+
+	attrContainerID        = "container.id"
+	attrContainerName      = "container.name"
+	attrContainerImageName = "container.image.name"
+	attrContainerImageTag  = "container.image.tag"
+	attrContainerLabel     = "container.label."
+)
+
+// containerInspector is implemented by the docker.Client and docker.ContainerdClient
+type containerInspector interface {
+	ContainerInfo(ctx context.Context, containerID string) (*docker.ContainerInfo, error)
+}
+
+// ContainerEnricher decorates the discovered processes with the metadata of the Docker or containerd
+// containers where they run, so they can be selected by container name or image, and the container metadata is
+// added to the service attributes.
+type ContainerEnricher struct {
+	Ctx    context.Context
+	Client containerInspector
+}
+
+func ContainerEnricherProvider(ce *ContainerEnricher) (node.MiddleFunc[[]Event[processAttrs], []Event[processAttrs]], error) {
+	enricher, err := newContainerEnricher(ce)
+	if err != nil {
+		return nil, err
+	}
+	return func(in <-chan []Event[processAttrs], out chan<- []Event[processAttrs]) {
+		for events := range in {
+			out <- enricher.enrich(events)
+		}
+	}, nil
+}
+
+func newContainerEnricher(ce *ContainerEnricher) (*containerEnricher, error) {
+	// containers are cached by ID, as many processes can run in the same container
+	containers, err := lru.New[string, *docker.ContainerInfo](containerCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &containerEnricher{
+		ctx:        ce.Ctx,
+		log:        slog.With("component", "discover.ContainerEnricher"),
+		client:     ce.Client,
+		containers: containers,
+	}, nil
+}
+
+type containerEnricher struct {
+	ctx        context.Context
+	log        *slog.Logger
+	client     containerInspector
+	containers *lru.Cache[string, *docker.ContainerInfo]
+}
+
+func (ce *containerEnricher) enrich(events []Event[processAttrs]) []Event[processAttrs] {
+	for i := range events {
+		if events[i].Type != EventCreated {
+			continue
+		}
+		if info, ok := ce.containerInfo(events[i].Obj.pid); ok {
+			events[i].Obj = withContainerMetadata(events[i].Obj, info)
+		}
+	}
+	return events
+}
+
+func (ce *containerEnricher) containerInfo(pid PID) (*docker.ContainerInfo, bool) {
+	cntInfo, err := containerInfoForPID(uint32(pid))
+	if err != nil {
+		// it is expected for any process not running inside a container
+		ce.log.Debug("can't get container info for PID", "pid", pid, "error", err)
+		return nil, false
+	}
+	if info, ok := ce.containers.Get(cntInfo.ContainerID); ok {
+		return info, true
+	}
+	info, err := ce.client.ContainerInfo(ce.ctx, cntInfo.ContainerID)
+	if err != nil {
+		ce.log.Debug("can't get container metadata. Ignoring", "pid", pid, "containerID", cntInfo.ContainerID, "error", err)
+		return nil, false
+	}
+	ce.containers.Add(cntInfo.ContainerID, info)
+	return info, true
+}
+
+// withContainerMetadata returns a copy with new maps to avoid race conditions in later stages of the pipeline
+func withContainerMetadata(pp processAttrs, info *docker.ContainerInfo) processAttrs {
+	ret := pp
+	ret.metadata = make(map[string]string, len(pp.metadata)+2)
+	maps.Copy(ret.metadata, pp.metadata)
+	image := info.ImageName
+	if info.ImageTag != "" {
+		image += ":" + info.ImageTag
+	}
+	ret.metadata[services.AttrContainerName] = info.Name
+	ret.metadata[services.AttrContainerImage] = image
+
+	ret.resourceAttributes = make(map[string]string, len(pp.resourceAttributes)+len(info.Labels)+4)
+	maps.Copy(ret.resourceAttributes, pp.resourceAttributes)
+	ret.resourceAttributes[attrContainerID] = info.ID
+	ret.resourceAttributes[attrContainerName] = info.Name
+	ret.resourceAttributes[attrContainerImageName] = info.ImageName
+	if info.ImageTag != "" {
+		ret.resourceAttributes[attrContainerImageTag] = info.ImageTag
+	}
+	for k, v := range info.Labels {
+		ret.resourceAttributes[attrContainerLabel+k] = v
+	}
+	return ret
+}
@@ -0,0 +1,75 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/docker"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+type fakeInspector map[string]*docker.ContainerInfo
+
+func (f fakeInspector) ContainerInfo(_ context.Context, containerID string) (*docker.ContainerInfo, error) {
+	if info, ok := f[containerID]; ok {
+		return info, nil
+	}
+	return nil, errors.New("container not found")
+}
+
+func TestContainerEnricher(t *testing.T) {
+	containerInfoForPID = fakeContainerInfo
+	processInfo = func(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: "/bin/server"}, nil
+	}
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: orders
+    container_image: ^shop/orders
+`), &pipeConfig))
+
+	enricherFunc, err := ContainerEnricherProvider(&ContainerEnricher{
+		Ctx: context.Background(),
+		Client: fakeInspector{
+			"container-1": {ID: "container-1", Name: "orders-1", ImageName: "shop/orders", ImageTag: "1.2",
+				Labels: map[string]string{"team": "checkout"}},
+			"container-2": {ID: "container-2", Name: "payments-1", ImageName: "shop/payments", ImageTag: "3.4"},
+		},
+	})
+	require.NoError(t, err)
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	enrichedProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go enricherFunc(discoveredProcesses, enrichedProcesses)
+	go matcherFunc(enrichedProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1}}, // pass
+		{Type: EventCreated, Obj: processAttrs{pid: 2}}, // filter: image does not match
+		{Type: EventCreated, Obj: processAttrs{pid: 3}}, // filter: container not found
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.EqualValues(t, 1, matches[0].Obj.Process.Pid)
+	assert.Equal(t, "orders", matches[0].Obj.Criteria.Name)
+	assert.Equal(t, map[string]string{
+		"container.id":         "container-1",
+		"container.name":       "orders-1",
+		"container.image.name": "shop/orders",
+		"container.image.tag":  "1.2",
+		"container.label.team": "checkout",
+	}, matches[0].Obj.ResourceAttributes)
+}
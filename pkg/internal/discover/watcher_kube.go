@@ -301,6 +301,7 @@ func withMetadata(pp processAttrs, info *kube.PodInfo) processAttrs {
 		services.AttrPodName:   info.Name,
 	}
 	ret.podLabels = info.Labels
+	ret.podAnnotations = info.Annotations
 	owner := info.Owner
 	for owner != nil {
 		ret.metadata[services.AttrOwnerName] = owner.Name
@@ -305,7 +305,7 @@ func fakeContainerInfo(pid uint32) (container.Info, error) {
 	return container.Info{ContainerID: fmt.Sprintf("container-%d", pid)}, nil
 }
 
-func fakeProcessInfo(pp processAttrs) (*services.ProcessInfo, error) {
+func fakeProcessInfo(pp processAttrs, _ bool) (*services.ProcessInfo, error) {
 	return &services.ProcessInfo{
 		Pid:       int32(pp.pid),
 		OpenPorts: pp.openPorts,
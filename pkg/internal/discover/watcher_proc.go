@@ -16,16 +16,23 @@ import (
 
 	"github.com/grafana/beyla/pkg/beyla"
 	"github.com/grafana/beyla/pkg/internal/ebpf"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
 	"github.com/grafana/beyla/pkg/internal/ebpf/watcher"
 	"github.com/grafana/beyla/pkg/services"
 )
 
 const (
-	defaultPollInterval = 5 * time.Second
+	defaultPollInterval      = 5 * time.Second
+	defaultReconcileInterval = 30 * time.Second
+
+	// maximum number of eBPF process creation/deletion events that are forwarded together
+	maxProcessEventsBatch = 100
 )
 
-// ProcessWatcher polls every PollInterval for new processes and forwards either new or deleted process PIDs
-// as well as PIDs from processes that setup a new connection
+// ProcessWatcher forwards either new or deleted process PIDs as well as PIDs from processes that setup a new connection.
+// If the eBPF watcher can be loaded, process creation and deletion are notified as soon as they are traced
+// from the sched_process_exec and sched_process_exit tracepoints, and the processes are polled every ReconcileInterval
+// as a fallback reconciliation mechanism. Otherwise, the processes are polled every PollInterval.
 type ProcessWatcher struct {
 	Ctx context.Context
 	Cfg *beyla.Config
@@ -50,6 +57,10 @@ type processAttrs struct {
 	openPorts []uint32
 	metadata  map[string]string
 	podLabels map[string]string
+	// podAnnotations only contains the Beyla-specific annotations of the Pod
+	podAnnotations map[string]string
+	// resourceAttributes that will be added to the metadata of the service (e.g. container.name)
+	resourceAttributes map[string]string
 }
 
 func wplog() *slog.Logger {
@@ -70,10 +81,15 @@ func ProcessWatcherProvider(w ProcessWatcher) (node.StartFunc[[]Event[processAtt
 		bpfWatcherEnabled: false, // async set by listening on the bpfWatchEvents channel
 		stateMux:          sync.Mutex{},
 		findingCriteria:   FindingCriteria(w.Cfg),
+		reconcileInterval: w.Cfg.Discovery.ReconcileInterval,
+		procEvents:        make(chan watcher.Event, maxProcessEventsBatch),
 	}
 	if acc.interval == 0 {
 		acc.interval = defaultPollInterval
 	}
+	if acc.reconcileInterval == 0 {
+		acc.reconcileInterval = defaultReconcileInterval
+	}
 	return acc.Run, nil
 }
 
@@ -83,8 +99,6 @@ type pidPort struct {
 	Port uint32
 }
 
-// TODO: combine the poller with an eBPF listener (poll at start and e.g. every 30 seconds, and keep listening eBPF in background)
-// ^ This is partially done, although it's not fully async, we only use the info to reduce the overhead of port scanning.
 type pollAccounter struct {
 	ctx      context.Context
 	cfg      *beyla.Config
@@ -105,6 +119,11 @@ type pollAccounter struct {
 	bpfWatcherEnabled bool
 	fetchPorts        bool
 	findingCriteria   services.DefinitionCriteria
+	// when the eBPF watcher is enabled, the processes are only polled every reconcileInterval,
+	// or when new ports need to be fetched
+	reconcileInterval time.Duration
+	// process creation and deletion events, as traced by the eBPF watcher
+	procEvents chan watcher.Event
 }
 
 func (pa *pollAccounter) Run(out chan<- []Event[processAttrs]) {
@@ -113,24 +132,38 @@ func (pa *pollAccounter) Run(out chan<- []Event[processAttrs]) {
 	bpfWatchEvents := make(chan watcher.Event, 100)
 	if err := pa.loadBPFWatcher(pa.cfg, bpfWatchEvents); err != nil {
 		log.Error("Unable to load eBPF watcher for process events", "error", err)
+	} else {
+		ebpfcommon.MarkLoaded()
 	}
 
 	go pa.watchForProcessEvents(log, bpfWatchEvents)
 
+	var lastPoll time.Time
 	for {
-		procs, err := pa.listProcesses(pa.portFetchRequired())
-		if err != nil {
-			log.Warn("can't get system processes", "error", err)
-		} else {
-			if events := pa.snapshot(procs); len(events) > 0 {
-				log.Debug("new process watching events", "events", events)
-				out <- events
+		fetchPorts := pa.portFetchRequired()
+		// if the eBPF watcher is ready, the process creation and deletion are notified through
+		// the procEvents channel, so the whole process list is only fetched for reconciliation
+		if fetchPorts || !pa.bpfWatcherReady() || time.Since(lastPoll) >= pa.reconcileInterval {
+			lastPoll = time.Now()
+			procs, err := pa.listProcesses(fetchPorts)
+			if err != nil {
+				log.Warn("can't get system processes", "error", err)
+			} else {
+				if events := pa.snapshot(procs); len(events) > 0 {
+					log.Debug("new process watching events", "events", events)
+					out <- events
+				}
 			}
 		}
 		select {
 		case <-pa.ctx.Done():
 			log.Debug("context canceled. Exiting")
 			return
+		case ev := <-pa.procEvents:
+			if events := pa.processEvents(ev); len(events) > 0 {
+				log.Debug("new process watching events", "events", events)
+				out <- events
+			}
 		case <-time.After(pa.interval):
 			// poll event starting again
 		}
@@ -143,6 +176,12 @@ func (pa *pollAccounter) bpfWatcherIsReady() {
 	pa.bpfWatcherEnabled = true
 }
 
+func (pa *pollAccounter) bpfWatcherReady() bool {
+	pa.stateMux.Lock()
+	defer pa.stateMux.Unlock()
+	return pa.bpfWatcherEnabled
+}
+
 func (pa *pollAccounter) refetchPorts() {
 	pa.stateMux.Lock()
 	defer pa.stateMux.Unlock()
@@ -173,6 +212,8 @@ func (pa *pollAccounter) watchForProcessEvents(log *slog.Logger, events <-chan w
 			if pa.cfg.Port.Matches(port) || pa.findingCriteria.PortOfInterest(port) {
 				pa.refetchPorts()
 			}
+		case watcher.NewProcess, watcher.ProcessExit:
+			pa.procEvents <- e
 		default:
 			log.Warn("Unknown ebpf process watch event", "type", e.Type)
 		}
@@ -230,6 +271,60 @@ func (pa *pollAccounter) snapshot(fetchedProcs map[PID]processAttrs) []Event[pro
 	return events
 }
 
+// processEvents converts the process creation and deletion events from the eBPF watcher, as well as
+// any other event that is already queued, into process watching events
+func (pa *pollAccounter) processEvents(first watcher.Event) []Event[processAttrs] {
+	events := pa.processEvent(nil, first)
+	for i := 1; i < maxProcessEventsBatch; i++ {
+		select {
+		case ev := <-pa.procEvents:
+			events = pa.processEvent(events, ev)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+func (pa *pollAccounter) processEvent(events []Event[processAttrs], ev watcher.Event) []Event[processAttrs] {
+	pid := PID(ev.Payload)
+	switch ev.Type {
+	case watcher.NewProcess:
+		if proc, ok := pa.pids[pid]; ok {
+			// the process replaced its executable, so the previous one is forgotten
+			pa.forget(proc)
+			events = append(events, Event[processAttrs]{Type: EventDeleted, Obj: proc})
+		}
+		if !pa.executableReady(pid) {
+			// it will be eventually notified by the reconciliation poll
+			wplog().Debug("Executable not ready", "pid", pid)
+			return events
+		}
+		// the process didn't have time to open any port yet. In that case, it will be notified
+		// again, with its ports, after a new port is bound
+		proc := processAttrs{pid: pid, openPorts: []uint32{}}
+		if pa.pids == nil {
+			pa.pids = map[PID]processAttrs{}
+		}
+		pa.pids[pid] = proc
+		events = append(events, Event[processAttrs]{Type: EventCreated, Obj: proc})
+	case watcher.ProcessExit:
+		if proc, ok := pa.pids[pid]; ok {
+			pa.forget(proc)
+			events = append(events, Event[processAttrs]{Type: EventDeleted, Obj: proc})
+		}
+	}
+	return events
+}
+
+// forget removes the process from the last polled snapshot
+func (pa *pollAccounter) forget(proc processAttrs) {
+	delete(pa.pids, proc.pid)
+	for _, port := range proc.openPorts {
+		delete(pa.pidPorts, pidPort{Pid: proc.pid, Port: port})
+	}
+}
+
 func executableReady(pid PID) bool {
 	proc, err := process.NewProcess(int32(pid))
 	if err != nil {
@@ -236,6 +236,64 @@ func TestPortsFetchRequired(t *testing.T) {
 	}
 }
 
+func TestWatcher_ProcessEvents(t *testing.T) {
+	p1 := processAttrs{pid: 1, openPorts: []uint32{3030}}
+	p2 := processAttrs{pid: 2, openPorts: []uint32{}}
+	p3 := processAttrs{pid: 3, openPorts: []uint32{}}
+	channelReturner := make(chan chan<- watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// GIVEN a pollAccounter whose reconciliation poll won't interfere with the test
+	acc := pollAccounter{
+		interval:          time.Hour,
+		reconcileInterval: time.Hour,
+		ctx:               ctx,
+		pidPorts:          map[pidPort]processAttrs{},
+		listProcesses: func(bool) (map[PID]processAttrs, error) {
+			return map[PID]processAttrs{p1.pid: p1}, nil
+		},
+		executableReady: func(PID) bool {
+			return true
+		},
+		loadBPFWatcher: func(_ *beyla.Config, events chan<- watcher.Event) error {
+			channelReturner <- events
+			return nil
+		},
+		procEvents: make(chan watcher.Event, 10),
+	}
+	accounterOutput := make(chan []Event[processAttrs], 1)
+	go acc.Run(accounterOutput)
+	eventsChan := testutil.ReadChannel(t, channelReturner, testTimeout)
+
+	// WHEN it polls the processes for the first time
+	// THEN it returns the creation of all the processes
+	assert.Equal(t, []Event[processAttrs]{{Type: EventCreated, Obj: p1}},
+		testutil.ReadChannel(t, accounterOutput, testTimeout))
+
+	// WHEN the eBPF watcher notifies the creation of processes
+	// THEN they are forwarded without waiting for the next poll
+	eventsChan <- watcher.Event{Type: watcher.Ready}
+	eventsChan <- watcher.Event{Type: watcher.NewProcess, Payload: 2}
+	assert.Equal(t, []Event[processAttrs]{{Type: EventCreated, Obj: p2}},
+		testutil.ReadChannel(t, accounterOutput, testTimeout))
+
+	// WHEN the eBPF watcher notifies the deletion of processes
+	// THEN only the deletion of the known processes is forwarded
+	eventsChan <- watcher.Event{Type: watcher.ProcessExit, Payload: 1234}
+	eventsChan <- watcher.Event{Type: watcher.ProcessExit, Payload: 1}
+	assert.Equal(t, []Event[processAttrs]{{Type: EventDeleted, Obj: p1}},
+		testutil.ReadChannel(t, accounterOutput, testTimeout))
+
+	// WHEN a known process executes a new program
+	// THEN the previous process is removed and the new one is created
+	eventsChan <- watcher.Event{Type: watcher.NewProcess, Payload: 3}
+	assert.Equal(t, []Event[processAttrs]{{Type: EventCreated, Obj: p3}},
+		testutil.ReadChannel(t, accounterOutput, testTimeout))
+	eventsChan <- watcher.Event{Type: watcher.NewProcess, Payload: 3}
+	assert.Equal(t, []Event[processAttrs]{{Type: EventDeleted, Obj: p3}, {Type: EventCreated, Obj: p3}},
+		testutil.ReadChannel(t, accounterOutput, testTimeout))
+}
+
 // auxiliary function just to allow comparing slices whose order is not deterministic
 func sort(events []Event[processAttrs]) []Event[processAttrs] {
 	slices.SortFunc(events, func(a, b Event[processAttrs]) int {
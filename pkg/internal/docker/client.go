@@ -0,0 +1,155 @@
+// Package docker provides minimal clients for the Docker Engine and containerd APIs, which are used
+// to decorate the instrumented processes with the metadata of the containers where they run.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSocket  = "/var/run/docker.sock"
+	DefaultTimeout = 5 * time.Second
+
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+)
+
+// Config for the container metadata decoration
+type Config struct {
+	// Enable the decoration of the services with the metadata of the containers where they run
+	Enable bool `yaml:"enable" env:"BEYLA_CONTAINER_METADATA_ENABLE"`
+	// Runtime whose API is queried: docker (also for compatible APIs, such as Podman's) or containerd
+	Runtime string `yaml:"runtime" env:"BEYLA_CONTAINER_METADATA_RUNTIME"`
+	// Socket is the path of the Unix socket that serves the container runtime API.
+	// For example /var/run/docker.sock, /run/podman/podman.sock or /run/containerd/containerd.sock.
+	// If unset, the default socket of the runtime is used.
+	Socket string `yaml:"socket" env:"BEYLA_CONTAINER_METADATA_SOCKET"`
+	// ContainerdNamespaces where the containers are looked for, when the runtime is containerd
+	ContainerdNamespaces []string `yaml:"containerd_namespaces" env:"BEYLA_CONTAINER_METADATA_CONTAINERD_NAMESPACES" envSeparator:","`
+	// Timeout of each container inspection request
+	Timeout time.Duration `yaml:"timeout" env:"BEYLA_CONTAINER_METADATA_TIMEOUT"`
+}
+
+func (c *Config) Validate() error {
+	switch c.Runtime {
+	case "", RuntimeDocker, RuntimeContainerd:
+		return nil
+	default:
+		return fmt.Errorf("invalid runtime %q. Accepted values: %s, %s", c.Runtime, RuntimeDocker, RuntimeContainerd)
+	}
+}
+
+// Inspector returns the metadata of the containers from the API of the container runtime
+type Inspector interface {
+	ContainerInfo(ctx context.Context, containerID string) (*ContainerInfo, error)
+}
+
+// NewInspector returns the client for the container runtime that is specified in the configuration
+func NewInspector(cfg *Config) (Inspector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Runtime == RuntimeContainerd {
+		return NewContainerdClient(cfg)
+	}
+	return NewClient(cfg), nil
+}
+
+// ContainerInfo contains the metadata of a container that is relevant to Beyla
+type ContainerInfo struct {
+	ID        string
+	Name      string
+	ImageName string
+	ImageTag  string
+	Labels    map[string]string
+}
+
+// Client for the Docker Engine API, through a Unix socket
+type Client struct {
+	http *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = DefaultSocket
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	dialer := net.Dialer{}
+	return &Client{http: &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}}
+}
+
+// inspectResponse contains the fields of the container inspection response that Beyla uses
+type inspectResponse struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// ContainerInfo returns the metadata of the container with the provided ID
+func (c *Client) ContainerInfo(ctx context.Context, containerID string) (*ContainerInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://docker/containers/"+url.PathEscape(containerID)+"/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating container inspection request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container %s: %w", containerID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("inspecting container %s: unexpected status %s: %s",
+			containerID, resp.Status, strings.TrimSpace(string(body)))
+	}
+	inspect := inspectResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil, fmt.Errorf("decoding container %s inspection: %w", containerID, err)
+	}
+	imageName, imageTag := splitImage(inspect.Config.Image)
+	return &ContainerInfo{
+		ID: inspect.ID,
+		// docker prefixes the container names with a slash
+		Name:      strings.TrimPrefix(inspect.Name, "/"),
+		ImageName: imageName,
+		ImageTag:  imageTag,
+		Labels:    inspect.Config.Labels,
+	}, nil
+}
+
+// splitImage returns the name and the tag of an image reference such as
+// registry:5000/org/image:1.2@sha256:abcd. The tag is "latest" if the reference
+// does not specify any tag nor digest, and empty if it only specifies a digest.
+func splitImage(image string) (name, tag string) {
+	name, _, hasDigest := strings.Cut(image, "@")
+	// the colon of the tag can't be confused with the colon of the registry port
+	if sep := strings.LastIndexByte(name, ':'); sep > strings.LastIndexByte(name, '/') {
+		return name[:sep], name[sep+1:]
+	}
+	if hasDigest {
+		return name, ""
+	}
+	return name, "latest"
+}
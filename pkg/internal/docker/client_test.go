@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDockerSocket serves a fake Docker Engine API in a Unix socket
+func fakeDockerSocket(t *testing.T) string {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/abcd/json", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write([]byte(`{
+			"Id": "abcd",
+			"Name": "/orders",
+			"Config": {
+				"Image": "registry:5000/shop/orders:1.2.3",
+				"Labels": {"team": "checkout"}
+			}
+		}`))
+	})
+	server := http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socket
+}
+
+func TestContainerInfo(t *testing.T) {
+	client := NewClient(&Config{Socket: fakeDockerSocket(t)})
+
+	info, err := client.ContainerInfo(context.Background(), "abcd")
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerInfo{
+		ID:        "abcd",
+		Name:      "orders",
+		ImageName: "registry:5000/shop/orders",
+		ImageTag:  "1.2.3",
+		Labels:    map[string]string{"team": "checkout"},
+	}, info)
+
+	_, err = client.ContainerInfo(context.Background(), "not-found")
+	assert.Error(t, err)
+}
+
+func TestSplitImage(t *testing.T) {
+	for _, tc := range []struct{ image, name, tag string }{
+		{image: "nginx", name: "nginx", tag: "latest"},
+		{image: "nginx:1.25", name: "nginx", tag: "1.25"},
+		{image: "registry:5000/nginx", name: "registry:5000/nginx", tag: "latest"},
+		{image: "registry:5000/nginx:1.25", name: "registry:5000/nginx", tag: "1.25"},
+		{image: "nginx:1.25@sha256:1234", name: "nginx", tag: "1.25"},
+		{image: "nginx@sha256:1234", name: "nginx", tag: ""},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			name, tag := splitImage(tc.image)
+			assert.Equal(t, tc.name, name)
+			assert.Equal(t, tc.tag, tag)
+		})
+	}
+}
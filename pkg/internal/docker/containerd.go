@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	DefaultContainerdSocket = "/run/containerd/containerd.sock"
+
+	containerdGetMethod = "/containerd.services.containers.v1.Containers/Get"
+	// every containerd request must specify the namespace of the containers
+	containerdNamespaceHeader = "containerd-namespace"
+
+	// containerd does not name the containers, but some of its clients store the name in a label
+	labelNerdctlName             = "nerdctl/name"
+	labelKubernetesContainerName = "io.kubernetes.container.name"
+)
+
+// DefaultContainerdNamespaces are the namespaces that are used by the most common containerd clients:
+// ctr and nerdctl, Kubernetes (CRI), and Docker
+var DefaultContainerdNamespaces = []string{"default", "k8s.io", "moby"}
+
+// field numbers of the containerd.services.containers.v1 messages that Beyla uses
+const (
+	getContainerRequestID         = protowire.Number(1)
+	getContainerResponseContainer = protowire.Number(1)
+	containerID                   = protowire.Number(1)
+	containerLabels               = protowire.Number(2)
+	containerImage                = protowire.Number(3)
+	mapEntryKey                   = protowire.Number(1)
+	mapEntryValue                 = protowire.Number(2)
+)
+
+// ContainerdClient for the containerd gRPC API, through a Unix socket.
+// The few protobuf messages that Beyla needs are encoded and decoded by hand, to avoid
+// depending on the containerd client and its large dependency tree.
+type ContainerdClient struct {
+	conn       *grpc.ClientConn
+	timeout    time.Duration
+	namespaces []string
+}
+
+func NewContainerdClient(cfg *Config) (*ContainerdClient, error) {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = DefaultContainerdSocket
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	namespaces := cfg.ContainerdNamespaces
+	if len(namespaces) == 0 {
+		namespaces = DefaultContainerdNamespaces
+	}
+	dialer := net.Dialer{}
+	// the connection is lazily established on the first request
+	conn, err := grpc.DialContext(context.Background(), "passthrough:///containerd",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating containerd client: %w", err)
+	}
+	return &ContainerdClient{conn: conn, timeout: timeout, namespaces: namespaces}, nil
+}
+
+// ContainerInfo returns the metadata of the container with the provided ID, looking for it
+// in all the configured namespaces
+func (c *ContainerdClient) ContainerInfo(ctx context.Context, containerID string) (*ContainerInfo, error) {
+	for _, namespace := range c.namespaces {
+		info, err := c.containerInfo(ctx, namespace, containerID)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("inspecting container %s in namespace %s: %w", containerID, namespace, err)
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("container %s not found in containerd namespaces %v", containerID, c.namespaces)
+}
+
+func (c *ContainerdClient) containerInfo(ctx context.Context, namespace, id string) (*ContainerInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, namespace)
+
+	request := protowire.AppendTag(nil, getContainerRequestID, protowire.BytesType)
+	request = protowire.AppendString(request, id)
+	var response []byte
+	if err := c.conn.Invoke(ctx, containerdGetMethod, request, &response); err != nil {
+		return nil, err
+	}
+	return parseGetContainerResponse(response)
+}
+
+func parseGetContainerResponse(response []byte) (*ContainerInfo, error) {
+	var container []byte
+	if err := forEachBytesField(response, func(num protowire.Number, value []byte) {
+		if num == getContainerResponseContainer {
+			container = value
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("decoding containerd response: %w", err)
+	}
+	info := ContainerInfo{Labels: map[string]string{}}
+	var image string
+	err := forEachBytesField(container, func(num protowire.Number, value []byte) {
+		switch num {
+		case containerID:
+			info.ID = string(value)
+		case containerImage:
+			image = string(value)
+		case containerLabels:
+			var key, val string
+			_ = forEachBytesField(value, func(num protowire.Number, value []byte) {
+				switch num {
+				case mapEntryKey:
+					key = string(value)
+				case mapEntryValue:
+					val = string(value)
+				}
+			})
+			info.Labels[key] = val
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decoding containerd container: %w", err)
+	}
+	if info.ID == "" {
+		return nil, fmt.Errorf("containerd response does not contain any container")
+	}
+	info.ImageName, info.ImageTag = splitImage(image)
+	switch {
+	case info.Labels[labelNerdctlName] != "":
+		info.Name = info.Labels[labelNerdctlName]
+	case info.Labels[labelKubernetesContainerName] != "":
+		info.Name = info.Labels[labelKubernetesContainerName]
+	default:
+		info.Name = info.ID
+	}
+	return &info, nil
+}
+
+// forEachBytesField invokes fn for each field of the protobuf message whose wire type is bytes
+// (strings, embedded messages and map entries). Other fields are skipped.
+func forEachBytesField(msg []byte, fn func(num protowire.Number, value []byte)) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, msg); n < 0 {
+				return protowire.ParseError(n)
+			}
+			msg = msg[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		fn(num, value)
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// rawCodec sends and receives the already encoded protobuf messages
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return msg, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*msg = append((*msg)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
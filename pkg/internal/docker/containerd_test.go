@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendString(msg []byte, num protowire.Number, value string) []byte {
+	msg = protowire.AppendTag(msg, num, protowire.BytesType)
+	return protowire.AppendString(msg, value)
+}
+
+func appendBytes(msg []byte, num protowire.Number, value []byte) []byte {
+	msg = protowire.AppendTag(msg, num, protowire.BytesType)
+	return protowire.AppendBytes(msg, value)
+}
+
+// fakeContainerdSocket serves a fake containerd API in a Unix socket, which only knows
+// the container "abcd" in the k8s.io namespace
+func fakeContainerdSocket(t *testing.T) string {
+	socket := filepath.Join(t.TempDir(), "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			if method, _ := grpc.MethodFromServerStream(stream); method != containerdGetMethod {
+				return status.Error(codes.Unimplemented, method)
+			}
+			var request []byte
+			if err := stream.RecvMsg(&request); err != nil {
+				return err
+			}
+			var id string
+			require.NoError(t, forEachBytesField(request, func(_ protowire.Number, value []byte) {
+				id = string(value)
+			}))
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			if id != "abcd" || len(md.Get(containerdNamespaceHeader)) == 0 ||
+				md.Get(containerdNamespaceHeader)[0] != "k8s.io" {
+				return status.Error(codes.NotFound, "container not found")
+			}
+			var container []byte
+			container = appendString(container, containerID, "abcd")
+			container = appendBytes(container, containerLabels,
+				appendString(appendString(nil, mapEntryKey, "io.kubernetes.container.name"), mapEntryValue, "orders"))
+			container = appendBytes(container, containerLabels,
+				appendString(appendString(nil, mapEntryKey, "team"), mapEntryValue, "checkout"))
+			container = appendString(container, containerImage, "registry:5000/shop/orders:1.2.3")
+			// runtime field, which is ignored
+			container = appendBytes(container, 4, appendString(nil, 1, "io.containerd.runc.v2"))
+			return stream.SendMsg(appendBytes(nil, getContainerResponseContainer, container))
+		}))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return socket
+}
+
+func TestContainerdContainerInfo(t *testing.T) {
+	client, err := NewContainerdClient(&Config{Socket: fakeContainerdSocket(t)})
+	require.NoError(t, err)
+
+	info, err := client.ContainerInfo(context.Background(), "abcd")
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerInfo{
+		ID:        "abcd",
+		Name:      "orders",
+		ImageName: "registry:5000/shop/orders",
+		ImageTag:  "1.2.3",
+		Labels:    map[string]string{"io.kubernetes.container.name": "orders", "team": "checkout"},
+	}, info)
+
+	_, err = client.ContainerInfo(context.Background(), "not-found")
+	assert.Error(t, err)
+}
+
+func TestParseGetContainerResponse_Name(t *testing.T) {
+	container := appendString(nil, containerID, "abcd")
+	info, err := parseGetContainerResponse(appendBytes(nil, getContainerResponseContainer, container))
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", info.Name)
+
+	container = appendBytes(container, containerLabels,
+		appendString(appendString(nil, mapEntryKey, "nerdctl/name"), mapEntryValue, "payments"))
+	info, err = parseGetContainerResponse(appendBytes(nil, getContainerResponseContainer, container))
+	require.NoError(t, err)
+	assert.Equal(t, "payments", info.Name)
+
+	_, err = parseGetContainerResponse(nil)
+	assert.Error(t, err)
+}
+
+func TestNewInspector(t *testing.T) {
+	inspector, err := NewInspector(&Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &Client{}, inspector)
+
+	inspector, err = NewInspector(&Config{Runtime: RuntimeContainerd})
+	require.NoError(t, err)
+	assert.IsType(t, &ContainerdClient{}, inspector)
+
+	_, err = NewInspector(&Config{Runtime: "cri-o"})
+	assert.Error(t, err)
+}
@@ -55,6 +55,7 @@ type bpfHttpInfoT struct {
 	StartMonotimeNs uint64
 	EndMonotimeNs   uint64
 	Buf             [160]uint8
+	RspBuf          [160]uint8
 	Len             uint32
 	RespLen         uint32
 	Status          uint16
@@ -102,6 +103,8 @@ type bpfHttpRequestTrace struct {
 		UserPid uint32
 		Ns      uint32
 	}
+	ReqHeaders  [4][64]uint8
+	RespHeaders [4][64]uint8
 }
 
 type bpfSqlRequestTrace struct {
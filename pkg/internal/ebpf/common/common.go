@@ -55,9 +55,16 @@ type TracerConfig struct {
 	// By default, it will be /var/run/beyla
 	BpfBaseDir string `yaml:"bpf_fs_base_dir" env:"BEYLA_BPF_FS_BASE_DIR"`
 
+	// PersistentState keeps the eBPF maps and the attached probes pinned under the BpfBaseDir
+	// when Beyla exits, so a restarted Beyla adopts them instead of attaching the probes again.
+	PersistentState bool `yaml:"persistent_state" env:"BEYLA_BPF_PERSISTENT_STATE"`
+
 	// If enabled, the kprobes based HTTP request tracking will start tracking the request
 	// headers to process any 'Traceparent' fields.
 	TrackRequestHeaders bool `yaml:"track_request_headers" env:"BEYLA_BPF_TRACK_REQUEST_HEADERS"`
+
+	// HTTPHeaders specifies which HTTP headers are captured and added to the spans
+	HTTPHeaders HTTPHeadersConfig `yaml:"http_headers"`
 }
 
 // Probe holds the information of the instrumentation points of a given function: its start and end offsets and
@@ -83,6 +90,22 @@ type Filter struct {
 func ptlog() *slog.Logger { return slog.With("component", "ebpf.ProcessTracer") }
 
 func ReadHTTPRequestTraceAsSpan(record *ringbuf.Record) (request.Span, bool, error) {
+	return readHTTPRequestTraceAsSpan(record, nil)
+}
+
+// httpRequestTraceReader returns a ring buffer record reader that also captures
+// the HTTP headers selected in the configuration
+func httpRequestTraceReader(cfg *HTTPHeadersConfig) func(*ringbuf.Record) (request.Span, bool, error) {
+	hc := newHeaderCapture(cfg)
+	if hc == nil {
+		return ReadHTTPRequestTraceAsSpan
+	}
+	return func(record *ringbuf.Record) (request.Span, bool, error) {
+		return readHTTPRequestTraceAsSpan(record, hc)
+	}
+}
+
+func readHTTPRequestTraceAsSpan(record *ringbuf.Record, hc *headerCapture) (request.Span, bool, error) {
 	var eventType uint8
 
 	// we read the type first, depending on the type we decide what kind of record we have
@@ -95,7 +118,7 @@ func ReadHTTPRequestTraceAsSpan(record *ringbuf.Record) (request.Span, bool, err
 	case EventTypeSQL:
 		return ReadSQLRequestTraceAsSpan(record)
 	case EventTypeKHTTP:
-		return ReadHTTPInfoIntoSpan(record)
+		return readHTTPInfoIntoSpan(record, hc)
 	case EventTypeKHTTP2:
 		return ReadHTTP2InfoIntoSpan(record)
 	}
@@ -107,7 +130,11 @@ func ReadHTTPRequestTraceAsSpan(record *ringbuf.Record) (request.Span, bool, err
 		return request.Span{}, true, err
 	}
 
-	return HTTPRequestTraceToSpan(&event), false, nil
+	span := HTTPRequestTraceToSpan(&event)
+	if span.Type == request.EventTypeHTTP || span.Type == request.EventTypeHTTPClient {
+		hc.addGoHeaders(&span, &event)
+	}
+	return span, false, nil
 }
 
 func ReadSQLRequestTraceAsSpan(record *ringbuf.Record) (request.Span, bool, error) {
@@ -0,0 +1,207 @@
+package ebpfcommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// these values must match the HEADERS_MAX_COUNT, HEADER_NAME_MAX_LEN and HEADER_VAL_MAX_LEN
+// definitions in bpf/http_trace.h
+const (
+	headersMaxCount  = 4
+	headerNameMaxLen = 32
+	headerValMaxLen  = 64
+)
+
+// HTTPHeadersConfig selects the HTTP headers whose values are captured and added to the spans
+type HTTPHeadersConfig struct {
+	// Request contains the names of the HTTP request headers to capture. Names are case-insensitive.
+	Request []string `yaml:"request" env:"BEYLA_BPF_HTTP_REQUEST_HEADERS" envSeparator:","`
+	// Response contains the names of the HTTP response headers to capture. Names are case-insensitive.
+	Response []string `yaml:"response" env:"BEYLA_BPF_HTTP_RESPONSE_HEADERS" envSeparator:","`
+	// MetricLabels contains the names of the captured headers that are also added as metric labels.
+	MetricLabels []string `yaml:"metric_labels" env:"BEYLA_BPF_HTTP_HEADERS_METRIC_LABELS" envSeparator:","`
+	// MetricLabelValues limits the number of distinct values that each header reports as metric label.
+	// Further values are reported as "other".
+	MetricLabelValues int `yaml:"metric_label_values" env:"BEYLA_BPF_HTTP_HEADERS_METRIC_LABEL_VALUES"`
+}
+
+func (c *HTTPHeadersConfig) Validate() error {
+	if err := validateHeaderNames("request", c.RequestNames()); err != nil {
+		return err
+	}
+	if err := validateHeaderNames("response", c.ResponseNames()); err != nil {
+		return err
+	}
+	if len(c.MetricLabels) > 0 && c.MetricLabelValues < 1 {
+		return fmt.Errorf("metric_label_values must be at least 1. Got %d", c.MetricLabelValues)
+	}
+	return nil
+}
+
+func validateHeaderNames(section string, names []string) error {
+	if len(names) > headersMaxCount {
+		return fmt.Errorf("%s: at most %d headers can be captured. Got %d", section, headersMaxCount, len(names))
+	}
+	for _, name := range names {
+		if len(name) > headerNameMaxLen {
+			return fmt.Errorf("%s: header name %q is longer than %d characters", section, name, headerNameMaxLen)
+		}
+	}
+	return nil
+}
+
+// RequestNames returns the lowercase names of the request headers to capture, without duplicates
+func (c *HTTPHeadersConfig) RequestNames() []string {
+	return headerNames(c.Request)
+}
+
+// ResponseNames returns the lowercase names of the response headers to capture, without duplicates
+func (c *HTTPHeadersConfig) ResponseNames() []string {
+	return headerNames(c.Response)
+}
+
+// MetricRequestHeaders returns the lowercase names of the captured request headers to be added as metric labels
+func (c *HTTPHeadersConfig) MetricRequestHeaders() []string {
+	return metricHeaders(c.RequestNames(), c.MetricLabels)
+}
+
+// MetricResponseHeaders returns the lowercase names of the captured response headers to be added as metric labels
+func (c *HTTPHeadersConfig) MetricResponseHeaders() []string {
+	return metricHeaders(c.ResponseNames(), c.MetricLabels)
+}
+
+func headerNames(names []string) []string {
+	var lower []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !contains(lower, name) {
+			lower = append(lower, name)
+		}
+	}
+	return lower
+}
+
+func metricHeaders(captured, labels []string) []string {
+	var names []string
+	for _, name := range headerNames(labels) {
+		if contains(captured, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HeaderNamesConstant returns the provided header names, and their lengths, in the format of the
+// request_header_names/request_header_lens and response_header_names/response_header_lens
+// constants of the Go eBPF programs.
+func HeaderNamesConstant(names []string) ([headersMaxCount][headerNameMaxLen]uint8, [headersMaxCount]uint8) {
+	var chars [headersMaxCount][headerNameMaxLen]uint8
+	var lens [headersMaxCount]uint8
+	for i, name := range names {
+		if i >= headersMaxCount {
+			break
+		}
+		lens[i] = uint8(copy(chars[i][:], name))
+	}
+	return chars, lens
+}
+
+// headerCapture contains the lowercase names of the request and response headers to capture,
+// in the same order as they are provided to the eBPF programs.
+// A nil headerCapture does not capture any header.
+type headerCapture struct {
+	request  []string
+	response []string
+}
+
+func newHeaderCapture(cfg *HTTPHeadersConfig) *headerCapture {
+	hc := headerCapture{request: cfg.RequestNames(), response: cfg.ResponseNames()}
+	if len(hc.request) == 0 && len(hc.response) == 0 {
+		return nil
+	}
+	return &hc
+}
+
+// addGoHeaders adds to the span the headers captured by the Go eBPF programs
+func (hc *headerCapture) addGoHeaders(span *request.Span, trace *HTTPRequestTrace) {
+	if hc == nil {
+		return
+	}
+	span.RequestHeaders = goHeaders(hc.request, trace.ReqHeaders[:])
+	span.ResponseHeaders = goHeaders(hc.response, trace.RespHeaders[:])
+}
+
+// addKernelHeaders adds to the span the headers parsed from the request and response
+// buffers captured by the kprobes
+func (hc *headerCapture) addKernelHeaders(span *request.Span, info *BPFHTTPInfo) {
+	if hc == nil {
+		return
+	}
+	span.RequestHeaders = parseHeaders(hc.request, info.Buf[:])
+	span.ResponseHeaders = parseHeaders(hc.response, info.RspBuf[:])
+}
+
+// goHeaders returns the headers captured by the Go eBPF programs, where the value of each
+// header is stored in the position of its name. Empty values belong to missing headers.
+func goHeaders(names []string, values [][headerValMaxLen]uint8) map[string]string {
+	var headers map[string]string
+	for i, name := range names {
+		if i >= len(values) || values[i][0] == 0 {
+			continue
+		}
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[name] = cstr(values[i][:])
+	}
+	return headers
+}
+
+// parse the provided headers from the raw request or response buffer, which starts with
+// the request or status line and might be truncated.
+func parseHeaders(names []string, buf []uint8) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	content := cstr(buf)
+	// if the buffer was filled up, the last line might be truncated, so we discard it
+	truncated := len(content) == len(buf)
+
+	var headers map[string]string
+	lines := strings.Split(content, "\r\n")
+	if truncated {
+		lines = lines[:len(lines)-1]
+	}
+	// ignoring the request or status line
+	for _, line := range lines[min(1, len(lines)):] {
+		if line == "" {
+			// end of headers section
+			break
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 {
+			continue
+		}
+		name := strings.ToLower(line[:colon])
+		if !contains(names, name) {
+			continue
+		}
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[name] = strings.TrimSpace(line[colon+1:])
+	}
+	return headers
+}
@@ -0,0 +1,120 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func TestParseHeaders(t *testing.T) {
+	names := []string{"x-tenant-id", "user-agent", "accept"}
+	assert.Equal(t, map[string]string{
+		"x-tenant-id": "tenant1",
+		"user-agent":  "curl/7.81.0",
+	}, parseHeaders(names, []uint8("GET /hello HTTP/1.1\r\nHost: localhost\r\n"+
+		"x-tenant-id: tenant1\r\nUSER-AGENT:curl/7.81.0 \r\n\r\nAccept: */*\r\n")))
+
+	// response buffers start with the status line
+	assert.Equal(t, map[string]string{"accept": "*/*"},
+		parseHeaders(names, []uint8("HTTP/1.1 200 OK\r\nAccept: */*\r\n\r\n")))
+
+	// headers not present
+	assert.Empty(t, parseHeaders(names, []uint8("GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")))
+
+	// the last line of a full buffer is discarded, as it might be truncated
+	buf := [16]uint8{}
+	copy(buf[:], "GET / HTTP/1.1\r\nAccept: */*")
+	assert.Empty(t, parseHeaders(names, buf[:]))
+
+	// no headers are captured if none are configured
+	assert.Nil(t, parseHeaders(nil, []uint8("GET / HTTP/1.1\r\nAccept: */*\r\n\r\n")))
+}
+
+func TestHTTPHeadersConfig(t *testing.T) {
+	cfg := HTTPHeadersConfig{
+		Request:           []string{"X-Tenant-ID", " user-agent ", "x-tenant-id"},
+		Response:          []string{"Content-Type"},
+		MetricLabels:      []string{"x-tenant-id", "Content-Type", "not-captured"},
+		MetricLabelValues: 10,
+	}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"x-tenant-id", "user-agent"}, cfg.RequestNames())
+	assert.Equal(t, []string{"content-type"}, cfg.ResponseNames())
+	assert.Equal(t, []string{"x-tenant-id"}, cfg.MetricRequestHeaders())
+	assert.Equal(t, []string{"content-type"}, cfg.MetricResponseHeaders())
+
+	names, lens := HeaderNamesConstant(cfg.RequestNames())
+	assert.Equal(t, [4]uint8{11, 10, 0, 0}, lens)
+	assert.Equal(t, "x-tenant-id", cstr(names[0][:]))
+	assert.Equal(t, "user-agent", cstr(names[1][:]))
+
+	cfg.Response = []string{"a", "b", "c", "d", "e"}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Response = []string{"x-a-very-long-header-name-over-the-limit"}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Response = nil
+	cfg.MetricLabelValues = 0
+	assert.Error(t, cfg.Validate())
+}
+
+func TestHTTPRequestTraceReader_CapturesHeaders(t *testing.T) {
+	var record BPFHTTPInfo
+	record.Flags = EventTypeKHTTP
+	record.Type = 1
+	copy(record.Buf[:], "GET /hello HTTP/1.1\r\nHost: localhost:7033\r\nX-Tenant-ID: tenant1\r\n\r\n")
+	copy(record.RspBuf[:], "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n")
+	raw := toBytes(t, &record)
+
+	cfg := HTTPHeadersConfig{Request: []string{"x-tenant-id"}, Response: []string{"content-type"}}
+	reader := httpRequestTraceReader(&cfg)
+	span, ignore, err := reader(&ringbuf.Record{RawSample: raw})
+	require.NoError(t, err)
+	assert.False(t, ignore)
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant1"}, span.RequestHeaders)
+	assert.Equal(t, map[string]string{"content-type": "text/plain"}, span.ResponseHeaders)
+
+	// by default, no headers are captured
+	span, _, err = httpRequestTraceReader(&HTTPHeadersConfig{})(&ringbuf.Record{RawSample: raw})
+	require.NoError(t, err)
+	assert.Nil(t, span.RequestHeaders)
+	assert.Nil(t, span.ResponseHeaders)
+}
+
+func TestHTTPRequestTraceReader_CapturesGoHeaders(t *testing.T) {
+	trace := makeHTTPRequestTrace("GET", "/hello", "127.0.0.1:1234", 200, 5)
+	copy(trace.ReqHeaders[1][:], "tenant1")
+	copy(trace.RespHeaders[0][:], "text/plain")
+	raw := toBytes(t, &trace)
+
+	cfg := HTTPHeadersConfig{
+		Request:  []string{"user-agent", "x-tenant-id"},
+		Response: []string{"content-type"},
+	}
+	span, _, err := httpRequestTraceReader(&cfg)(&ringbuf.Record{RawSample: raw})
+	require.NoError(t, err)
+	assert.Equal(t, request.EventTypeHTTP, span.Type)
+	// missing headers, as the user-agent, aren't reported
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant1"}, span.RequestHeaders)
+	assert.Equal(t, map[string]string{"content-type": "text/plain"}, span.ResponseHeaders)
+
+	// gRPC events don't report headers
+	trace.Type = uint8(request.EventTypeGRPC)
+	span, _, err = httpRequestTraceReader(&cfg)(&ringbuf.Record{RawSample: toBytes(t, &trace)})
+	require.NoError(t, err)
+	assert.Nil(t, span.RequestHeaders)
+	assert.Nil(t, span.ResponseHeaders)
+}
+
+func toBytes(t *testing.T, record any) []byte {
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, record))
+	return buf.Bytes()
+}
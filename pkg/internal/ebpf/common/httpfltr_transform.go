@@ -61,6 +61,10 @@ type HTTPInfo struct {
 }
 
 func ReadHTTPInfoIntoSpan(record *ringbuf.Record) (request.Span, bool, error) {
+	return readHTTPInfoIntoSpan(record, nil)
+}
+
+func readHTTPInfoIntoSpan(record *ringbuf.Record, hc *headerCapture) (request.Span, bool, error) {
 	var event BPFHTTPInfo
 	var result HTTPInfo
 
@@ -90,7 +94,9 @@ func ReadHTTPInfoIntoSpan(record *ringbuf.Record) (request.Span, bool, error) {
 	// set generic service to be overwritten later by the PID filters
 	result.Service = svc.ID{SDKLanguage: svc.InstrumentableGeneric}
 
-	return httpInfoToSpan(&result), false, nil
+	span := httpInfoToSpan(&result)
+	hc.addKernelHeaders(&span, &event)
+	return span, false, nil
 }
 
 func (event *BPFHTTPInfo) url() string {
@@ -50,9 +50,20 @@ type PIDsFilter struct {
 	log     *slog.Logger
 	current map[uint32]map[uint32]PIDInfo
 	mux     *sync.RWMutex
+	// updated receives a value after the allowed PIDs change
+	updated chan struct{}
+}
+
+// PIDEntry is an allowed PID, as seen from its namespace, and the service it belongs to
+type PIDEntry struct {
+	Namespace uint32  `json:"namespace"`
+	PID       uint32  `json:"pid"`
+	Type      PIDType `json:"type"`
+	Service   svc.ID  `json:"service"`
 }
 
 var commonPIDsFilter *PIDsFilter
+var commonIdentityFilter *IdentityPidsFilter
 var commonLock sync.Mutex
 
 func NewPIDsFilter(log *slog.Logger) *PIDsFilter {
@@ -60,6 +71,7 @@ func NewPIDsFilter(log *slog.Logger) *PIDsFilter {
 		log:     log,
 		current: map[uint32]map[uint32]PIDInfo{},
 		mux:     &sync.RWMutex{},
+		updated: make(chan struct{}, 1),
 	}
 }
 
@@ -68,7 +80,10 @@ func CommonPIDsFilter(systemWide bool) ServiceFilter {
 	defer commonLock.Unlock()
 
 	if systemWide {
-		return &IdentityPidsFilter{}
+		if commonIdentityFilter == nil {
+			commonIdentityFilter = &IdentityPidsFilter{}
+		}
+		return commonIdentityFilter
 	}
 
 	if commonPIDsFilter == nil {
@@ -82,12 +97,59 @@ func (pf *PIDsFilter) AllowPID(pid uint32, svc svc.ID, pidType PIDType) {
 	pf.mux.Lock()
 	defer pf.mux.Unlock()
 	pf.addPID(pid, svc, pidType)
+	pf.notifyUpdate()
 }
 
 func (pf *PIDsFilter) BlockPID(pid uint32) {
 	pf.mux.Lock()
 	defer pf.mux.Unlock()
 	pf.removePID(pid)
+	pf.notifyUpdate()
+}
+
+// Updated returns a channel that receives a value after the allowed PIDs change. Successive
+// changes might be notified only once, and the channel should have a single receiver.
+func (pf *PIDsFilter) Updated() <-chan struct{} {
+	return pf.updated
+}
+
+func (pf *PIDsFilter) notifyUpdate() {
+	select {
+	case pf.updated <- struct{}{}:
+	default:
+		// a notification is already pending
+	}
+}
+
+// Entries returns all the allowed PIDs
+func (pf *PIDsFilter) Entries() []PIDEntry {
+	pf.mux.RLock()
+	defer pf.mux.RUnlock()
+	var entries []PIDEntry
+	for ns, pids := range pf.current {
+		for pid, info := range pids {
+			entries = append(entries, PIDEntry{Namespace: ns, PID: pid, Type: info.pidType, Service: info.service})
+		}
+	}
+	return entries
+}
+
+// SetEntries replaces all the allowed PIDs by the provided entries, e.g. as received from
+// the Entries method of the PIDsFilter of another process.
+func (pf *PIDsFilter) SetEntries(entries []PIDEntry) {
+	current := map[uint32]map[uint32]PIDInfo{}
+	for _, e := range entries {
+		ns, ok := current[e.Namespace]
+		if !ok {
+			ns = map[uint32]PIDInfo{}
+			current[e.Namespace] = ns
+		}
+		ns[e.PID] = PIDInfo{service: e.Service, pidType: e.Type}
+	}
+	pf.mux.Lock()
+	defer pf.mux.Unlock()
+	pf.current = current
+	pf.notifyUpdate()
 }
 
 func (pf *PIDsFilter) CurrentPIDs(t PIDType) map[uint32]map[uint32]svc.ID {
@@ -191,24 +253,48 @@ func (pf *PIDsFilter) removePID(pid uint32) {
 	}
 }
 
-// IdentityPidsFilter is a PIDsFilter that does not filter anything. It is feasible
-// for system-wide instrumenation
-type IdentityPidsFilter struct{}
+// IdentityPidsFilter is a PIDsFilter that does not filter anything, excepting the
+// PIDs that have been explicitly blocked (e.g. because they are excluded from the
+// discovery). It is feasible for system-wide instrumenation
+type IdentityPidsFilter struct {
+	mux     sync.RWMutex
+	blocked map[uint32]struct{}
+}
 
-func (pf *IdentityPidsFilter) AllowPID(_ uint32, _ svc.ID, _ PIDType) {}
+// AllowPID removes the PID from the list of blocked PIDs
+func (pf *IdentityPidsFilter) AllowPID(pid uint32, _ svc.ID, _ PIDType) {
+	pf.mux.Lock()
+	defer pf.mux.Unlock()
+	delete(pf.blocked, pid)
+}
 
-func (pf *IdentityPidsFilter) BlockPID(_ uint32) {}
+// BlockPID causes the spans from the given PID to be discarded
+func (pf *IdentityPidsFilter) BlockPID(pid uint32) {
+	pf.mux.Lock()
+	defer pf.mux.Unlock()
+	if pf.blocked == nil {
+		pf.blocked = map[uint32]struct{}{}
+	}
+	pf.blocked[pid] = struct{}{}
+}
 
 func (pf *IdentityPidsFilter) CurrentPIDs(_ PIDType) map[uint32]map[uint32]svc.ID {
 	return nil
 }
 
 func (pf *IdentityPidsFilter) Filter(inputSpans []request.Span) []request.Span {
+	pf.mux.RLock()
+	defer pf.mux.RUnlock()
+	outputSpans := inputSpans[:0]
 	for i := range inputSpans {
 		s := &inputSpans[i]
+		if _, ok := pf.blocked[s.Pid.HostPID]; ok {
+			continue
+		}
 		s.ServiceID = serviceInfo(s.Pid.HostPID)
+		outputSpans = append(outputSpans, *s)
 	}
-	return inputSpans
+	return outputSpans
 }
 
 func serviceInfo(pid uint32) svc.ID {
@@ -126,3 +126,39 @@ func TestFilter_NewNSLater(t *testing.T) {
 		{Pid: request.PidInfo{UserPID: 789, HostPID: 234, Namespace: 33}},
 	}, pf.Filter(spanSet))
 }
+
+func TestFilter_Entries(t *testing.T) {
+	readNamespace = func(pid int32) (uint32, error) {
+		if pid == 1000 {
+			return 44, nil
+		}
+		return 33, nil
+	}
+	readNamespacePIDs = func(pid int32) ([]uint32, error) {
+		return []uint32{uint32(pid)}, nil
+	}
+	pf := NewPIDsFilter(slog.With("env", "testing"))
+	pf.AllowPID(123, svc.ID{Name: "foo"}, PIDTypeGo)
+	pf.AllowPID(1000, svc.ID{Name: "bar"}, PIDTypeKProbes)
+	select {
+	case <-pf.Updated():
+	default:
+		t.Fatal("expected the filter to notify the update")
+	}
+
+	// the entries of a filter can be copied into another filter, e.g. in another process
+	entries := pf.Entries()
+	assert.ElementsMatch(t, []PIDEntry{
+		{Namespace: 33, PID: 123, Type: PIDTypeGo, Service: svc.ID{Name: "foo"}},
+		{Namespace: 44, PID: 1000, Type: PIDTypeKProbes, Service: svc.ID{Name: "bar"}},
+	}, entries)
+
+	copied := NewPIDsFilter(slog.With("env", "testing"))
+	copied.AllowPID(789, svc.ID{Name: "replaced"}, PIDTypeGo)
+	copied.SetEntries(entries)
+	assert.ElementsMatch(t, entries, copied.Entries())
+	assert.Equal(t, []request.Span{
+		{Pid: request.PidInfo{UserPID: 123, HostPID: 333, Namespace: 33}, ServiceID: svc.ID{Name: "foo"}},
+		{Pid: request.PidInfo{UserPID: 1000, HostPID: 1234, Namespace: 44}, ServiceID: svc.ID{Name: "bar"}},
+	}, copied.Filter(spanSet))
+}
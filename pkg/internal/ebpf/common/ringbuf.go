@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cilium/ebpf"
@@ -29,9 +30,12 @@ var readerFactory = func(rb *ebpf.Map) (ringBufReader, error) {
 }
 
 type ringBufForwarder struct {
-	cfg        *TracerConfig
-	logger     *slog.Logger
-	ringbuffer *ebpf.Map
+	// name of the forwarder, to label its internal metrics
+	name   string
+	cfg    *TracerConfig
+	logger *slog.Logger
+	// openReader returns the reader of the ring buffer
+	openReader func() (ringBufReader, error)
 	closers    []io.Closer
 	spans      []request.Span
 	spansLen   int
@@ -42,11 +46,59 @@ type ringBufForwarder struct {
 	// belong to a process that does not match the discovery policies
 	filter  func([]request.Span) []request.Span
 	metrics imetrics.Reporter
+
+	// reading is true since the forwarder starts reading the ring buffer
+	reading atomic.Bool
+	// lastHeartbeat is the Unix nanoseconds of the last time that the forwarder was able to
+	// flush the events on timeout. It is not updated if the batch timeout is disabled.
+	lastHeartbeat atomic.Int64
 }
 
+// sharedRingbufName identifies the forwarder of the ring buffer that is shared by all the tracers
+const sharedRingbufName = "shared"
+
 var singleRbf *ringBufForwarder
 var singleRbfLock sync.Mutex
 
+// loaded is true since Beyla is able to load the eBPF programs of the tracers
+var loaded atomic.Bool
+
+// MarkLoaded records that the eBPF programs of the process watcher have been loaded, or that the
+// process tracer has been initialized, so Beyla is ready to instrument the processes even if none
+// of them has been discovered yet.
+func MarkLoaded() {
+	loaded.Store(true)
+}
+
+// Loaded returns whether the eBPF programs can be loaded, as recorded by MarkLoaded, or whether the
+// shared ring buffer forwarder has started reading events.
+func Loaded() bool {
+	return loaded.Load() || SharedRingbufReading()
+}
+
+// SharedRingbufReading returns whether the shared ring buffer forwarder has started reading events,
+// which means that the eBPF programs of at least one tracer have been loaded. If the ring buffer has
+// been handed over to another process, it returns whether any tracer has been loaded.
+func SharedRingbufReading() bool {
+	singleRbfLock.Lock()
+	defer singleRbfLock.Unlock()
+	return singleRbf != nil && singleRbf.reading.Load()
+}
+
+// SharedRingbufHeartbeat returns the last time that the shared ring buffer forwarder was able to
+// flush its events on timeout. If the forwarder stops updating it, it is stalled, either in its own
+// read loop or because the next pipeline stages don't accept more events.
+// The returned boolean is false if the shared forwarder is not running, the batch timeout is disabled or
+// the ring buffer has been handed over to another process.
+func SharedRingbufHeartbeat() (time.Time, bool) {
+	singleRbfLock.Lock()
+	defer singleRbfLock.Unlock()
+	if singleRbf == nil || singleRbf.cfg.BatchTimeout <= 0 || !singleRbf.reading.Load() || singleRbf.handedOver() {
+		return time.Time{}, false
+	}
+	return time.Unix(0, singleRbf.lastHeartbeat.Load()), true
+}
+
 // ForwardRingbuf returns a function reads HTTPRequestTraces from an input ring buffer, accumulates them into an
 // internal buffer, and forwards them to an output events channel, previously converted to request.Span
 // instances.
@@ -62,13 +114,17 @@ func SharedRingbuf(
 
 	if singleRbf != nil {
 		singleRbf.closers = append(singleRbf.closers, closers...)
+		if singleRbf.handedOver() {
+			// the events are read by another process since the first tracer is loaded
+			singleRbf.reading.Store(true)
+		}
 		return singleRbf.alreadyForwarded
 	}
 
 	log := slog.With("component", "ringbuf.Tracer")
 	rbf := ringBufForwarder{
-		cfg: cfg, logger: log, ringbuffer: ringbuffer,
-		closers: closers, reader: ReadHTTPRequestTraceAsSpan,
+		name: sharedRingbufName, cfg: cfg, logger: log, openReader: mapReader(ringbuffer),
+		closers: closers, reader: httpRequestTraceReader(&cfg.HTTPHeaders),
 		filter: filter.Filter, metrics: metrics,
 	}
 	singleRbf = &rbf
@@ -76,6 +132,7 @@ func SharedRingbuf(
 }
 
 func ForwardRingbuf(
+	name string,
 	cfg *TracerConfig,
 	ringbuffer *ebpf.Map,
 	filter ServiceFilter,
@@ -85,18 +142,24 @@ func ForwardRingbuf(
 	closers ...io.Closer,
 ) func(context.Context, chan<- []request.Span) {
 	rbf := ringBufForwarder{
-		cfg: cfg, logger: logger, ringbuffer: ringbuffer,
+		name: name, cfg: cfg, logger: logger, openReader: mapReader(ringbuffer),
 		closers: closers, reader: reader,
 		filter: filter.Filter, metrics: metrics,
 	}
 	return rbf.readAndForward
 }
 
+func mapReader(ringbuffer *ebpf.Map) func() (ringBufReader, error) {
+	return func() (ringBufReader, error) {
+		return readerFactory(ringbuffer)
+	}
+}
+
 func (rbf *ringBufForwarder) readAndForward(ctx context.Context, spansChan chan<- []request.Span) {
 	rbf.logger.Debug("start reading and forwarding")
 	// BPF will send each measured trace via Ring Buffer, so we listen for them from the
 	// user space.
-	eventsReader, err := readerFactory(rbf.ringbuffer)
+	eventsReader, err := rbf.openReader()
 	if err != nil {
 		rbf.logger.Error("creating perf reader. Exiting", err)
 		return
@@ -113,9 +176,11 @@ func (rbf *ringBufForwarder) readAndForward(ctx context.Context, spansChan chan<
 
 	// Forwards periodically on timeout, if the batch is not full
 	if rbf.cfg.BatchTimeout > 0 {
+		rbf.lastHeartbeat.Store(time.Now().UnixNano())
 		rbf.ticker = time.NewTicker(rbf.cfg.BatchTimeout)
 		go rbf.bgFlushOnTimeout(spansChan)
 	}
+	rbf.reading.Store(true)
 
 	// Main loop:
 	// 1. Listen for content in the ring buffer
@@ -145,6 +210,11 @@ func (rbf *ringBufForwarder) readAndForward(ctx context.Context, spansChan chan<
 	}
 }
 
+// handedOver returns true if the ring buffer is read by another process
+func (rbf *ringBufForwarder) handedOver() bool {
+	return rbf.openReader == nil
+}
+
 func (rbf *ringBufForwarder) alreadyForwarded(ctx context.Context, _ chan<- []request.Span) {
 	<-ctx.Done()
 }
@@ -154,6 +224,7 @@ func (rbf *ringBufForwarder) processAndForward(record ringbuf.Record, spansChan
 	defer rbf.access.Unlock()
 	s, ignore, err := rbf.reader(&record)
 	if err != nil {
+		rbf.metrics.TracerRecordError()
 		rbf.logger.Error("error parsing perf event", err)
 		return
 	}
@@ -175,7 +246,12 @@ func (rbf *ringBufForwarder) processAndForward(record ringbuf.Record, spansChan
 
 func (rbf *ringBufForwarder) flushEvents(spansChan chan<- []request.Span) {
 	rbf.metrics.TracerFlush(rbf.spansLen)
-	spansChan <- rbf.filter(rbf.spans[:rbf.spansLen])
+	filtered := rbf.filter(rbf.spans[:rbf.spansLen])
+	if discarded := rbf.spansLen - len(filtered); discarded > 0 {
+		rbf.metrics.FilteredSpans(discarded)
+	}
+	rbf.metrics.TracerChannelFill(rbf.name, len(spansChan), cap(spansChan))
+	spansChan <- filtered
 	rbf.spans = make([]request.Span, rbf.cfg.BatchLength)
 	rbf.spansLen = 0
 }
@@ -188,6 +264,7 @@ func (rbf *ringBufForwarder) bgFlushOnTimeout(spansChan chan<- []request.Span) {
 			rbf.logger.Debug("submitting traces on timeout", "len", rbf.spansLen)
 			rbf.flushEvents(spansChan)
 		}
+		rbf.lastHeartbeat.Store(time.Now().UnixNano())
 		rbf.access.Unlock()
 	}
 }
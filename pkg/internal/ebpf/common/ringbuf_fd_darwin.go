@@ -0,0 +1,8 @@
+package ebpfcommon
+
+import "errors"
+
+// convenience function to allow unit tests compiling in Darwin
+func newFDReader(_ int, _ int) (ringBufReader, error) {
+	return nil, errors.New("reading ring buffers from file descriptors is only supported in Linux")
+}
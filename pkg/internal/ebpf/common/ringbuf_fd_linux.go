@@ -0,0 +1,163 @@
+package ebpfcommon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/sys/unix"
+)
+
+// flags and size of the header of each ring buffer record, as defined in the kernel
+const (
+	ringbufBusyBit    = 1 << 31
+	ringbufDiscardBit = 1 << 30
+	ringbufHeaderSize = 8
+)
+
+// fdReader reads the records of a BPF ring buffer from its file descriptor, by mapping the ring
+// buffer memory into the process. Unlike ringbuf.Reader, it doesn't invoke the bpf() syscall,
+// so it doesn't require any eBPF capability.
+type fdReader struct {
+	fd       int
+	pageSize int
+	mask     uint64
+	// consumer page, which is writable, and the producer page followed by the data pages.
+	// The data pages are mapped twice, so the records that wrap around the end of the ring
+	// buffer are contiguous in memory.
+	consumer []byte
+	producer []byte
+
+	epollFd int
+	// closeFd is an eventfd that interrupts the wait for new records when the reader is closed
+	closeFd int
+	events  []unix.EpollEvent
+
+	// reading is locked during the Read invocations, to not release the resources while reading
+	reading sync.Mutex
+	closed  atomic.Bool
+}
+
+// newFDReader returns a reader for the ring buffer with the provided file descriptor and size,
+// which must be a power of 2 multiple of the page size. The reader owns the file descriptor.
+func newFDReader(fd int, size int) (ringBufReader, error) {
+	pageSize := os.Getpagesize()
+	if size <= 0 || size&(size-1) != 0 || size%pageSize != 0 {
+		return nil, fmt.Errorf("invalid ring buffer size %d: must be a power of 2 multiple of the page size", size)
+	}
+	r := &fdReader{fd: fd, pageSize: pageSize, mask: uint64(size - 1), epollFd: -1, closeFd: -1}
+	var err error
+	if r.consumer, err = unix.Mmap(fd, 0, pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		r.release()
+		return nil, fmt.Errorf("mapping the ring buffer consumer page: %w", err)
+	}
+	if r.producer, err = unix.Mmap(fd, int64(pageSize), pageSize+2*size, unix.PROT_READ, unix.MAP_SHARED); err != nil {
+		r.release()
+		return nil, fmt.Errorf("mapping the ring buffer data pages: %w", err)
+	}
+	if err := r.setupEpoll(); err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fdReader) setupEpoll() error {
+	var err error
+	if r.epollFd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC); err != nil {
+		return fmt.Errorf("creating epoll: %w", err)
+	}
+	if r.closeFd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK); err != nil {
+		return fmt.Errorf("creating eventfd: %w", err)
+	}
+	for _, fd := range []int{r.fd, r.closeFd} {
+		event := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(fd)}
+		if err := unix.EpollCtl(r.epollFd, unix.EPOLL_CTL_ADD, fd, &event); err != nil {
+			return fmt.Errorf("adding file descriptor to epoll: %w", err)
+		}
+	}
+	r.events = make([]unix.EpollEvent, 2)
+	return nil
+}
+
+// Read the next record, blocking until it is available. It returns ringbuf.ErrClosed if the
+// reader has been closed.
+func (r *fdReader) Read() (ringbuf.Record, error) {
+	r.reading.Lock()
+	defer r.reading.Unlock()
+	for {
+		if r.closed.Load() {
+			return ringbuf.Record{}, ringbuf.ErrClosed
+		}
+		if sample, ok := r.next(); ok {
+			return ringbuf.Record{RawSample: sample}, nil
+		}
+		if _, err := unix.EpollWait(r.epollFd, r.events, -1); err != nil && !errors.Is(err, unix.EINTR) {
+			return ringbuf.Record{}, fmt.Errorf("waiting for ring buffer records: %w", err)
+		}
+	}
+}
+
+// next returns a copy of the next committed record, and false if there are no more records
+func (r *fdReader) next() ([]byte, bool) {
+	consumerPos := (*uint64)(unsafe.Pointer(&r.consumer[0]))
+	producerPos := (*uint64)(unsafe.Pointer(&r.producer[0]))
+	for {
+		cons := atomic.LoadUint64(consumerPos)
+		if cons >= atomic.LoadUint64(producerPos) {
+			return nil, false
+		}
+		start := r.pageSize + int(cons&r.mask)
+		header := atomic.LoadUint32((*uint32)(unsafe.Pointer(&r.producer[start])))
+		if header&ringbufBusyBit != 0 {
+			// the record is reserved but not committed yet
+			return nil, false
+		}
+		length := int(header &^ (ringbufBusyBit | ringbufDiscardBit))
+		var sample []byte
+		if header&ringbufDiscardBit == 0 {
+			sample = make([]byte, length)
+			copy(sample, r.producer[start+ringbufHeaderSize:])
+		}
+		// records are 8-byte aligned
+		atomic.StoreUint64(consumerPos, cons+uint64((ringbufHeaderSize+length+7)&^7))
+		if sample != nil {
+			return sample, true
+		}
+	}
+}
+
+// Close the reader, interrupting any blocked Read invocation, and release its resources
+// as well as the ring buffer file descriptor.
+func (r *fdReader) Close() error {
+	if r.closed.Swap(true) {
+		return nil
+	}
+	var wakeup [8]byte
+	wakeup[0] = 1
+	if _, err := unix.Write(r.closeFd, wakeup[:]); err != nil {
+		return fmt.Errorf("interrupting the ring buffer reader: %w", err)
+	}
+	r.reading.Lock()
+	defer r.reading.Unlock()
+	r.release()
+	return nil
+}
+
+func (r *fdReader) release() {
+	if r.consumer != nil {
+		_ = unix.Munmap(r.consumer)
+	}
+	if r.producer != nil {
+		_ = unix.Munmap(r.producer)
+	}
+	for _, fd := range []int{r.epollFd, r.closeFd, r.fd} {
+		if fd >= 0 {
+			_ = unix.Close(fd)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestFDReader(t *testing.T) {
+	size := os.Getpagesize()
+	rb, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: uint32(size)})
+	if err != nil {
+		t.Skipf("can't create a ring buffer. This test requires eBPF privileges: %v", err)
+	}
+	defer rb.Close()
+
+	// the reader owns its file descriptor, as it would receive it from another process
+	fd, err := unix.Dup(rb.FD())
+	require.NoError(t, err)
+	reader, err := newFDReader(fd, size)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// records of diverse lengths, so they are not always aligned and wrap around the ring buffer
+	for i := 0; i < 300; i++ {
+		payload := make([]byte, 4*(1+i%16))
+		for j := range payload {
+			payload[j] = byte(i + j)
+		}
+		writeRecord(t, rb, payload)
+		record, err := reader.Read()
+		require.NoError(t, err)
+		require.Equal(t, payload, record.RawSample, "record %d", i)
+	}
+
+	// it reads the records that have been accumulated
+	writeRecord(t, rb, []byte{1, 2, 3, 4})
+	writeRecord(t, rb, []byte{5, 6, 7, 8, 9, 10, 11, 12})
+	record, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, record.RawSample)
+	record, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{5, 6, 7, 8, 9, 10, 11, 12}, record.RawSample)
+}
+
+func TestFDReader_Close(t *testing.T) {
+	size := os.Getpagesize()
+	rb, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: uint32(size)})
+	if err != nil {
+		t.Skipf("can't create a ring buffer. This test requires eBPF privileges: %v", err)
+	}
+	defer rb.Close()
+	fd, err := unix.Dup(rb.FD())
+	require.NoError(t, err)
+	reader, err := newFDReader(fd, size)
+	require.NoError(t, err)
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := reader.Read()
+		readErr <- err
+	}()
+	require.NoError(t, reader.Close())
+	select {
+	case err := <-readErr:
+		assert.True(t, errors.Is(err, ringbuf.ErrClosed), err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout while waiting for the reader to be interrupted")
+	}
+	// closing twice does nothing
+	require.NoError(t, reader.Close())
+}
+
+func TestFDReader_InvalidSize(t *testing.T) {
+	_, err := newFDReader(-1, 1000)
+	require.Error(t, err)
+}
+
+// writeRecord submits the provided payload, whose length must be multiple of 4, to the ring buffer
+// from an eBPF program
+func writeRecord(t *testing.T, rb *ebpf.Map, payload []byte) {
+	t.Helper()
+	var insns asm.Instructions
+	for i := 0; i < len(payload); i += 4 {
+		insns = append(insns, asm.StoreImm(asm.RFP, int16(i-len(payload)),
+			int64(int32(binary.LittleEndian.Uint32(payload[i:]))), asm.Word))
+	}
+	insns = append(insns,
+		asm.LoadMapPtr(asm.R1, rb.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, int32(-len(payload))),
+		asm.Mov.Imm(asm.R3, int32(len(payload))),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnRingbufOutput.Call(),
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+	)
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.SocketFilter,
+		License:      "GPL",
+		Instructions: insns,
+	})
+	require.NoError(t, err)
+	defer prog.Close()
+	_, err = prog.Run(&ebpf.RunOptions{Data: make([]byte, 14)})
+	require.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+package ebpfcommon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// EventsMapName is the name of the ring buffer that is shared by all the tracers
+const EventsMapName = "events"
+
+// EventsMapSize must match the max_entries of the events ring buffer in bpf/ringbuf.h
+const EventsMapSize = 1 << 16
+
+// fdReaderFactory instantiates a ringBufReader from the file descriptor of a ring buffer. In
+// unit tests, we can replace this function by a mock/dummy.
+var fdReaderFactory = func(fd int, size int) (ringBufReader, error) {
+	return newFDReader(fd, size)
+}
+
+// HandOverSharedRingbuf prepares the shared ring buffer to be read by another process, which receives
+// its file descriptor (see SharedRingbufFromFD). It creates and pins the ring buffer into the provided
+// path, so the tracers that are loaded later reuse it, and makes SharedRingbuf not read it.
+// The ring buffer, as well as the resources of the tracers, are closed when the context is done.
+func HandOverSharedRingbuf(ctx context.Context, cfg *TracerConfig, pinPath string) (*ebpf.Map, error) {
+	singleRbfLock.Lock()
+	defer singleRbfLock.Unlock()
+
+	if singleRbf != nil {
+		return nil, errors.New("the shared ring buffer is already being read")
+	}
+	ringbuffer, err := ebpf.NewMapWithOptions(&ebpf.MapSpec{
+		Name:       EventsMapName,
+		Type:       ebpf.RingBuf,
+		MaxEntries: EventsMapSize,
+		Pinning:    ebpf.PinByName,
+	}, ebpf.MapOptions{PinPath: pinPath})
+	if err != nil {
+		return nil, fmt.Errorf("creating the events ring buffer: %w", err)
+	}
+	// the forwarder has no reader, so it just accumulates the closers of the tracers
+	rbf := &ringBufForwarder{
+		cfg: cfg, logger: slog.With("component", "ringbuf.Tracer"),
+		closers: []io.Closer{ringbuffer},
+	}
+	singleRbf = rbf
+	go func() {
+		<-ctx.Done()
+		singleRbfLock.Lock()
+		defer singleRbfLock.Unlock()
+		rbf.closeAllResources()
+	}()
+	return ringbuffer, nil
+}
+
+// SharedRingbufFromFD returns a function that reads the events of the shared ring buffer from the file
+// descriptor that another process handed over (see HandOverSharedRingbuf), and forwards them as the
+// function returned by SharedRingbuf does. Reading the ring buffer doesn't require any eBPF capability.
+func SharedRingbufFromFD(
+	cfg *TracerConfig,
+	filter ServiceFilter,
+	fd int,
+	size int,
+	metrics imetrics.Reporter,
+) func(context.Context, chan<- []request.Span) {
+	singleRbfLock.Lock()
+	defer singleRbfLock.Unlock()
+
+	rbf := ringBufForwarder{
+		cfg: cfg, logger: slog.With("component", "ringbuf.Tracer"),
+		openReader: func() (ringBufReader, error) {
+			return fdReaderFactory(fd, size)
+		},
+		reader: httpRequestTraceReader(&cfg.HTTPHeaders),
+		filter: filter.Filter, metrics: metrics,
+	}
+	singleRbf = &rbf
+	return singleRbf.readAndForward
+}
@@ -33,6 +33,7 @@ func TestForwardRingbuf_CapacityFull(t *testing.T) {
 	fltr := TestPidsFilter{services: map[uint32]svc.ID{}}
 	fltr.AllowPID(1, svc.ID{Name: "myService"}, PIDTypeGo)
 	go ForwardRingbuf(
+		"test",
 		&TracerConfig{BatchLength: 10},
 		nil, // the source ring buffer can be null
 		&fltr,
@@ -75,6 +76,46 @@ func TestForwardRingbuf_CapacityFull(t *testing.T) {
 	}
 }
 
+func TestForwardRingbuf_FilteredSpans(t *testing.T) {
+	// GIVEN a ring buffer forwarder
+	ringBuf, restore := replaceTestRingBuf()
+	defer restore()
+	metrics := &metricsReporter{}
+	forwardedMessages := make(chan []request.Span, 100)
+	fltr := allowedPidsFilter{TestPidsFilter{services: map[uint32]svc.ID{}}}
+	fltr.AllowPID(1, svc.ID{Name: "myService"}, PIDTypeGo)
+	go ForwardRingbuf(
+		"test",
+		&TracerConfig{BatchLength: 10},
+		nil, // the source ring buffer can be null
+		&fltr,
+		ReadHTTPRequestTraceAsSpan,
+		slog.With("test", "TestForwardRingbuf_FilteredSpans"),
+		metrics,
+		nil,
+	)(context.Background(), forwardedMessages)
+
+	// WHEN it receives trace events from both allowed and not allowed processes
+	var get = [7]byte{'G', 'E', 'T', 0, 0, 0, 0}
+	for i := 0; i < 20; i++ {
+		t := HTTPRequestTrace{Type: 1, Method: get, ContentLength: int64(i)}
+		t.Pid.HostPid = uint32(1 + i%2)
+		ringBuf.events <- t
+	}
+
+	// THEN it only forwards the events from the allowed processes
+	batch := testutil.ReadChannel(t, forwardedMessages, testTimeout)
+	require.Len(t, batch, 5)
+	batch = testutil.ReadChannel(t, forwardedMessages, testTimeout)
+	require.Len(t, batch, 5)
+
+	// AND accounts the discarded events in the metrics
+	assert.Equal(t, 20, metrics.flushedLen)
+	assert.Equal(t, 10, metrics.filtered)
+	assert.Equal(t, 100, metrics.channelCapacity)
+	assert.Equal(t, "test", metrics.channelTracer)
+}
+
 func TestForwardRingbuf_Deadline(t *testing.T) {
 	// GIVEN a ring buffer forwarder
 	ringBuf, restore := replaceTestRingBuf()
@@ -85,6 +126,7 @@ func TestForwardRingbuf_Deadline(t *testing.T) {
 	fltr := TestPidsFilter{services: map[uint32]svc.ID{}}
 	fltr.AllowPID(1, svc.ID{Name: "myService"}, PIDTypeGo)
 	go ForwardRingbuf(
+		"test",
 		&TracerConfig{BatchLength: 10, BatchTimeout: 20 * time.Millisecond},
 		nil,   // the source ring buffer can be null
 		&fltr, // change fltr to a pointer
@@ -125,6 +167,7 @@ func TestForwardRingbuf_Close(t *testing.T) {
 	metrics := &metricsReporter{}
 	closable := closableObject{}
 	go ForwardRingbuf(
+		"test",
 		&TracerConfig{BatchLength: 10},
 		nil, // the source ring buffer can be null
 		(&IdentityPidsFilter{}),
@@ -151,6 +194,111 @@ func TestForwardRingbuf_Close(t *testing.T) {
 	assert.Equal(t, 0, metrics.flushedLen)
 }
 
+func TestSharedRingbuf_Heartbeat(t *testing.T) {
+	// GIVEN a shared ring buffer forwarder
+	ringBuf, restore := replaceTestRingBuf()
+	defer restore()
+	resetSharedRingbuf()
+	defer resetSharedRingbuf()
+
+	_, ok := SharedRingbufHeartbeat()
+	assert.False(t, ok)
+	assert.False(t, SharedRingbufReading())
+
+	fltr := TestPidsFilter{services: map[uint32]svc.ID{}}
+	fltr.AllowPID(1, svc.ID{Name: "myService"}, PIDTypeGo)
+	// nobody reads from the unbuffered channel until the test decides it
+	forwardedMessages := make(chan []request.Span)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go SharedRingbuf(
+		&TracerConfig{BatchLength: 10, BatchTimeout: 10 * time.Millisecond},
+		&fltr,
+		nil, // the source ring buffer can be null
+		&metricsReporter{},
+	)(ctx, forwardedMessages)
+
+	// WHEN it starts reading
+	test.Eventually(t, testTimeout, func(t require.TestingT) {
+		assert.True(t, SharedRingbufReading())
+	})
+	// THEN the heartbeat is periodically updated
+	first, ok := SharedRingbufHeartbeat()
+	require.True(t, ok)
+	test.Eventually(t, testTimeout, func(t require.TestingT) {
+		last, _ := SharedRingbufHeartbeat()
+		assert.True(t, last.After(first))
+	})
+
+	// WHEN the next pipeline stage doesn't accept more events
+	var get = [7]byte{'G', 'E', 'T', 0, 0, 0, 0}
+	trace := HTTPRequestTrace{Type: 1, Method: get}
+	trace.Pid.HostPid = 1
+	ringBuf.events <- trace
+
+	// THEN the heartbeat stops
+	var stalled time.Time
+	test.Eventually(t, testTimeout, func(t require.TestingT) {
+		stalled, _ = SharedRingbufHeartbeat()
+		time.Sleep(50 * time.Millisecond)
+		last, _ := SharedRingbufHeartbeat()
+		assert.Equal(t, stalled, last)
+	})
+
+	// AND it is resumed after the pipeline accepts events again
+	testutil.ReadChannel(t, forwardedMessages, testTimeout)
+	test.Eventually(t, testTimeout, func(t require.TestingT) {
+		last, _ := SharedRingbufHeartbeat()
+		assert.True(t, last.After(stalled))
+	})
+}
+
+func TestSharedRingbufFromFD(t *testing.T) {
+	// GIVEN a shared ring buffer forwarder that reads from a file descriptor
+	rb := fakeRingBufReader{events: make(chan HTTPRequestTrace, 100), closeCh: make(chan struct{})}
+	oldFactory := fdReaderFactory
+	var openedFD, openedSize int
+	fdReaderFactory = func(fd int, size int) (ringBufReader, error) {
+		openedFD, openedSize = fd, size
+		return &rb, nil
+	}
+	defer func() { fdReaderFactory = oldFactory }()
+	resetSharedRingbuf()
+	defer resetSharedRingbuf()
+
+	fltr := TestPidsFilter{services: map[uint32]svc.ID{}}
+	fltr.AllowPID(1, svc.ID{Name: "myService"}, PIDTypeGo)
+	forwardedMessages := make(chan []request.Span, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go SharedRingbufFromFD(
+		&TracerConfig{BatchLength: 1},
+		&fltr,
+		123, 4096,
+		&metricsReporter{},
+	)(ctx, forwardedMessages)
+
+	// WHEN it receives events
+	var get = [7]byte{'G', 'E', 'T', 0, 0, 0, 0}
+	trace := HTTPRequestTrace{Type: 1, Method: get}
+	trace.Pid.HostPid = 1
+	rb.events <- trace
+
+	// THEN it forwards them from the ring buffer of the provided file descriptor
+	batch := testutil.ReadChannel(t, forwardedMessages, testTimeout)
+	require.Len(t, batch, 1)
+	assert.Equal(t, "myService", batch[0].ServiceID.Name)
+	assert.Equal(t, 123, openedFD)
+	assert.Equal(t, 4096, openedSize)
+	assert.True(t, SharedRingbufReading())
+}
+
+func resetSharedRingbuf() {
+	singleRbfLock.Lock()
+	defer singleRbfLock.Unlock()
+	singleRbf = nil
+}
+
 // replaces the original ring buffer factory by a fake ring buffer creator and returns it,
 // along with a function to invoke deferred to restore the real ring buffer factory
 func replaceTestRingBuf() (ringBuf *fakeRingBufReader, restorer func()) {
@@ -206,8 +354,20 @@ func (c *closableObject) Close() error {
 
 type metricsReporter struct {
 	imetrics.NoopReporter
-	flushes    int
-	flushedLen int
+	flushes         int
+	flushedLen      int
+	filtered        int
+	channelCapacity int
+	channelTracer   string
+}
+
+func (m *metricsReporter) FilteredSpans(count int) {
+	m.filtered += count
+}
+
+func (m *metricsReporter) TracerChannelFill(tracer string, _, capacity int) {
+	m.channelTracer = tracer
+	m.channelCapacity = capacity
 }
 
 func (m *metricsReporter) TracerFlush(len int) {
@@ -238,3 +398,19 @@ func (pf *TestPidsFilter) Filter(inputSpans []request.Span) []request.Span {
 	}
 	return inputSpans
 }
+
+// allowedPidsFilter discards the spans whose PID has not been allowed
+type allowedPidsFilter struct {
+	TestPidsFilter
+}
+
+func (pf *allowedPidsFilter) Filter(inputSpans []request.Span) []request.Span {
+	outputSpans := inputSpans[:0]
+	for i := range inputSpans {
+		if svcID, ok := pf.services[inputSpans[i].Pid.HostPID]; ok {
+			inputSpans[i].ServiceID = svcID
+			outputSpans = append(outputSpans, inputSpans[i])
+		}
+	}
+	return outputSpans
+}
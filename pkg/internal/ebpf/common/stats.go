@@ -0,0 +1,140 @@
+package ebpfcommon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+// Indexes of the per-CPU counters in the beyla_stats eBPF map. They need to line up
+// with the STAT_* definitions in bpf/ringbuf.h
+const (
+	statRingbufReserveFailed = iota
+	statsCount
+)
+
+const statsMapName = "beyla_stats"
+
+// StatsCollector periodically reads the internal counters and the fill levels of the eBPF maps
+// that are pinned into a given path, and reports them as internal metrics
+type StatsCollector struct {
+	log     *slog.Logger
+	pinPath string
+	metrics imetrics.Reporter
+	// last value of each beyla_stats counter, to report the increments since the last read
+	last [statsCount]uint64
+}
+
+func NewStatsCollector(pinPath string, metrics imetrics.Reporter) *StatsCollector {
+	return &StatsCollector{
+		log:     slog.With("component", "ebpfCommon.StatsCollector"),
+		pinPath: pinPath,
+		metrics: metrics,
+	}
+}
+
+// Run collects the eBPF statistics every period, until the context is done
+func (sc *StatsCollector) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			sc.log.Debug("context is cancelled. Stopping")
+			return
+		case <-ticker.C:
+			sc.collect()
+		}
+	}
+}
+
+func (sc *StatsCollector) collect() {
+	pinned, err := os.ReadDir(sc.pinPath)
+	if err != nil {
+		sc.log.Debug("can't list pinned eBPF maps", "path", sc.pinPath, "error", err)
+		return
+	}
+	for _, file := range pinned {
+		if file.IsDir() {
+			continue
+		}
+		m, err := ebpf.LoadPinnedMap(path.Join(sc.pinPath, file.Name()), &ebpf.LoadPinOptions{ReadOnly: true})
+		if err != nil {
+			sc.log.Debug("can't load pinned eBPF map", "name", file.Name(), "error", err)
+			continue
+		}
+		sc.collectMap(file.Name(), m)
+		_ = m.Close()
+	}
+}
+
+func (sc *StatsCollector) collectMap(name string, m *ebpf.Map) {
+	switch {
+	case name == statsMapName:
+		for stat := uint32(0); stat < statsCount; stat++ {
+			var perCPU []uint64
+			if err := m.Lookup(stat, &perCPU); err != nil {
+				sc.log.Debug("can't read eBPF counter", "index", stat, "error", err)
+				continue
+			}
+			var total uint64
+			for _, v := range perCPU {
+				total += v
+			}
+			sc.reportCounter(stat, total)
+		}
+	case m.Type() == ebpf.Hash || m.Type() == ebpf.LRUHash:
+		entries, err := mapEntries(m)
+		if err != nil {
+			sc.log.Debug("can't count eBPF map entries", "name", name, "error", err)
+			return
+		}
+		sc.metrics.BPFMapEntries(name, entries, int(m.MaxEntries()))
+	}
+}
+
+// reportCounter reports the increment of a beyla_stats counter since its last read
+func (sc *StatsCollector) reportCounter(stat uint32, total uint64) {
+	if total < sc.last[stat] {
+		// the map has been recreated (e.g. after unpinning), so the counter starts from zero
+		sc.last[stat] = 0
+	}
+	increment := int(total - sc.last[stat])
+	sc.last[stat] = total
+	if increment == 0 {
+		return
+	}
+	switch stat {
+	case statRingbufReserveFailed:
+		sc.metrics.RingbufReserveFailures(increment)
+	}
+}
+
+// mapEntries counts the keys of a hash map. As the map can be concurrently modified (e.g. LRU evictions),
+// the result is an approximation that never goes beyond the map maximum entries.
+func mapEntries(m *ebpf.Map) (int, error) {
+	key := make([]byte, m.KeySize())
+	next := make([]byte, m.KeySize())
+	// a nil key makes NextKey to return the first key of the map
+	var prev any
+	entries := 0
+	for entries < int(m.MaxEntries()) {
+		if err := m.NextKey(prev, next); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				break
+			}
+			return entries, err
+		}
+		entries++
+		copy(key, next)
+		prev = key
+	}
+	return entries, nil
+}
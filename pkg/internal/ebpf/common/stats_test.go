@@ -0,0 +1,31 @@
+package ebpfcommon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+type statsReporter struct {
+	imetrics.NoopReporter
+	reserveFailures []int
+}
+
+func (s *statsReporter) RingbufReserveFailures(count int) {
+	s.reserveFailures = append(s.reserveFailures, count)
+}
+
+func TestStatsCollector_ReportsIncrements(t *testing.T) {
+	metrics := &statsReporter{}
+	sc := NewStatsCollector("/sys/fs/bpf/test", metrics)
+
+	sc.reportCounter(statRingbufReserveFailed, 3)
+	sc.reportCounter(statRingbufReserveFailed, 3)
+	sc.reportCounter(statRingbufReserveFailed, 10)
+	// the counter is reset if the eBPF map is recreated
+	sc.reportCounter(statRingbufReserveFailed, 2)
+
+	assert.Equal(t, []int{3, 7, 2}, metrics.reserveFailures)
+}
@@ -112,6 +112,7 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
+	BeylaStats                   *ebpf.MapSpec `ebpf:"beyla_stats"`
 	Events                       *ebpf.MapSpec `ebpf:"events"`
 	GoTraceMap                   *ebpf.MapSpec `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
@@ -144,6 +145,7 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
+	BeylaStats                   *ebpf.Map `ebpf:"beyla_stats"`
 	Events                       *ebpf.Map `ebpf:"events"`
 	GoTraceMap                   *ebpf.Map `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
@@ -159,6 +161,7 @@ type bpfMaps struct {
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.BeylaStats,
 		m.Events,
 		m.GoTraceMap,
 		m.GolangMapbucketStorageMap,
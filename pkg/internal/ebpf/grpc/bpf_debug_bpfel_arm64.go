@@ -112,6 +112,7 @@ type bpf_debugProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugMapSpecs struct {
+	BeylaStats                   *ebpf.MapSpec `ebpf:"beyla_stats"`
 	Events                       *ebpf.MapSpec `ebpf:"events"`
 	GoTraceMap                   *ebpf.MapSpec `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
@@ -144,6 +145,7 @@ func (o *bpf_debugObjects) Close() error {
 //
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugMaps struct {
+	BeylaStats                   *ebpf.Map `ebpf:"beyla_stats"`
 	Events                       *ebpf.Map `ebpf:"events"`
 	GoTraceMap                   *ebpf.Map `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
@@ -159,6 +161,7 @@ type bpf_debugMaps struct {
 
 func (m *bpf_debugMaps) Close() error {
 	return _Bpf_debugClose(
+		m.BeylaStats,
 		m.Events,
 		m.GoTraceMap,
 		m.GolangMapbucketStorageMap,
@@ -118,6 +118,7 @@ type bpf_tpProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_tpMapSpecs struct {
+	BeylaStats                   *ebpf.MapSpec `ebpf:"beyla_stats"`
 	Events                       *ebpf.MapSpec `ebpf:"events"`
 	GoTraceMap                   *ebpf.MapSpec `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
@@ -151,6 +152,7 @@ func (o *bpf_tpObjects) Close() error {
 //
 // It can be passed to loadBpf_tpObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_tpMaps struct {
+	BeylaStats                   *ebpf.Map `ebpf:"beyla_stats"`
 	Events                       *ebpf.Map `ebpf:"events"`
 	GoTraceMap                   *ebpf.Map `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap    *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
@@ -167,6 +169,7 @@ type bpf_tpMaps struct {
 
 func (m *bpf_tpMaps) Close() error {
 	return _Bpf_tpClose(
+		m.BeylaStats,
 		m.Events,
 		m.GoTraceMap,
 		m.GolangMapbucketStorageMap,
@@ -69,6 +69,7 @@ type bpf_debugHttpInfoT struct {
 	StartMonotimeNs uint64
 	EndMonotimeNs   uint64
 	Buf             [160]uint8
+	RspBuf          [160]uint8
 	Len             uint32
 	RespLen         uint32
 	Status          uint16
@@ -193,8 +194,10 @@ type bpf_debugMapSpecs struct {
 	ActiveSslHandshakes     *ebpf.MapSpec `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs       *ebpf.MapSpec `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs      *ebpf.MapSpec `ebpf:"active_ssl_write_args"`
+	BeylaStats              *ebpf.MapSpec `ebpf:"beyla_stats"`
 	CloneMap                *ebpf.MapSpec `ebpf:"clone_map"`
 	Events                  *ebpf.MapSpec `ebpf:"events"`
+	FallbackHttpInfoMem     *ebpf.MapSpec `ebpf:"fallback_http_info_mem"`
 	FilteredConnections     *ebpf.MapSpec `ebpf:"filtered_connections"`
 	Http2InfoMem            *ebpf.MapSpec `ebpf:"http2_info_mem"`
 	HttpInfoMem             *ebpf.MapSpec `ebpf:"http_info_mem"`
@@ -238,8 +241,10 @@ type bpf_debugMaps struct {
 	ActiveSslHandshakes     *ebpf.Map `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs       *ebpf.Map `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs      *ebpf.Map `ebpf:"active_ssl_write_args"`
+	BeylaStats              *ebpf.Map `ebpf:"beyla_stats"`
 	CloneMap                *ebpf.Map `ebpf:"clone_map"`
 	Events                  *ebpf.Map `ebpf:"events"`
+	FallbackHttpInfoMem     *ebpf.Map `ebpf:"fallback_http_info_mem"`
 	FilteredConnections     *ebpf.Map `ebpf:"filtered_connections"`
 	Http2InfoMem            *ebpf.Map `ebpf:"http2_info_mem"`
 	HttpInfoMem             *ebpf.Map `ebpf:"http_info_mem"`
@@ -266,8 +271,10 @@ func (m *bpf_debugMaps) Close() error {
 		m.ActiveSslHandshakes,
 		m.ActiveSslReadArgs,
 		m.ActiveSslWriteArgs,
+		m.BeylaStats,
 		m.CloneMap,
 		m.Events,
+		m.FallbackHttpInfoMem,
 		m.FilteredConnections,
 		m.Http2InfoMem,
 		m.HttpInfoMem,
@@ -4,10 +4,13 @@ package ebpf
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path"
+	"runtime"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -26,35 +29,117 @@ type instrumenter struct {
 	offsets   *goexec.Offsets
 	exe       *link.Executable
 	closables []io.Closer
+	// failedProbes records the optional probes that couldn't be attached
+	failedProbes []string
+	// state, if not nil, pins the attached probes to survive Beyla restarts
+	state *PersistentState
+	// program name and executable inode, which identify the pinned probes
+	program string
+	ino     uint64
 }
 
 func ilog() *slog.Logger {
 	return slog.With("component", "ebpf.Instrumenter")
 }
 
-func (i *instrumenter) goprobes(p Tracer) error {
-	log := ilog().With("probes", "goprobes")
-	// TODO: not running program if it does not find the required probes
-	for funcName, funcPrograms := range p.GoProbes() {
-		offs, ok := i.offsets.Funcs[funcName]
-		if !ok {
-			// the program function is not in the detected offsets. Ignoring
-			log.Debug("ignoring function", "function", funcName)
-			continue
-		}
-		log.Debug("going to instrument function", "function", funcName, "offsets", offs, "programs", funcPrograms)
-		if err := i.goprobe(ebpfcommon.Probe{
-			Offsets:  offs,
-			Programs: funcPrograms,
-		}); err != nil {
-			return fmt.Errorf("instrumenting function %q: %w", funcName, err)
+// attach the probes of a unit through the provided function, unless the persistent state is
+// enabled and a former Beyla process pinned the probes of the same unit, which are adopted
+// instead. The attached probes are pinned if the persistent state is enabled.
+// The target identifies the instrumented executable, library or kernel hooks. If empty, the
+// probes are not pinned.
+func (i *instrumenter) attach(target string, attachFunc func() error) error {
+	if i.state == nil || target == "" {
+		return attachFunc()
+	}
+	unit := unitName(target, i.program)
+	if i.state.adopt(unit) {
+		return nil
+	}
+	first := len(i.closables)
+	if err := attachFunc(); err != nil {
+		return err
+	}
+	i.pin(unit, i.closables[first:])
+	return nil
+}
+
+// pin all the links of the unit. If any of them can't be pinned, none is kept pinned.
+func (i *instrumenter) pin(unit string, links []io.Closer) {
+	if len(links) == 0 {
+		return
+	}
+	dir, err := i.state.unitDir(unit)
+	for n := 0; err == nil && n < len(links); n++ {
+		if l, ok := links[n].(link.Link); ok {
+			err = pinLink(l, path.Join(dir, fmt.Sprintf("link-%d", n)))
+		} else {
+			err = fmt.Errorf("%T is not a link", links[n])
 		}
-		p.AddCloser(i.closables...)
 	}
+	if err != nil {
+		i.state.pinFailed(unit, err)
+	}
+}
+
+// pinLink pins the link into the provided file of the BPF filesystem.
+// The cilium/ebpf library doesn't support pinning the links of perf events (kprobes, uprobes and
+// tracepoints), as it can't recover them after loading. Since the kernel keeps the perf event
+// alive as long as its link is pinned, they are pinned directly by their file descriptor.
+func pinLink(l link.Link, fileName string) error {
+	err := l.Pin(fileName)
+	if err == nil || !errors.Is(err, link.ErrNotSupported) {
+		return err
+	}
+	fdLink, ok := l.(interface{ FD() int })
+	if !ok {
+		return err
+	}
+	return objPin(fdLink.FD(), fileName)
+}
 
+// objPin invokes the BPF_OBJ_PIN command for the eBPF object with the provided file descriptor
+func objPin(fd int, fileName string) error {
+	pathname, err := unix.BytePtrFromString(fileName)
+	if err != nil {
+		return err
+	}
+	attr := struct {
+		pathname  uint64
+		bpfFD     uint32
+		fileFlags uint32
+	}{pathname: uint64(uintptr(unsafe.Pointer(pathname))), bpfFD: uint32(fd)}
+	_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_OBJ_PIN, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	runtime.KeepAlive(pathname)
+	if errno != 0 {
+		return fmt.Errorf("pinning %s: %w", fileName, errno)
+	}
 	return nil
 }
 
+func (i *instrumenter) goprobes(p Tracer) error {
+	log := ilog().With("probes", "goprobes")
+	return i.attach(executableTarget(i.ino), func() error {
+		// TODO: not running program if it does not find the required probes
+		for funcName, funcPrograms := range p.GoProbes() {
+			offs, ok := i.offsets.Funcs[funcName]
+			if !ok {
+				// the program function is not in the detected offsets. Ignoring
+				log.Debug("ignoring function", "function", funcName)
+				continue
+			}
+			log.Debug("going to instrument function", "function", funcName, "offsets", offs, "programs", funcPrograms)
+			if err := i.goprobe(ebpfcommon.Probe{
+				Offsets:  offs,
+				Programs: funcPrograms,
+			}); err != nil {
+				return fmt.Errorf("instrumenting function %q: %w", funcName, err)
+			}
+			p.AddCloser(i.closables...)
+		}
+		return nil
+	})
+}
+
 func (i *instrumenter) goprobe(probe ebpfcommon.Probe) error {
 	// Attach BPF programs as start and return probes
 	if probe.Programs.Start != nil {
@@ -86,16 +171,17 @@ func (i *instrumenter) goprobe(probe ebpfcommon.Probe) error {
 
 func (i *instrumenter) kprobes(p KprobesTracer) error {
 	log := ilog().With("probes", "kprobes")
-	for kfunc, kprobes := range p.KProbes() {
-		log.Debug("going to add kprobe to function", "function", kfunc, "probes", kprobes)
+	return i.attach(kprobesTarget, func() error {
+		for kfunc, kprobes := range p.KProbes() {
+			log.Debug("going to add kprobe to function", "function", kfunc, "probes", kprobes)
 
-		if err := i.kprobe(kfunc, kprobes); err != nil {
-			return fmt.Errorf("instrumenting function %q: %w", kfunc, err)
+			if err := i.kprobe(kfunc, kprobes); err != nil {
+				return fmt.Errorf("instrumenting function %q: %w", kfunc, err)
+			}
+			p.AddCloser(i.closables...)
 		}
-		p.AddCloser(i.closables...)
-	}
-
-	return nil
+		return nil
+	})
 }
 
 func (i *instrumenter) kprobe(funcName string, programs ebpfcommon.FunctionPrograms) error {
@@ -167,17 +253,30 @@ func (i *instrumenter) uprobes(pid int32, p Tracer) error {
 			return err
 		}
 
-		for funcName, funcPrograms := range pMap {
-			log.Debug("going to instrument function", "function", funcName, "programs", funcPrograms)
-			if err := i.uprobe(funcName, libExe, funcPrograms); err != nil {
-				if funcPrograms.Required {
-					return fmt.Errorf("instrumenting function %q: %w", funcName, err)
-				}
+		// the pinned uprobes are identified by the inode of the instrumented library or executable
+		target := ""
+		if ino != 0 {
+			target = libraryTarget(ino)
+		} else if libMap == nil {
+			target = libraryTarget(i.ino)
+		}
+		if err := i.attach(target, func() error {
+			for funcName, funcPrograms := range pMap {
+				log.Debug("going to instrument function", "function", funcName, "programs", funcPrograms)
+				if err := i.uprobe(funcName, libExe, funcPrograms); err != nil {
+					if funcPrograms.Required {
+						return fmt.Errorf("instrumenting function %q: %w", funcName, err)
+					}
 
-				// error will be common here since this could be no openssl loaded
-				log.Debug("error instrumenting uprobe", "function", funcName, "error", err)
+					// error will be common here since this could be no openssl loaded
+					log.Debug("error instrumenting uprobe", "function", funcName, "error", err)
+					i.failedProbes = append(i.failedProbes, fmt.Sprintf("%s %s: %s", lib, funcName, err))
+				}
+				p.AddCloser(i.closables...)
 			}
-			p.AddCloser(i.closables...)
+			return nil
+		}); err != nil {
+			return err
 		}
 
 		if ino != 0 {
@@ -235,16 +334,17 @@ func attachSocketFilter(filter *ebpf.Program) (int, error) {
 }
 
 func (i *instrumenter) tracepoints(p KprobesTracer) error {
-	for sfunc, sprobes := range p.Tracepoints() {
-		slog.Debug("going to add syscall", "function", sfunc, "probes", sprobes)
+	return i.attach(tracepointsTarget, func() error {
+		for sfunc, sprobes := range p.Tracepoints() {
+			slog.Debug("going to add syscall", "function", sfunc, "probes", sprobes)
 
-		if err := i.tracepoint(sfunc, sprobes); err != nil {
-			return fmt.Errorf("instrumenting function %q: %w", sfunc, err)
+			if err := i.tracepoint(sfunc, sprobes); err != nil {
+				return fmt.Errorf("instrumenting function %q: %w", sfunc, err)
+			}
+			p.AddCloser(i.closables...)
 		}
-		p.AddCloser(i.closables...)
-	}
-
-	return nil
+		return nil
+	})
 }
 
 func (i *instrumenter) tracepoint(funcName string, programs ebpfcommon.FunctionPrograms) error {
@@ -0,0 +1,173 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"debug/elf"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+//go:noinline
+func uprobeTarget(n int) int {
+	return n + 1
+}
+
+// funcOffset returns the offset of the function in the executable file
+func funcOffset(t *testing.T, fn any) uint64 {
+	f, err := elf.Open("/proc/self/exe")
+	require.NoError(t, err)
+	defer f.Close()
+	addr := uint64(reflect.ValueOf(fn).Pointer())
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 &&
+			prog.Vaddr <= addr && addr < prog.Vaddr+prog.Memsz {
+			return addr - prog.Vaddr + prog.Off
+		}
+	}
+	t.Skip("function address not found in the executable segments. Is it a position independent executable?")
+	return 0
+}
+
+// counterProbe is an uprobe program that counts the invocations of uprobeTarget
+type counterProbe struct {
+	counter *ebpf.Map
+	prog    *ebpf.Program
+	exe     *link.Executable
+	offset  uint64
+	// bpfFS is a temporary BPF file system where the links can be pinned
+	bpfFS string
+}
+
+func newCounterProbe(t *testing.T) *counterProbe {
+	counter, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Array, KeySize: 4, ValueSize: 8, MaxEntries: 1})
+	if err != nil {
+		t.Skipf("can't create a map. This test requires eBPF privileges: %v", err)
+	}
+	t.Cleanup(func() { _ = counter.Close() })
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:    ebpf.Kprobe,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+			asm.LoadMapPtr(asm.R1, counter.FD()),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, -4),
+			asm.FnMapLookupElem.Call(),
+			asm.JEq.Imm(asm.R0, 0, "exit"),
+			asm.Mov.Imm(asm.R1, 1),
+			asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+			asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+			asm.Return(),
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = prog.Close() })
+
+	bpfFS := t.TempDir()
+	if err := unix.Mount(bpfFS, bpfFS, "bpf", 0, ""); err != nil {
+		t.Skipf("can't mount the BPF file system: %v", err)
+	}
+	t.Cleanup(func() { _ = unix.Unmount(bpfFS, 0) })
+
+	exe, err := link.OpenExecutable("/proc/self/exe")
+	require.NoError(t, err)
+	cp := &counterProbe{counter: counter, prog: prog, exe: exe, bpfFS: bpfFS,
+		// the test binary is stripped, so the probe is attached to the function address, as the Go probes
+		offset: funcOffset(t, uprobeTarget)}
+	l, err := cp.attach()
+	if err != nil {
+		t.Skipf("can't attach uprobe: %v", err)
+	}
+	_ = l.Close()
+	return cp
+}
+
+func (cp *counterProbe) attach() (link.Link, error) {
+	return cp.exe.Uprobe("", cp.prog, &link.UprobeOptions{Address: cp.offset})
+}
+
+// hits invokes uprobeTarget and returns the increment of the counter
+func (cp *counterProbe) hits(t *testing.T) uint64 {
+	var before, after uint64
+	require.NoError(t, cp.counter.Lookup(uint32(0), &before))
+	uprobeTarget(1)
+	require.NoError(t, cp.counter.Lookup(uint32(0), &after))
+	return after - before
+}
+
+func TestPinLink(t *testing.T) {
+	cp := newCounterProbe(t)
+	l, err := cp.attach()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, cp.hits(t))
+
+	// the probe keeps attached after closing the link, as it is pinned
+	pinned := path.Join(cp.bpfFS, "link-0")
+	require.NoError(t, pinLink(l, pinned))
+	require.NoError(t, l.Close())
+	assert.EqualValues(t, 1, cp.hits(t))
+
+	// unpinning the link detaches the probe
+	require.NoError(t, os.Remove(pinned))
+	assert.Eventually(t, func() bool {
+		return cp.hits(t) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestInstrumenter_AdoptPinnedProbes(t *testing.T) {
+	cp := newCounterProbe(t)
+	attachFunc := func(i *instrumenter) func() error {
+		return func() error {
+			l, err := cp.attach()
+			if err != nil {
+				return err
+			}
+			i.closables = append(i.closables, l)
+			return nil
+		}
+	}
+	closeAll := func(i *instrumenter) {
+		for _, c := range i.closables {
+			require.NoError(t, c.Close())
+		}
+	}
+
+	// the first Beyla process attaches and pins the probe
+	state, err := OpenPersistentState(cp.bpfFS, "v1")
+	require.NoError(t, err)
+	first := &instrumenter{state: state, program: "test.Tracer"}
+	require.NoError(t, first.attach(executableTarget(1), attachFunc(first)))
+	assert.FileExists(t, path.Join(cp.bpfFS, "links/exe-1/test_Tracer/link-0"))
+	closeAll(first)
+	require.NoError(t, state.Close())
+	require.EqualValues(t, 1, cp.hits(t))
+
+	// the restarted Beyla adopts the probe instead of attaching it again
+	state, err = OpenPersistentState(cp.bpfFS, "v1")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = state.Close() })
+	second := &instrumenter{state: state, program: "test.Tracer"}
+	require.NoError(t, second.attach(executableTarget(1), func() error {
+		return errors.New("the probe shouldn't be attached again")
+	}))
+	assert.Empty(t, second.closables)
+	assert.EqualValues(t, 1, cp.hits(t))
+
+	// the probe is detached when the executable isn't instrumented anymore
+	state.RemoveExecutable(1)
+	assert.Eventually(t, func() bool {
+		return cp.hits(t) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
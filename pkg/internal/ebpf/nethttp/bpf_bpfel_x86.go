@@ -30,6 +30,7 @@ type bpfHttpClientDataT struct {
 	Host          [64]uint8
 	_             [5]byte
 	ContentLength int64
+	ReqPtr        uint64
 	Pid           struct {
 		HostPid uint32
 		UserPid uint32
@@ -128,6 +129,7 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
+	BeylaStats                    *ebpf.MapSpec `ebpf:"beyla_stats"`
 	Events                        *ebpf.MapSpec `ebpf:"events"`
 	GoTraceMap                    *ebpf.MapSpec `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap     *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
@@ -159,6 +161,7 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
+	BeylaStats                    *ebpf.Map `ebpf:"beyla_stats"`
 	Events                        *ebpf.Map `ebpf:"events"`
 	GoTraceMap                    *ebpf.Map `ebpf:"go_trace_map"`
 	GolangMapbucketStorageMap     *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
@@ -173,6 +176,7 @@ type bpfMaps struct {
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.BeylaStats,
 		m.Events,
 		m.GoTraceMap,
 		m.GolangMapbucketStorageMap,
@@ -36,6 +36,7 @@ type bpf_tp_debugHttpClientDataT struct {
 	Host          [64]uint8
 	_             [5]byte
 	ContentLength int64
+	ReqPtr        uint64
 	Pid           struct {
 		HostPid uint32
 		UserPid uint32
@@ -134,6 +135,7 @@ type bpf_tp_debugProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_tp_debugMapSpecs struct {
+	BeylaStats                    *ebpf.MapSpec `ebpf:"beyla_stats"`
 	Events                        *ebpf.MapSpec `ebpf:"events"`
 	FramerInvocationMap           *ebpf.MapSpec `ebpf:"framer_invocation_map"`
 	GoTraceMap                    *ebpf.MapSpec `ebpf:"go_trace_map"`
@@ -168,6 +170,7 @@ func (o *bpf_tp_debugObjects) Close() error {
 //
 // It can be passed to loadBpf_tp_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_tp_debugMaps struct {
+	BeylaStats                    *ebpf.Map `ebpf:"beyla_stats"`
 	Events                        *ebpf.Map `ebpf:"events"`
 	FramerInvocationMap           *ebpf.Map `ebpf:"framer_invocation_map"`
 	GoTraceMap                    *ebpf.Map `ebpf:"go_trace_map"`
@@ -185,6 +188,7 @@ type bpf_tp_debugMaps struct {
 
 func (m *bpf_tp_debugMaps) Close() error {
 	return _Bpf_tp_debugClose(
+		m.BeylaStats,
 		m.Events,
 		m.FramerInvocationMap,
 		m.GoTraceMap,
@@ -120,6 +120,8 @@ func (p *Tracer) Constants(_ *exec.FileInfo, offsets *goexec.Offsets) map[string
 		"rws_req_pos",
 		"cc_next_stream_id_pos",
 		"framer_w_pos",
+		"resp_handler_header_ptr_pos",
+		"resp_header_ptr_pos",
 	} {
 		constants[s] = offsets.Field[s]
 		if constants[s] == nil {
@@ -127,6 +129,12 @@ func (p *Tracer) Constants(_ *exec.FileInfo, offsets *goexec.Offsets) map[string
 		}
 	}
 
+	// HTTP headers to capture
+	constants["request_header_names"], constants["request_header_lens"] =
+		ebpfcommon.HeaderNamesConstant(p.cfg.HTTPHeaders.RequestNames())
+	constants["response_header_names"], constants["response_header_lens"] =
+		ebpfcommon.HeaderNamesConstant(p.cfg.HTTPHeaders.ResponseNames())
+
 	return constants
 }
 
@@ -0,0 +1,339 @@
+package ebpf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// linksDir contains the pinned links of the current Beyla process
+	linksDir = "links"
+	// previousLinksDir contains the pinned links of the former Beyla process that haven't been
+	// adopted yet by the current Beyla process
+	previousLinksDir = "links_previous"
+	// fingerprintPrefix prefixes the name of the empty directory that identifies the Beyla version
+	// and configuration that pinned the eBPF state
+	fingerprintPrefix = "fingerprint-"
+	// kprobesTarget contains the units of the kprobes, which are attached to the whole host
+	kprobesTarget = "kprobes"
+	// tracepointsTarget contains the units of the tracepoints, which are attached to the whole host
+	tracepointsTarget = "tracepoints"
+)
+
+// PersistentState of the eBPF programs, which survives Beyla restarts.
+// The links of the probes are pinned into units, which group the links that share the same eBPF
+// program instance (e.g. the Go probes of a program for a given executable). As the probes of a
+// unit share the private maps of their program, a restarted Beyla either adopts all the pinned
+// links of a unit, or removes them and attaches the probes again.
+// Removing a unit directory unpins its links, which detaches the probes when no process holds them.
+type PersistentState struct {
+	log      *slog.Logger
+	mux      sync.Mutex
+	dir      string
+	previous string
+	// lock of the pin path, which is held while the state is open
+	lock *os.File
+	// unsupported is true once pinning a link has failed, to avoid logging the same warning repeatedly
+	unsupported bool
+}
+
+// OpenPersistentState of the eBPF programs in the provided pin path. If the state was pinned by a
+// Beyla process with a different fingerprint (e.g. another version or eBPF configuration), the whole
+// content of the pin path is removed, as the pinned maps and programs might be incompatible.
+// The links that were pinned by the former Beyla process are kept apart until they are adopted or
+// removed by RemoveStale.
+// The pin path is exclusively locked until the state is closed, and opening it fails if it is
+// locked by another Beyla process, whose pinned state must not be removed while it is running.
+func OpenPersistentState(pinPath, fingerprint string) (*PersistentState, error) {
+	lock, err := lockPinPath(pinPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &PersistentState{
+		log:      slog.With("component", "ebpf.PersistentState"),
+		dir:      path.Join(pinPath, linksDir),
+		previous: path.Join(pinPath, previousLinksDir),
+		lock:     lock,
+	}
+	if err := s.open(pinPath, fingerprint); err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PersistentState) open(pinPath, fingerprint string) error {
+	marker := fingerprintPrefix + fingerprint
+	if _, err := os.Stat(path.Join(pinPath, marker)); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("accessing state fingerprint: %w", err)
+		}
+		if err := s.clear(pinPath); err != nil {
+			return err
+		}
+		if err := os.Mkdir(path.Join(pinPath, marker), 0700); err != nil {
+			return fmt.Errorf("creating state fingerprint: %w", err)
+		}
+	}
+	return s.rotate()
+}
+
+// lockPinPath takes an exclusive lock of the pin path. The directory itself is locked, as the
+// BPF file system doesn't allow creating regular files such as a lock file.
+// The lock is released when the returned file is closed, or when the Beyla process exits.
+func lockPinPath(pinPath string) (*os.File, error) {
+	dir, err := os.Open(pinPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening pin path: %w", err)
+	}
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		_ = dir.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, fmt.Errorf("the eBPF state pinned in %s is in use by another Beyla process."+
+				" Beyla instances sharing the same bpf_fs_base_dir can't run at the same time", pinPath)
+		}
+		return nil, fmt.Errorf("locking pin path: %w", err)
+	}
+	return dir, nil
+}
+
+// Close releases the lock of the pin path. The pinned state is kept for the next Beyla process.
+func (s *PersistentState) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.lock.Close()
+}
+
+// StateFingerprint identifies the eBPF maps and programs of the provided tracers, as well as the
+// provided configuration, which might change the constants that are rewritten at load time.
+// Upgrading Beyla keeps the pinned state unless the upgrade changes the eBPF programs or maps.
+func StateFingerprint(tracers []Tracer, config []byte) (string, error) {
+	h := sha256.New()
+	h.Write(config)
+	for _, t := range tracers {
+		spec, err := t.Load()
+		if err != nil {
+			return "", fmt.Errorf("loading eBPF spec: %w", err)
+		}
+		for _, name := range sortedKeys(spec.Maps) {
+			m := spec.Maps[name]
+			fmt.Fprintf(h, "map %s %s %d %d %d %d %d\n",
+				name, m.Type, m.KeySize, m.ValueSize, m.MaxEntries, m.Flags, m.Pinning)
+		}
+		for _, name := range sortedKeys(spec.Programs) {
+			p := spec.Programs[name]
+			fmt.Fprintf(h, "program %s %s %s %s\n%v\n",
+				name, p.Type, p.AttachType, p.SectionName, p.Instructions)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// clear removes any pinned map and link from the pin path
+func (s *PersistentState) clear(pinPath string) error {
+	entries, err := os.ReadDir(pinPath)
+	if err != nil {
+		return fmt.Errorf("listing pinned state: %w", err)
+	}
+	// the root of a BPF file system might contain iterators that are preloaded by the kernel
+	entries = slices.DeleteFunc(entries, func(entry os.DirEntry) bool {
+		return entry.Name() == "maps.debug" || entry.Name() == "progs.debug"
+	})
+	if len(entries) > 0 {
+		s.log.Info("removing the eBPF state pinned by a different Beyla version or configuration",
+			"path", pinPath)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(path.Join(pinPath, entry.Name())); err != nil {
+			return fmt.Errorf("removing pinned state: %w", err)
+		}
+	}
+	return nil
+}
+
+// rotate moves the units of the former Beyla process to the previous links dir. If a former Beyla
+// process didn't remove its own previous links, they are merged, keeping the newest ones.
+func (s *PersistentState) rotate() error {
+	units, err := listUnits(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, unit := range units {
+		dst := path.Join(s.previous, unit)
+		if err := os.RemoveAll(dst); err != nil {
+			return fmt.Errorf("removing former unit %s: %w", unit, err)
+		}
+		if err := os.MkdirAll(path.Dir(dst), 0700); err != nil {
+			return fmt.Errorf("creating previous links directory: %w", err)
+		}
+		if err := os.Rename(path.Join(s.dir, unit), dst); err != nil {
+			return fmt.Errorf("moving unit %s: %w", unit, err)
+		}
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("creating links directory: %w", err)
+	}
+	if len(units) > 0 {
+		s.log.Info("found eBPF probes pinned by a former Beyla process", "units", len(units))
+	}
+	return nil
+}
+
+// listUnits returns the units in the provided links directory, whose paths have two levels:
+// the instrumented target and the program name (e.g. exe-1234/nethttp_Tracer)
+func listUnits(dir string) ([]string, error) {
+	targets, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing pinned links: %w", err)
+	}
+	var units []string
+	for _, target := range targets {
+		if !target.IsDir() {
+			continue
+		}
+		programs, err := os.ReadDir(path.Join(dir, target.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("listing pinned links: %w", err)
+		}
+		for _, program := range programs {
+			if program.IsDir() {
+				units = append(units, path.Join(target.Name(), program.Name()))
+			}
+		}
+	}
+	return units, nil
+}
+
+// adopt the links of the unit that were pinned by the former Beyla process. It returns false if
+// there were no pinned links for that unit.
+func (s *PersistentState) adopt(unit string) bool {
+	if s == nil {
+		return false
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	src := path.Join(s.previous, unit)
+	if _, err := os.Stat(src); err != nil {
+		return false
+	}
+	dst := path.Join(s.dir, unit)
+	err := os.MkdirAll(path.Dir(dst), 0700)
+	if err == nil {
+		err = os.Rename(src, dst)
+	}
+	if err != nil {
+		s.log.Warn("can't adopt pinned eBPF probes. Attaching them again", "unit", unit, "error", err)
+		_ = os.RemoveAll(src)
+		return false
+	}
+	s.log.Info("adopted eBPF probes pinned by a former Beyla process", "unit", unit)
+	return true
+}
+
+// unitDir returns an empty directory where the links of the unit can be pinned
+func (s *PersistentState) unitDir(unit string) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	dir := path.Join(s.dir, unit)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// pinFailed removes the links that were pinned in a unit whose pinning failed, so its probes
+// will be attached again after a restart instead of adopting an incomplete unit
+func (s *PersistentState) pinFailed(unit string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_ = os.RemoveAll(path.Join(s.dir, unit))
+	if s.unsupported {
+		s.log.Debug("can't pin eBPF probes", "unit", unit, "error", err)
+		return
+	}
+	s.unsupported = true
+	s.log.Warn("can't pin eBPF probes. They will be attached again if Beyla restarts",
+		"unit", unit, "error", err)
+}
+
+// RemoveExecutable unpins the links of the probes that are attached to the executable with the
+// provided inode, so they are detached once the executable is not instrumented anymore.
+// It includes the uprobes that are attached to the executable itself instead of a shared library,
+// which are pinned as a library target.
+func (s *PersistentState) RemoveExecutable(ino uint64) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, target := range []string{executableTarget(ino), libraryTarget(ino)} {
+		if err := os.RemoveAll(path.Join(s.dir, target)); err != nil {
+			s.log.Warn("can't unpin eBPF probes of executable", "ino", ino, "error", err)
+		}
+	}
+}
+
+// RemoveStale unpins the links of the former Beyla process that haven't been adopted yet,
+// e.g. because their instrumented processes have finished.
+func (s *PersistentState) RemoveStale() {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	units, err := listUnits(s.previous)
+	if err != nil {
+		s.log.Warn("can't list stale eBPF probes", "error", err)
+	}
+	if len(units) > 0 {
+		s.log.Info("removing eBPF probes that haven't been adopted", "units", strings.Join(units, ","))
+	}
+	if err := os.RemoveAll(s.previous); err != nil {
+		s.log.Warn("can't unpin stale eBPF probes", "error", err)
+	}
+}
+
+// executableTarget is the name of the directory containing the units of the Go probes that are
+// attached to the executable with the provided inode
+func executableTarget(ino uint64) string {
+	return fmt.Sprintf("exe-%d", ino)
+}
+
+// unitName returns the name of the unit of the program probes in the provided target. As the BPF
+// file system doesn't accept dots in the file names, they are replaced in the program name.
+func unitName(target, program string) string {
+	return path.Join(target, strings.ReplaceAll(program, ".", "_"))
+}
+
+// libraryTarget is the name of the directory containing the units of the uprobes that are attached
+// to the library (or executable) with the provided inode
+func libraryTarget(ino uint64) string {
+	return fmt.Sprintf("lib-%d", ino)
+}
@@ -0,0 +1,177 @@
+package ebpf
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the pinned links are simulated with regular files, as the persistent state only manages
+// the directories where they are pinned
+
+func pinFakeLink(t *testing.T, dir, unit string) {
+	require.NoError(t, os.MkdirAll(path.Join(dir, unit), 0700))
+	require.NoError(t, os.WriteFile(path.Join(dir, unit, "link-0"), nil, 0600))
+}
+
+func TestPersistentState(t *testing.T) {
+	pinPath := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(pinPath, "events"), nil, 0600))
+
+	// a new state removes anything that wasn't pinned with the same fingerprint
+	state, err := OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path.Join(pinPath, "events"))
+	assert.DirExists(t, path.Join(pinPath, "fingerprint-v1"))
+	assert.False(t, state.adopt("exe-1/nethttp_Tracer"))
+
+	// the first Beyla process pins some units
+	for _, unit := range []string{"exe-1/nethttp_Tracer", "exe-2/grpc_Tracer", "kprobes/httpfltr_Tracer"} {
+		dir, err := state.unitDir(unit)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path.Join(dir, "link-0"), nil, 0600))
+	}
+	require.NoError(t, os.WriteFile(path.Join(pinPath, "events"), nil, 0600))
+	require.NoError(t, state.Close())
+
+	// a restarted Beyla with the same fingerprint keeps the maps and adopts the pinned units
+	state, err = OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	assert.FileExists(t, path.Join(pinPath, "events"))
+	assert.True(t, state.adopt("exe-1/nethttp_Tracer"))
+	assert.True(t, state.adopt("kprobes/httpfltr_Tracer"))
+	assert.False(t, state.adopt("exe-3/nethttp_Tracer"))
+	assert.FileExists(t, path.Join(pinPath, "links", "exe-1/nethttp_Tracer", "link-0"))
+	assert.FileExists(t, path.Join(pinPath, "links", "kprobes/httpfltr_Tracer", "link-0"))
+
+	// the units that weren't adopted are removed after some time
+	assert.FileExists(t, path.Join(pinPath, "links_previous", "exe-2/grpc_Tracer", "link-0"))
+	state.RemoveStale()
+	assert.NoDirExists(t, path.Join(pinPath, "links_previous"))
+	assert.False(t, state.adopt("exe-2/grpc_Tracer"))
+
+	// the units of an executable are removed when it isn't instrumented anymore, including
+	// the uprobes that are attached to the executable itself
+	dir, err := state.unitDir("lib-1/httpssl_Tracer")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(dir, "link-0"), nil, 0600))
+	state.RemoveExecutable(1)
+	assert.NoDirExists(t, path.Join(pinPath, "links", "exe-1"))
+	assert.NoDirExists(t, path.Join(pinPath, "links", "lib-1"))
+	assert.DirExists(t, path.Join(pinPath, "links", "kprobes/httpfltr_Tracer"))
+	require.NoError(t, state.Close())
+
+	// a Beyla process with another fingerprint removes the whole former state
+	state, err = OpenPersistentState(pinPath, "v2")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path.Join(pinPath, "events"))
+	assert.NoDirExists(t, path.Join(pinPath, "fingerprint-v1"))
+	assert.False(t, state.adopt("kprobes/httpfltr_Tracer"))
+}
+
+func TestPersistentState_MergePrevious(t *testing.T) {
+	pinPath := t.TempDir()
+	state, err := OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	require.NoError(t, state.Close())
+
+	// a former Beyla process exited before removing the stale units of its own former process
+	pinFakeLink(t, path.Join(pinPath, "links_previous"), "exe-1/nethttp_Tracer")
+	pinFakeLink(t, path.Join(pinPath, "links_previous"), "lib-2/httpssl_Tracer")
+	pinFakeLink(t, path.Join(pinPath, "links"), "lib-2/httpssl_Tracer")
+	require.NoError(t, os.WriteFile(path.Join(pinPath, "links", "lib-2/httpssl_Tracer", "link-1"), nil, 0600))
+
+	state, err = OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	// the newest units replace the oldest ones
+	assert.True(t, state.adopt("lib-2/httpssl_Tracer"))
+	assert.FileExists(t, path.Join(pinPath, "links", "lib-2/httpssl_Tracer", "link-1"))
+	assert.True(t, state.adopt("exe-1/nethttp_Tracer"))
+}
+
+func TestPersistentState_Locked(t *testing.T) {
+	pinPath := t.TempDir()
+	state, err := OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	dir, err := state.unitDir("exe-1/nethttp_Tracer")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(dir, "link-0"), nil, 0600))
+
+	// another Beyla process can't remove nor rotate the state while it is in use
+	_, err = OpenPersistentState(pinPath, "v2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "in use by another Beyla process")
+	_, err = OpenPersistentState(pinPath, "v1")
+	require.Error(t, err)
+	assert.DirExists(t, path.Join(pinPath, "fingerprint-v1"))
+	assert.FileExists(t, path.Join(pinPath, "links", "exe-1/nethttp_Tracer", "link-0"))
+
+	// the state can be opened once it is released
+	require.NoError(t, state.Close())
+	state, err = OpenPersistentState(pinPath, "v1")
+	require.NoError(t, err)
+	assert.True(t, state.adopt("exe-1/nethttp_Tracer"))
+}
+
+func TestPersistentState_PinFailed(t *testing.T) {
+	state, err := OpenPersistentState(t.TempDir(), "v1")
+	require.NoError(t, err)
+	dir, err := state.unitDir("exe-1/nethttp_Tracer")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(dir, "link-0"), nil, 0600))
+
+	// incomplete units are not kept
+	state.pinFailed("exe-1/nethttp_Tracer", assert.AnError)
+	assert.NoDirExists(t, dir)
+}
+
+func TestPersistentState_Disabled(t *testing.T) {
+	var state *PersistentState
+	assert.False(t, state.adopt("exe-1/nethttp_Tracer"))
+	state.RemoveExecutable(1)
+	state.RemoveStale()
+	assert.NoError(t, state.Close())
+}
+
+// specTracer is a Tracer that only provides its collection spec
+type specTracer struct {
+	Tracer
+	spec *ebpf.CollectionSpec
+}
+
+func (st *specTracer) Load() (*ebpf.CollectionSpec, error) {
+	return st.spec, nil
+}
+
+func tracerWithSpec(retCode int32) Tracer {
+	return &specTracer{spec: &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"events": {Name: "events", Type: ebpf.RingBuf, MaxEntries: 4096},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			"uprobe_ServeHTTP": {Name: "uprobe_ServeHTTP", Type: ebpf.Kprobe, Instructions: asm.Instructions{
+				asm.Mov.Imm(asm.R0, retCode),
+				asm.Return(),
+			}},
+		},
+	}}
+}
+
+func TestStateFingerprint(t *testing.T) {
+	fingerprint := func(cfg string, tracers ...Tracer) string {
+		fp, err := StateFingerprint(tracers, []byte(cfg))
+		require.NoError(t, err)
+		return fp
+	}
+	base := fingerprint("cfg", tracerWithSpec(0))
+	// the fingerprint only depends on the eBPF specs and configuration, not on the Beyla version
+	assert.Equal(t, base, fingerprint("cfg", tracerWithSpec(0)))
+	assert.NotEqual(t, base, fingerprint("cfg", tracerWithSpec(1)))
+	assert.NotEqual(t, base, fingerprint("other", tracerWithSpec(0)))
+	assert.NotEqual(t, base, fingerprint("cfg", tracerWithSpec(0), tracerWithSpec(0)))
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"slices"
+	"sync"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -94,9 +96,42 @@ type ProcessTracer struct {
 	Goffsets *goexec.Offsets
 	Exe      *link.Executable
 	PinPath  string
+	// State, if not nil, pins the attached probes so they are adopted by a restarted Beyla
+	State *PersistentState
 
 	SystemWide bool
 	Type       ProcessTracerType
+
+	statusMux sync.RWMutex
+	status    TracerStatus
+}
+
+// TracerStatus reports the state of the eBPF programs of a ProcessTracer
+type TracerStatus struct {
+	// Running is true once the eBPF programs have been loaded and attached
+	Running bool `json:"running"`
+	// Programs that are attached to the instrumented executable (e.g. httpfltr.Tracer)
+	Programs []string `json:"programs,omitempty"`
+	// FailedProbes lists the optional probes that couldn't be attached, and the failure cause
+	FailedProbes []string `json:"failed_probes,omitempty"`
+	// Error that prevented the ProcessTracer from running, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Status returns a copy of the current state of the ProcessTracer eBPF programs
+func (pt *ProcessTracer) Status() TracerStatus {
+	pt.statusMux.RLock()
+	defer pt.statusMux.RUnlock()
+	st := pt.status
+	st.Programs = slices.Clone(st.Programs)
+	st.FailedProbes = slices.Clone(st.FailedProbes)
+	return st
+}
+
+func (pt *ProcessTracer) setStatus(st TracerStatus) {
+	pt.statusMux.Lock()
+	pt.status = st
+	pt.statusMux.Unlock()
 }
 
 func (pt *ProcessTracer) AllowPID(pid uint32, svc svc.ID) {
@@ -25,11 +25,17 @@ func (pt *ProcessTracer) Run(ctx context.Context, out chan<- []request.Span) {
 
 	pt.log.Debug("starting process tracer")
 	// Searches for traceable functions
-	trcrs, err := pt.tracers()
+	trcrs, failedProbes, err := pt.tracers()
 	if err != nil {
+		pt.setStatus(TracerStatus{FailedProbes: failedProbes, Error: err.Error()})
 		pt.log.Error("couldn't trace process. Stopping process tracer", "error", err)
 		return
 	}
+	programs := make([]string, 0, len(trcrs))
+	for _, t := range trcrs {
+		programs = append(programs, programName(t))
+	}
+	pt.setStatus(TracerStatus{Running: true, Programs: programs, FailedProbes: failedProbes})
 
 	for _, t := range trcrs {
 		go t.Run(ctx, out)
@@ -51,21 +57,28 @@ func (pt *ProcessTracer) loadSpec(p Tracer) (*ebpf.CollectionSpec, error) {
 	return spec, nil
 }
 
+// programName returns the type name of the Tracer, without the pointer mark (e.g. httpfltr.Tracer)
+func programName(p Tracer) string {
+	return strings.TrimPrefix(reflect.TypeOf(p).String(), "*")
+}
+
 // tracers returns Tracer implementer for each discovered eBPF traceable source: GRPC, HTTP...
-func (pt *ProcessTracer) tracers() ([]Tracer, error) {
+// It also returns the optional probes that couldn't be attached.
+func (pt *ProcessTracer) tracers() ([]Tracer, []string, error) {
 	loadMux.Lock()
 	defer loadMux.Unlock()
 	var log = ptlog()
 
 	// tracerFuncs contains the eBPF Programs (HTTP, GRPC tracers...)
 	var tracers []Tracer
+	var failedProbes []string
 
 	for _, p := range pt.Programs {
 		plog := log.With("program", reflect.TypeOf(p))
 		plog.Debug("loading eBPF program", "PinPath", pt.PinPath, "pid", pt.ELFInfo.Pid, "cmd", pt.ELFInfo.CmdExePath)
 		spec, err := pt.loadSpec(p)
 		if err != nil {
-			return nil, err
+			return nil, failedProbes, err
 		}
 		if err := spec.LoadAndAssign(p.BpfObjects(), &ebpf.CollectionOptions{
 			Maps: ebpf.MapOptions{
@@ -87,48 +100,55 @@ func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 			}
 			if err != nil {
 				printVerifierErrorInfo(err)
-				return nil, fmt.Errorf("loading and assigning BPF objects: %w", err)
+				return nil, failedProbes, fmt.Errorf("loading and assigning BPF objects: %w", err)
 			}
 		}
 		i := instrumenter{
 			exe:     pt.Exe,
 			offsets: pt.Goffsets,
+			state:   pt.State,
+			program: programName(p),
+			ino:     pt.ELFInfo.Ino,
 		}
 
 		//Go style Uprobes
 		if err := i.goprobes(p); err != nil {
 			printVerifierErrorInfo(err)
-			return nil, err
+			return nil, failedProbes, err
 		}
 
 		//Kprobes to be used for native instrumentation points
 		if err := i.kprobes(p); err != nil {
 			printVerifierErrorInfo(err)
-			return nil, err
+			return nil, failedProbes, err
 		}
 
 		//Uprobes to be used for native module instrumentation points
-		if err := i.uprobes(pt.ELFInfo.Pid, p); err != nil {
+		err = i.uprobes(pt.ELFInfo.Pid, p)
+		for _, failed := range i.failedProbes {
+			failedProbes = append(failedProbes, programName(p)+": "+failed)
+		}
+		if err != nil {
 			printVerifierErrorInfo(err)
-			return nil, err
+			return nil, failedProbes, err
 		}
 
 		//Tracepoints support
 		if err := i.tracepoints(p); err != nil {
 			printVerifierErrorInfo(err)
-			return nil, err
+			return nil, failedProbes, err
 		}
 
 		//Sock filters support
 		if err := i.sockfilters(p); err != nil {
 			printVerifierErrorInfo(err)
-			return nil, err
+			return nil, failedProbes, err
 		}
 
 		tracers = append(tracers, p)
 	}
 
-	return tracers, nil
+	return tracers, failedProbes, nil
 }
 
 func printVerifierErrorInfo(err error) {
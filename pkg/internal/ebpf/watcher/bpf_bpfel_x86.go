@@ -58,7 +58,9 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
-	KprobeSysBind *ebpf.ProgramSpec `ebpf:"kprobe_sys_bind"`
+	KprobeSysBind              *ebpf.ProgramSpec `ebpf:"kprobe_sys_bind"`
+	TracepointSchedProcessExec *ebpf.ProgramSpec `ebpf:"tracepoint_sched_process_exec"`
+	TracepointSchedProcessExit *ebpf.ProgramSpec `ebpf:"tracepoint_sched_process_exit"`
 }
 
 // bpfMapSpecs contains maps before they are loaded into the kernel.
@@ -100,12 +102,16 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
-	KprobeSysBind *ebpf.Program `ebpf:"kprobe_sys_bind"`
+	KprobeSysBind              *ebpf.Program `ebpf:"kprobe_sys_bind"`
+	TracepointSchedProcessExec *ebpf.Program `ebpf:"tracepoint_sched_process_exec"`
+	TracepointSchedProcessExit *ebpf.Program `ebpf:"tracepoint_sched_process_exit"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
 		p.KprobeSysBind,
+		p.TracepointSchedProcessExec,
+		p.TracepointSchedProcessExit,
 	)
 }
 
@@ -58,7 +58,9 @@ type bpf_debugSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugProgramSpecs struct {
-	KprobeSysBind *ebpf.ProgramSpec `ebpf:"kprobe_sys_bind"`
+	KprobeSysBind              *ebpf.ProgramSpec `ebpf:"kprobe_sys_bind"`
+	TracepointSchedProcessExec *ebpf.ProgramSpec `ebpf:"tracepoint_sched_process_exec"`
+	TracepointSchedProcessExit *ebpf.ProgramSpec `ebpf:"tracepoint_sched_process_exit"`
 }
 
 // bpf_debugMapSpecs contains maps before they are loaded into the kernel.
@@ -100,12 +102,16 @@ func (m *bpf_debugMaps) Close() error {
 //
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugPrograms struct {
-	KprobeSysBind *ebpf.Program `ebpf:"kprobe_sys_bind"`
+	KprobeSysBind              *ebpf.Program `ebpf:"kprobe_sys_bind"`
+	TracepointSchedProcessExec *ebpf.Program `ebpf:"tracepoint_sched_process_exec"`
+	TracepointSchedProcessExit *ebpf.Program `ebpf:"tracepoint_sched_process_exit"`
 }
 
 func (p *bpf_debugPrograms) Close() error {
 	return _Bpf_debugClose(
 		p.KprobeSysBind,
+		p.TracepointSchedProcessExec,
+		p.TracepointSchedProcessExit,
 	)
 }
 
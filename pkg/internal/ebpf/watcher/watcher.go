@@ -33,6 +33,17 @@ type EventType int
 const (
 	Ready = EventType(iota)
 	NewPort
+	// NewProcess is notified when a process executes a new program
+	NewProcess
+	// ProcessExit is notified when a process finishes
+	ProcessExit
+)
+
+// flags of the watch_info_t events, as defined in watch_helper.c
+const (
+	watchBind = 0x1
+	watchExec = 0x2
+	watchExit = 0x3
 )
 
 type Event struct {
@@ -78,12 +89,22 @@ func (p *Watcher) KProbes() map[string]ebpfcommon.FunctionPrograms {
 }
 
 func (p *Watcher) Tracepoints() map[string]ebpfcommon.FunctionPrograms {
-	return nil
+	return map[string]ebpfcommon.FunctionPrograms{
+		"sched/sched_process_exec": {
+			Required: true,
+			Start:    p.bpfObjects.TracepointSchedProcessExec,
+		},
+		"sched/sched_process_exit": {
+			Required: true,
+			Start:    p.bpfObjects.TracepointSchedProcessExit,
+		},
+	}
 }
 
 func (p *Watcher) Run(ctx context.Context) {
 	p.events <- Event{Type: Ready}
 	ebpfcommon.ForwardRingbuf(
+		"watcher",
 		&p.cfg.EBPF,
 		p.bpfObjects.WatchEvents,
 		&ebpfcommon.IdentityPidsFilter{},
@@ -103,13 +124,24 @@ func (p *Watcher) processWatchEvent(record *ringbuf.Record) (request.Span, bool,
 		return request.Span{}, true, err
 	}
 
-	if flags == 1 { // socket bind
+	switch flags {
+	case watchBind:
 		err = binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event)
 
 		if err == nil {
 			p.log.Debug("New port bind event", "port", event.Payload)
 			p.events <- Event{Type: NewPort, Payload: uint32(event.Payload)}
 		}
+	case watchExec, watchExit:
+		err = binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event)
+
+		if err == nil {
+			eventType := NewProcess
+			if flags == watchExit {
+				eventType = ProcessExit
+			}
+			p.events <- Event{Type: eventType, Payload: uint32(event.Payload)}
+		}
 	}
 
 	return request.Span{}, true, nil
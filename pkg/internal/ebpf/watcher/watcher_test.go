@@ -0,0 +1,52 @@
+package watcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+func TestWatcher_Load(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		cfg := beyla.DefaultConfig
+		cfg.EBPF.BpfDebug = debug
+		w := New(&cfg, nil)
+		spec, err := w.Load()
+		require.NoError(t, err)
+
+		// the compiled objects must provide all the programs and maps of the generated bindings
+		var specs bpfSpecs
+		require.NoError(t, spec.Assign(&specs), "debug: %v", debug)
+		assert.Equal(t, "kprobe/sys_bind", specs.KprobeSysBind.SectionName)
+		assert.Equal(t, "tracepoint/sched/sched_process_exec", specs.TracepointSchedProcessExec.SectionName)
+		assert.Equal(t, "tracepoint/sched/sched_process_exit", specs.TracepointSchedProcessExit.SectionName)
+	}
+}
+
+func TestWatcher_LoadPrograms(t *testing.T) {
+	if err := rlimit.RemoveMemlock(); err != nil {
+		t.Skipf("can't remove the memlock limit. This test requires eBPF privileges: %v", err)
+	}
+	cfg := beyla.DefaultConfig
+	w := New(&cfg, nil)
+	spec, err := w.Load()
+	require.NoError(t, err)
+	if err := spec.LoadAndAssign(w.BpfObjects(), nil); err != nil {
+		var verr *ebpf.VerifierError
+		if errors.As(err, &verr) {
+			t.Fatalf("the kernel rejected the programs: %+v", verr)
+		}
+		t.Skipf("can't load the eBPF programs. This test requires eBPF privileges: %v", err)
+	}
+	defer w.bpfObjects.Close()
+
+	assert.NotNil(t, w.KProbes()["sys_bind"].Start)
+	assert.NotNil(t, w.Tracepoints()["sched/sched_process_exec"].Start)
+	assert.NotNil(t, w.Tracepoints()["sched/sched_process_exit"].Start)
+}
@@ -0,0 +1,184 @@
+package envcheck
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+)
+
+// Injectable for tests
+var (
+	kernelVersion   = ebpfcommon.KernelVersion
+	lockdownMode    = ebpfcommon.KernelLockdownMode
+	propagation     = ebpfcommon.SupportsContextPropagation
+	loadKernelBTF   = func() error { _, err := btf.LoadKernelSpec(); return err }
+	procStatusPath  = "/proc/self/status"
+	filesystemsPath = "/proc/filesystems"
+)
+
+// Linux capabilities, as defined in linux/capability.h. They are defined here instead of
+// taken from the unix package, as they are not defined for all the operating systems.
+const (
+	capNetAdmin    = 12
+	capSysPtrace   = 19
+	capSysAdmin    = 21
+	capSysResource = 24
+	capPerfmon     = 38
+	capBPF         = 39
+)
+
+func kernelCheck() Result {
+	major, minor := kernelVersion()
+	if err := beyla.CheckKernelVersion(major, minor); err != nil {
+		return Result{Name: checkKernel, Status: StatusFailed, Details: err.Error()}
+	}
+	return Result{Name: checkKernel, Status: StatusOK, Details: fmt.Sprintf("%d.%d", major, minor)}
+}
+
+func btfCheck() Result {
+	if err := loadKernelBTF(); err != nil {
+		return Result{Name: checkBTF, Status: StatusFailed,
+			Details: "kernel BTF information is not available: " + err.Error()}
+	}
+	return Result{Name: checkBTF, Status: StatusOK, Details: "available"}
+}
+
+func lockdownCheck() Result {
+	var mode string
+	switch lockdownMode() {
+	case ebpfcommon.KernelLockdownNone:
+		return Result{Name: checkLockdown, Status: StatusOK, Details: "none"}
+	case ebpfcommon.KernelLockdownIntegrity:
+		mode = "integrity"
+	case ebpfcommon.KernelLockdownConfidentiality:
+		mode = "confidentiality"
+	default:
+		mode = "unknown mode"
+	}
+	if propagation(slog.With("component", "envcheck.lockdownCheck")) {
+		return Result{Name: checkLockdown, Status: StatusOK, Details: mode}
+	}
+	return Result{Name: checkLockdown, Status: StatusWarning,
+		Details: mode + ": trace context propagation is disabled"}
+}
+
+func capabilitiesChecks() []Result {
+	caps, err := effectiveCapabilities()
+	if err != nil {
+		details := "can't verify: " + err.Error()
+		return []Result{
+			{Name: checkCapsBPF, Status: StatusWarning, Details: details},
+			{Name: checkCapsPtrace, Status: StatusWarning, Details: details},
+			{Name: checkCapsMemlock, Status: StatusWarning, Details: details},
+			{Name: checkCapsNet, Status: StatusWarning, Details: details},
+		}
+	}
+	has := func(capability uint) bool {
+		return caps&(1<<capability) != 0
+	}
+	results := make([]Result, 0, 4)
+
+	switch {
+	case has(capSysAdmin):
+		results = append(results, Result{Name: checkCapsBPF, Status: StatusOK, Details: "CAP_SYS_ADMIN"})
+	case has(capBPF) && has(capPerfmon):
+		results = append(results, Result{Name: checkCapsBPF, Status: StatusOK, Details: "CAP_BPF, CAP_PERFMON"})
+	default:
+		results = append(results, Result{Name: checkCapsBPF, Status: StatusFailed,
+			Details: "missing CAP_SYS_ADMIN, or CAP_BPF and CAP_PERFMON"})
+	}
+
+	results = append(results, requireCapability(checkCapsPtrace, has(capSysPtrace), "CAP_SYS_PTRACE"))
+
+	// since kernel 5.11, the memory of the eBPF maps is not accounted in the locked memory limit
+	if major, minor := kernelVersion(); major > 5 || (major == 5 && minor >= 11) {
+		results = append(results, Result{Name: checkCapsMemlock, Status: StatusOK,
+			Details: "CAP_SYS_RESOURCE is not required for kernels >= 5.11"})
+	} else {
+		results = append(results, requireCapability(checkCapsMemlock, has(capSysResource), "CAP_SYS_RESOURCE"))
+	}
+
+	results = append(results, requireCapability(checkCapsNet, has(capNetAdmin), "CAP_NET_ADMIN"))
+	return results
+}
+
+func requireCapability(name string, present bool, capability string) Result {
+	if present {
+		return Result{Name: name, Status: StatusOK, Details: capability}
+	}
+	return Result{Name: name, Status: StatusFailed, Details: "missing " + capability}
+}
+
+// effectiveCapabilities returns the bitmask of the effective capabilities of the current process
+func effectiveCapabilities() (uint64, error) {
+	status, err := os.Open(procStatusPath)
+	if err != nil {
+		return 0, err
+	}
+	defer status.Close()
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		if hex, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			return strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("CapEff not found in %s", procStatusPath)
+}
+
+// bpffsCheck verifies that Beyla would be able to mount the BPF filesystem where it pins its maps
+func bpffsCheck(baseDir string) Result {
+	supported, err := supportsFilesystem("bpf")
+	if err != nil {
+		return Result{Name: checkBPFFS, Status: StatusWarning, Details: "can't verify: " + err.Error()}
+	}
+	if !supported {
+		return Result{Name: checkBPFFS, Status: StatusFailed,
+			Details: "the kernel doesn't support the bpf filesystem"}
+	}
+	// the base directory is created if it doesn't exist, so we check the first existing parent
+	dir := baseDir
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) || dir == path.Dir(dir) {
+			return Result{Name: checkBPFFS, Status: StatusFailed, Details: fmt.Sprintf("can't access %s: %s", dir, err)}
+		}
+		dir = path.Dir(dir)
+	}
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		return Result{Name: checkBPFFS, Status: StatusFailed,
+			Details: fmt.Sprintf("%s is not writable: %s", dir, err)}
+	}
+	return Result{Name: checkBPFFS, Status: StatusOK, Details: "can be mounted in " + baseDir}
+}
+
+func supportsFilesystem(fsType string) (bool, error) {
+	filesystems, err := os.Open(filesystemsPath)
+	if err != nil {
+		return false, err
+	}
+	defer filesystems.Close()
+	// each line has the format: [nodev]\t<type>
+	scanner := bufio.NewScanner(filesystems)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[len(fields)-1] == fsType {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
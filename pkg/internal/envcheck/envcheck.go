@@ -0,0 +1,178 @@
+// Package envcheck inspects the host environment to report which Beyla features are supported.
+// It doesn't load any eBPF program, so it can be run before deploying Beyla into a node.
+package envcheck
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+// Status of a check or a feature
+type Status int
+
+const (
+	StatusOK Status = iota
+	// StatusWarning means that the check couldn't be verified, or that it limits some functionality
+	StatusWarning
+	StatusFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "OK"
+	case StatusWarning:
+		return "WARN"
+	case StatusFailed:
+		return "FAIL"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// Result of a check, or of the aggregated checks that are required by a feature
+type Result struct {
+	Name    string
+	Status  Status
+	Details string
+}
+
+// FeatureResult is the aggregated result of the checks that are required by a feature
+type FeatureResult struct {
+	Result
+	// Required is false if Beyla can work without the feature
+	Required bool
+}
+
+// Report of an environment check
+type Report struct {
+	Checks   []Result
+	Features []FeatureResult
+}
+
+// Failed returns whether any required feature won't work
+func (r *Report) Failed() bool {
+	for i := range r.Features {
+		if r.Features[i].Required && r.Features[i].Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// identifiers of the different checks, to be referenced from the features
+const (
+	checkKernel      = "kernel version"
+	checkBTF         = "BTF"
+	checkLockdown    = "kernel lockdown"
+	checkCapsBPF     = "capabilities: eBPF"
+	checkCapsPtrace  = "capabilities: process inspection"
+	checkCapsMemlock = "capabilities: locked memory"
+	checkCapsNet     = "capabilities: network"
+	checkBPFFS       = "bpffs"
+)
+
+type feature struct {
+	name string
+	// checks whose failure makes the feature not to work
+	requires []string
+	// kprobes and tracepoints that are required by the feature
+	probes func(*beyla.Config) []probe
+	// required returns whether Beyla can't work without the feature, given the provided configuration
+	required func(*beyla.Config) bool
+}
+
+// checks that are required to instrument any application
+var appChecks = []string{checkKernel, checkBTF, checkCapsBPF, checkCapsPtrace, checkCapsMemlock, checkBPFFS}
+
+var features = []feature{{
+	name:     "Go services instrumentation",
+	requires: appChecks,
+	required: appO11yRequired,
+}, {
+	name:     "Non-Go services instrumentation",
+	requires: appChecks,
+	probes:   httpProbes,
+	required: appO11yRequired,
+}, {
+	// if the process discovery can't listen for eBPF events, it polls the running processes
+	name:     "Process discovery by eBPF events",
+	requires: appChecks,
+	probes:   watcherProbes,
+}, {
+	name:     "Trace context propagation",
+	requires: append(slices.Clone(appChecks), checkLockdown),
+}, {
+	name:     "Network metrics",
+	requires: []string{checkKernel, checkBTF, checkCapsBPF, checkCapsMemlock, checkCapsNet},
+	required: func(cfg *beyla.Config) bool { return cfg.Enabled(beyla.FeatureNetO11y) },
+}}
+
+// the application observability is considered required unless the configuration
+// explicitly enables only the network observability
+func appO11yRequired(cfg *beyla.Config) bool {
+	return cfg.Enabled(beyla.FeatureAppO11y) || !cfg.Enabled(beyla.FeatureNetO11y)
+}
+
+// Run all the checks and report which features are supported by the environment
+func Run(cfg *beyla.Config) *Report {
+	report := &Report{}
+	checks := map[string]Result{}
+	add := func(r Result) {
+		if _, ok := checks[r.Name]; ok {
+			return
+		}
+		checks[r.Name] = r
+		report.Checks = append(report.Checks, r)
+	}
+
+	add(kernelCheck())
+	add(btfCheck())
+	add(lockdownCheck())
+	for _, r := range capabilitiesChecks() {
+		add(r)
+	}
+	add(bpffsCheck(cfg.EBPF.BpfBaseDir))
+	for _, r := range probeChecks(newTracingFS(), append(watcherProbes(cfg), httpProbes(cfg)...)) {
+		add(r)
+	}
+
+	for _, f := range features {
+		requires := f.requires
+		if f.probes != nil {
+			requires = append(slices.Clone(requires), checkNames(f.probes(cfg))...)
+		}
+		report.Features = append(report.Features, FeatureResult{
+			Result:   aggregate(f.name, requires, checks),
+			Required: f.required != nil && f.required(cfg),
+		})
+	}
+	return report
+}
+
+// aggregate the results of the checks that are required by a feature. The status of the
+// feature is the worst status of its checks.
+func aggregate(name string, requires []string, checks map[string]Result) Result {
+	result := Result{Name: name, Status: StatusOK}
+	for _, req := range requires {
+		check, ok := checks[req]
+		if !ok || check.Status == StatusOK {
+			continue
+		}
+		if check.Status > result.Status {
+			result.Status = check.Status
+		}
+		if result.Details != "" {
+			result.Details += "; "
+		}
+		result.Details += check.Name
+	}
+	switch result.Status {
+	case StatusFailed:
+		result.Details = "won't work. Failed: " + result.Details
+	case StatusWarning:
+		result.Details = "might not work, or work partially. See: " + result.Details
+	}
+	return result
+}
@@ -0,0 +1,162 @@
+package envcheck
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+)
+
+func TestKernelAndLockdownChecks(t *testing.T) {
+	defer restoreAfterTest()()
+	kernelVersion = func() (int, int) { return 5, 4 }
+	lockdownMode = func() ebpfcommon.KernelLockdown { return ebpfcommon.KernelLockdownIntegrity }
+	propagation = func(*slog.Logger) bool { return true }
+	assert.Equal(t, StatusFailed, kernelCheck().Status)
+	// lockdown doesn't matter if the kernel supports the context propagation anyway
+	assert.Equal(t, Result{Name: checkLockdown, Status: StatusOK, Details: "integrity"}, lockdownCheck())
+
+	kernelVersion = func() (int, int) { return 6, 1 }
+	propagation = func(*slog.Logger) bool { return false }
+	assert.Equal(t, Result{Name: checkKernel, Status: StatusOK, Details: "6.1"}, kernelCheck())
+	assert.Equal(t, StatusWarning, lockdownCheck().Status)
+	lockdownMode = func() ebpfcommon.KernelLockdown { return ebpfcommon.KernelLockdownNone }
+	assert.Equal(t, StatusOK, lockdownCheck().Status)
+}
+
+func TestCapabilitiesChecks(t *testing.T) {
+	defer restoreAfterTest()()
+	kernelVersion = func() (int, int) { return 5, 10 }
+
+	// CAP_BPF, CAP_PERFMON and CAP_SYS_PTRACE
+	procStatusPath = writeFile(t, "status", "Name:\tbeyla\nCapEff:\t000000c000080000\n")
+	assert.Equal(t, []Result{
+		{Name: checkCapsBPF, Status: StatusOK, Details: "CAP_BPF, CAP_PERFMON"},
+		{Name: checkCapsPtrace, Status: StatusOK, Details: "CAP_SYS_PTRACE"},
+		{Name: checkCapsMemlock, Status: StatusFailed, Details: "missing CAP_SYS_RESOURCE"},
+		{Name: checkCapsNet, Status: StatusFailed, Details: "missing CAP_NET_ADMIN"},
+	}, capabilitiesChecks())
+
+	// CAP_SYS_ADMIN only, in a kernel that doesn't require CAP_SYS_RESOURCE
+	kernelVersion = func() (int, int) { return 5, 11 }
+	procStatusPath = writeFile(t, "status", "CapEff:\t0000000000200000\n")
+	results := capabilitiesChecks()
+	require.Len(t, results, 4)
+	assert.Equal(t, Result{Name: checkCapsBPF, Status: StatusOK, Details: "CAP_SYS_ADMIN"}, results[0])
+	assert.Equal(t, StatusFailed, results[1].Status)
+	assert.Equal(t, StatusOK, results[2].Status)
+	assert.Equal(t, StatusFailed, results[3].Status)
+
+	// capabilities can't be read
+	procStatusPath = path.Join(t.TempDir(), "not-found")
+	for _, r := range capabilitiesChecks() {
+		assert.Equal(t, StatusWarning, r.Status)
+	}
+}
+
+func TestBPFFSCheck(t *testing.T) {
+	defer restoreAfterTest()()
+	filesystemsPath = writeFile(t, "filesystems", "nodev\tsysfs\nnodev\tbpf\n\text4\n")
+	baseDir := path.Join(t.TempDir(), "var", "run", "beyla")
+	assert.Equal(t, StatusOK, bpffsCheck(baseDir).Status)
+
+	filesystemsPath = writeFile(t, "filesystems", "nodev\tsysfs\n\text4\n")
+	assert.Equal(t, StatusFailed, bpffsCheck(baseDir).Status)
+}
+
+func TestProbeChecks(t *testing.T) {
+	defer restoreAfterTest()()
+	tracefs := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tracefs, "events", "sched", "sched_process_exec"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tracefs, "events", "sched", "sched_process_exec", "id"), []byte("1"), 0o600))
+	tracefsPaths = []string{path.Join(t.TempDir(), "not-mounted"), tracefs}
+	// tracefs doesn't provide the list of functions, so the kernel symbols are read
+	kallsymsPath = writeFile(t, "kallsyms", "0000000000000000 T "+syscallPrefix()+"sys_bind\n0000000000000000 T tcp_connect\n")
+
+	results := probeChecks(newTracingFS(), []probe{
+		{kind: probeKprobe, name: "sys_bind"},
+		{kind: probeKprobe, name: "tcp_connect"},
+		{kind: probeKprobe, name: "tcp_sendmsg"},
+		{kind: probeTracepoint, name: "sched/sched_process_exec"},
+		{kind: probeTracepoint, name: "sched/sched_process_exit"},
+	})
+	assert.Equal(t, []Result{
+		{Name: "kprobe sys_bind", Status: StatusOK, Details: syscallPrefix() + "sys_bind"},
+		{Name: "kprobe tcp_connect", Status: StatusOK, Details: "tcp_connect"},
+		{Name: "kprobe tcp_sendmsg", Status: StatusFailed, Details: "kernel function not found"},
+		{Name: "tracepoint sched/sched_process_exec", Status: StatusOK, Details: path.Join(tracefs, "events", "sched", "sched_process_exec")},
+		{Name: "tracepoint sched/sched_process_exit", Status: StatusFailed, Details: "tracepoint not found"},
+	}, results)
+
+	// neither tracefs nor the kernel symbols are available
+	tracefsPaths = []string{path.Join(t.TempDir(), "not-mounted")}
+	kallsymsPath = path.Join(t.TempDir(), "not-found")
+	results = probeChecks(newTracingFS(), []probe{
+		{kind: probeKprobe, name: "sys_bind"},
+		{kind: probeTracepoint, name: "sched/sched_process_exec"},
+	})
+	require.Len(t, results, 2)
+	assert.Equal(t, StatusWarning, results[0].Status)
+	assert.Equal(t, Result{Name: "tracepoint sched/sched_process_exec", Status: StatusFailed, Details: "tracefs is not mounted"}, results[1])
+}
+
+func TestRun(t *testing.T) {
+	defer restoreAfterTest()()
+	kernelVersion = func() (int, int) { return 6, 1 }
+	lockdownMode = func() ebpfcommon.KernelLockdown { return ebpfcommon.KernelLockdownConfidentiality }
+	propagation = func(*slog.Logger) bool { return false }
+	loadKernelBTF = func() error { return nil }
+	// all the capabilities but CAP_NET_ADMIN
+	procStatusPath = writeFile(t, "status", "CapEff:\t000001ffffffefff\n")
+	filesystemsPath = writeFile(t, "filesystems", "nodev\tbpf\n")
+	// no tracepoints nor kernel functions are available
+	tracefsPaths = []string{t.TempDir()}
+	kallsymsPath = writeFile(t, "kallsyms", "0000000000000000 T foo\n")
+
+	cfg := beyla.DefaultConfig
+	cfg.EBPF.BpfBaseDir = t.TempDir()
+	report := Run(&cfg)
+
+	features := map[string]FeatureResult{}
+	for _, f := range report.Features {
+		features[f.Name] = f
+	}
+	assert.Equal(t, StatusOK, features["Go services instrumentation"].Status)
+	assert.True(t, features["Go services instrumentation"].Required)
+	assert.Equal(t, StatusFailed, features["Non-Go services instrumentation"].Status)
+	assert.Contains(t, features["Non-Go services instrumentation"].Details, "kprobe tcp_connect")
+	assert.Equal(t, StatusFailed, features["Process discovery by eBPF events"].Status)
+	assert.False(t, features["Process discovery by eBPF events"].Required)
+	assert.Equal(t, StatusWarning, features["Trace context propagation"].Status)
+	assert.Equal(t, StatusFailed, features["Network metrics"].Status)
+	assert.False(t, features["Network metrics"].Required)
+	assert.True(t, report.Failed())
+
+	// the BTF information is required by all the features
+	loadKernelBTF = func() error { return errors.New("not found") }
+	for _, f := range Run(&cfg).Features {
+		assert.Equal(t, StatusFailed, f.Status, f.Name)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	file := path.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func restoreAfterTest() func() {
+	kv, lm, pr, btf := kernelVersion, lockdownMode, propagation, loadKernelBTF
+	ps, fs, tp, ks := procStatusPath, filesystemsPath, tracefsPaths, kallsymsPath
+	return func() {
+		kernelVersion, lockdownMode, propagation, loadKernelBTF = kv, lm, pr, btf
+		procStatusPath, filesystemsPath, tracefsPaths, kallsymsPath = ps, fs, tp, ks
+	}
+}
@@ -0,0 +1,188 @@
+package envcheck
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/ebpf/httpfltr"
+	"github.com/grafana/beyla/pkg/internal/ebpf/watcher"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+// Injectable for tests
+var (
+	tracefsPaths = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+	kallsymsPath = "/proc/kallsyms"
+)
+
+const (
+	probeKprobe     = "kprobe"
+	probeTracepoint = "tracepoint"
+)
+
+type probe struct {
+	kind string
+	name string
+}
+
+func (p probe) checkName() string {
+	return p.kind + " " + p.name
+}
+
+// watcherProbes returns the probes that are used by the process discovery
+func watcherProbes(cfg *beyla.Config) []probe {
+	w := watcher.New(cfg, nil)
+	return probesOf(w.KProbes(), w.Tracepoints())
+}
+
+// httpProbes returns the probes that are used by the kprobes-based HTTP tracer
+func httpProbes(cfg *beyla.Config) []probe {
+	t := httpfltr.New(cfg, imetrics.NoopReporter{})
+	return probesOf(t.KProbes(), t.Tracepoints())
+}
+
+func probesOf(kprobes, tracepoints map[string]ebpfcommon.FunctionPrograms) []probe {
+	probes := make([]probe, 0, len(kprobes)+len(tracepoints))
+	for name := range kprobes {
+		probes = append(probes, probe{kind: probeKprobe, name: name})
+	}
+	for name := range tracepoints {
+		probes = append(probes, probe{kind: probeTracepoint, name: name})
+	}
+	slices.SortFunc(probes, func(a, b probe) int {
+		if a.kind != b.kind {
+			return cmp.Compare(a.kind, b.kind)
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+	return probes
+}
+
+func checkNames(probes []probe) []string {
+	names := make([]string, 0, len(probes))
+	for _, p := range probes {
+		names = append(names, p.checkName())
+	}
+	return names
+}
+
+// tracingFS provides the information about the kernel functions and the tracepoints that
+// can be instrumented
+type tracingFS struct {
+	// tracefs mount path. Empty if it is not mounted
+	path string
+	// kernel functions that can be instrumented by kprobes
+	functions map[string]struct{}
+	// functionsErr is not nil if the available kernel functions can't be read
+	functionsErr error
+}
+
+func newTracingFS() *tracingFS {
+	tfs := &tracingFS{}
+	for _, p := range tracefsPaths {
+		// if the tracefs is mounted but we don't have permissions, the tracepoints check will report it
+		if _, err := os.Stat(path.Join(p, "events")); err == nil || errors.Is(err, os.ErrPermission) {
+			tfs.path = p
+			break
+		}
+	}
+	// available_filter_functions lists the functions that can be instrumented. If it can't be
+	// read (it requires privileges), we fall back to the list of kernel symbols.
+	if tfs.path != "" {
+		tfs.functions, tfs.functionsErr = readSymbols(path.Join(tfs.path, "available_filter_functions"), 0)
+	}
+	if tfs.path == "" || tfs.functionsErr != nil {
+		// each line has the format: <address> <type> <symbol> [module]
+		tfs.functions, tfs.functionsErr = readSymbols(kallsymsPath, 2)
+	}
+	return tfs
+}
+
+// readSymbols reads the symbol that is in the provided whitespace-separated field of each line of a file
+func readSymbols(file string, field int) (map[string]struct{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	symbols := map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > field {
+			symbols[fields[field]] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("%s is empty", file)
+	}
+	return symbols, nil
+}
+
+func probeChecks(tfs *tracingFS, probes []probe) []Result {
+	results := make([]Result, 0, len(probes))
+	for _, p := range probes {
+		var r Result
+		switch p.kind {
+		case probeKprobe:
+			r = tfs.kprobeCheck(p.name)
+		case probeTracepoint:
+			r = tfs.tracepointCheck(p.name)
+		}
+		r.Name = p.checkName()
+		results = append(results, r)
+	}
+	return results
+}
+
+func (tfs *tracingFS) kprobeCheck(function string) Result {
+	if tfs.functionsErr != nil {
+		return Result{Status: StatusWarning, Details: "can't verify: " + tfs.functionsErr.Error()}
+	}
+	// syscalls can be defined with an architecture-specific prefix
+	candidates := []string{function}
+	if strings.HasPrefix(function, "sys_") {
+		candidates = append(candidates, syscallPrefix()+function)
+	}
+	for _, c := range candidates {
+		if _, ok := tfs.functions[c]; ok {
+			return Result{Status: StatusOK, Details: c}
+		}
+	}
+	return Result{Status: StatusFailed, Details: "kernel function not found"}
+}
+
+func (tfs *tracingFS) tracepointCheck(tracepoint string) Result {
+	if tfs.path == "" {
+		return Result{Status: StatusFailed, Details: "tracefs is not mounted"}
+	}
+	if _, err := os.Stat(path.Join(tfs.path, "events", tracepoint, "id")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Result{Status: StatusFailed, Details: "tracepoint not found"}
+		}
+		return Result{Status: StatusWarning, Details: "can't verify: " + err.Error()}
+	}
+	return Result{Status: StatusOK, Details: path.Join(tfs.path, "events", tracepoint)}
+}
+
+func syscallPrefix() string {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "__arm64_"
+	case "s390x":
+		return "__s390x_"
+	default:
+		return "__x64_"
+	}
+}
@@ -16,9 +16,29 @@ func (p PrintEnabled) Enabled() bool {
 	return bool(p)
 }
 
-func PrinterNode(_ PrintEnabled) (node.TerminalFunc[[]request.Span], error) {
+// PrinterConfig of the node that prints the traces into the standard output
+type PrinterConfig struct {
+	Print PrintEnabled
+	// Toggle, if not nil, allows enabling and disabling the printing at runtime. Its initial
+	// value is taken from Print, and the node is instantiated even if Print is false.
+	Toggle *Toggle
+}
+
+func (p PrinterConfig) Enabled() bool {
+	return p.Print.Enabled() || p.Toggle != nil
+}
+
+func PrinterNode(cfg PrinterConfig) (node.TerminalFunc[[]request.Span], error) {
+	toggle := cfg.Toggle
+	if toggle == nil {
+		toggle = &Toggle{}
+	}
+	toggle.Set(cfg.Print.Enabled())
 	return func(input <-chan []request.Span) {
 		for spans := range input {
+			if !toggle.On() {
+				continue
+			}
 			for i := range spans {
 				t := spans[i].Timings()
 				fmt.Printf("%s (%s[%s]) %s %v %s %s [%s]->[%s:%d] size:%dB svc=[%s %s] traceparent=[%s]\n",
@@ -0,0 +1,23 @@
+package debug
+
+import "sync/atomic"
+
+// Printers that can be enabled and disabled at runtime from the admin HTTP API. They only take
+// effect if the printer nodes have been instantiated with them.
+var (
+	TracesPrinter = &Toggle{}
+	FlowsPrinter  = &Toggle{}
+)
+
+// Toggle is an on/off switch that can be changed at runtime. It is safe for concurrent use.
+type Toggle struct {
+	on atomic.Bool
+}
+
+func (t *Toggle) On() bool {
+	return t.on.Load()
+}
+
+func (t *Toggle) Set(on bool) {
+	t.on.Store(on)
+}
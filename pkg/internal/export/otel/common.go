@@ -5,7 +5,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"slices"
+	"sort"
 
 	"github.com/go-logr/logr"
 	"github.com/hashicorp/golang-lru/v2/simplelru"
@@ -19,9 +24,41 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
+// EndpointAddresses returns the host:port addresses of the enabled OTEL metrics and traces
+// endpoints, without duplicates, to check whether they are reachable before anything is exported.
+func EndpointAddresses(metrics *MetricsConfig, traces *TracesConfig) []string {
+	var endpoints []*url.URL
+	if metrics.EndpointEnabled() {
+		if endpoint, _, err := parseMetricsEndpoint(metrics); err == nil {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if traces.Enabled() {
+		if endpoint, _, err := parseTracesEndpoint(traces); err == nil {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	var addrs []string
+	for _, endpoint := range endpoints {
+		port := endpoint.Port()
+		if port == "" {
+			port = "80"
+			if endpoint.Scheme == "https" {
+				port = "443"
+			}
+		}
+		addr := net.JoinHostPort(endpoint.Hostname(), port)
+		if !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
 // Protocol values for the OTEL_EXPORTER_OTLP_PROTOCOL, OTEL_EXPORTER_OTLP_TRACES_PROTOCOL and
 // OTEL_EXPORTER_OTLP_METRICS_PROTOCOL standard configuration values
 // More info: https://opentelemetry.io/docs/concepts/sdk-configuration/otlp-exporter-configuration/
@@ -115,6 +152,28 @@ func (rp *ReporterPool[T]) For(service svc.ID) (T, error) {
 	return m, nil
 }
 
+// ExporterProfile overrides the endpoint and headers of an OTEL exporter for the services whose
+// discovery criteria reference it by name.
+type ExporterProfile struct {
+	// Endpoint of the exporter for the services of this profile. If empty, the endpoint
+	// of the exporter is used.
+	Endpoint string `yaml:"endpoint" secret:"userinfo"`
+	// Headers that are added to the exported requests (e.g. a tenant ID)
+	Headers map[string]string `yaml:"headers" secret:"true"`
+}
+
+// exportHeaders returns the headers that are added to the requests of the profile exporter.
+// If the profile overrides the endpoint, the returned headers are never nil, so they replace
+// the headers that the OTEL SDK reads from the OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_EXPORTER_OTLP_<SIGNAL>_HEADERS variables, as they might contain credentials that must not
+// be sent to other endpoints.
+func (p *ExporterProfile) exportHeaders() map[string]string {
+	if p.Endpoint != "" && p.Headers == nil {
+		return map[string]string{}
+	}
+	return p.Headers
+}
+
 // Intermediate representation of option functions suitable for testing
 type otlpOptions struct {
 	Endpoint      string
@@ -124,6 +183,18 @@ type otlpOptions struct {
 	HTTPHeaders   map[string]string
 }
 
+// addHeaders to the exported requests. In gRPC exporters, they are sent as request metadata.
+// Non-nil headers replace the headers that are defined in the OTEL_EXPORTER_OTLP_* variables.
+func (o *otlpOptions) addHeaders(headers map[string]string) {
+	if headers == nil {
+		return
+	}
+	if o.HTTPHeaders == nil {
+		o.HTTPHeaders = make(map[string]string, len(headers))
+	}
+	maps.Copy(o.HTTPHeaders, headers)
+}
+
 func (o *otlpOptions) AsMetricHTTP() []otlpmetrichttp.Option {
 	opts := []otlpmetrichttp.Option{
 		otlpmetrichttp.WithEndpoint(o.Endpoint),
@@ -137,7 +208,7 @@ func (o *otlpOptions) AsMetricHTTP() []otlpmetrichttp.Option {
 	if o.SkipTLSVerify {
 		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(&tls.Config{InsecureSkipVerify: true}))
 	}
-	if len(o.HTTPHeaders) > 0 {
+	if o.HTTPHeaders != nil {
 		opts = append(opts, otlpmetrichttp.WithHeaders(o.HTTPHeaders))
 	}
 	return opts
@@ -153,6 +224,9 @@ func (o *otlpOptions) AsMetricGRPC() []otlpmetricgrpc.Option {
 	if o.SkipTLSVerify {
 		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})))
 	}
+	if o.HTTPHeaders != nil {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(o.HTTPHeaders))
+	}
 	return opts
 }
 
@@ -169,7 +243,7 @@ func (o *otlpOptions) AsTraceHTTP() []otlptracehttp.Option {
 	if o.SkipTLSVerify {
 		opts = append(opts, otlptracehttp.WithTLSClientConfig(&tls.Config{InsecureSkipVerify: true}))
 	}
-	if len(o.HTTPHeaders) > 0 {
+	if o.HTTPHeaders != nil {
 		opts = append(opts, otlptracehttp.WithHeaders(o.HTTPHeaders))
 	}
 	return opts
@@ -185,6 +259,9 @@ func (o *otlpOptions) AsTraceGRPC() []otlptracegrpc.Option {
 	if o.SkipTLSVerify {
 		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})))
 	}
+	if o.HTTPHeaders != nil {
+		opts = append(opts, otlptracegrpc.WithHeaders(o.HTTPHeaders))
+	}
 	return opts
 }
 
@@ -256,6 +333,11 @@ const (
 	ServerPortKey             = attribute.Key("server.port")
 	HTTPRequestBodySizeKey    = attribute.Key("http.request.body.size")
 	HTTPResponseBodySizeKey   = attribute.Key("http.response.body.size")
+
+	// HTTPRequestHeaderPrefix is followed by the lowercase name of the HTTP request header
+	HTTPRequestHeaderPrefix = "http.request.header."
+	// HTTPResponseHeaderPrefix is followed by the lowercase name of the HTTP response header
+	HTTPResponseHeaderPrefix = "http.response.header."
 )
 
 func HTTPRequestMethod(val string) attribute.KeyValue {
@@ -297,3 +379,44 @@ func HTTPRequestBodySize(val int) attribute.KeyValue {
 func HTTPResponseBodySize(val int) attribute.KeyValue {
 	return HTTPResponseBodySizeKey.Int(val)
 }
+
+func HTTPRequestHeader(name, val string) attribute.KeyValue {
+	return attribute.StringSlice(HTTPRequestHeaderPrefix+name, []string{val})
+}
+
+func HTTPResponseHeader(name, val string) attribute.KeyValue {
+	return attribute.StringSlice(HTTPResponseHeaderPrefix+name, []string{val})
+}
+
+// httpHeaders returns the attributes of the captured HTTP request and response headers,
+// sorted by name
+func httpHeaders(span *request.Span) []attribute.KeyValue {
+	attrs := appendHeaders(nil, span.RequestHeaders, HTTPRequestHeader)
+	return appendHeaders(attrs, span.ResponseHeaders, HTTPResponseHeader)
+}
+
+func appendHeaders(
+	attrs []attribute.KeyValue, headers map[string]string, attr func(name, val string) attribute.KeyValue,
+) []attribute.KeyValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attrs = append(attrs, attr(name, headers[name]))
+	}
+	return attrs
+}
+
+// appendMetricHeaders appends the captured HTTP headers that are reported as metric attributes.
+// Missing headers are reported as an empty value, so all the metrics have the same attributes.
+func appendMetricHeaders(attrs []attribute.KeyValue, headers *request.MetricHeaders, span *request.Span) []attribute.KeyValue {
+	for _, name := range headers.Request() {
+		attrs = append(attrs, HTTPRequestHeader(name, headers.RequestValue(span, name)))
+	}
+	for _, name := range headers.Response() {
+		attrs = append(attrs, HTTPResponseHeader(name, headers.ResponseValue(span, name)))
+	}
+	return attrs
+}
@@ -7,6 +7,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestEndpointAddresses(t *testing.T) {
+	assert.Empty(t, EndpointAddresses(&MetricsConfig{}, &TracesConfig{}))
+	assert.Equal(t, []string{"collector:4318"}, EndpointAddresses(
+		&MetricsConfig{CommonEndpoint: "http://collector:4318"},
+		&TracesConfig{CommonEndpoint: "http://collector:4318"}))
+	assert.Equal(t, []string{"metrics:443", "traces:80"}, EndpointAddresses(
+		&MetricsConfig{MetricsEndpoint: "https://metrics/v1/metrics"},
+		&TracesConfig{TracesEndpoint: "http://traces"}))
+	assert.Equal(t, []string{"otlp-gateway-eu-west-0.grafana.net:443"}, EndpointAddresses(
+		&MetricsConfig{Grafana: &GrafanaOTLP{CloudZone: "eu-west-0", Submit: []string{"metrics"}}},
+		&TracesConfig{}))
+}
+
 func TestOtlpOptions_AsMetricHTTP(t *testing.T) {
 	type testCase struct {
 		in  otlpOptions
@@ -36,7 +36,7 @@ type GrafanaOTLP struct {
 	InstanceID string `yaml:"cloud_instance_id" env:"GRAFANA_CLOUD_INSTANCE_ID"`
 
 	// APIKey of your Grafana Cloud account.
-	APIKey string `yaml:"cloud_api_key" env:"GRAFANA_CLOUD_API_KEY"`
+	APIKey string `yaml:"cloud_api_key" env:"GRAFANA_CLOUD_API_KEY" secret:"true"`
 }
 
 func (cfg *GrafanaOTLP) MetricsEnabled() bool {
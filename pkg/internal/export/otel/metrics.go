@@ -51,8 +51,8 @@ const (
 type MetricsConfig struct {
 	Interval time.Duration `yaml:"interval" env:"BEYLA_METRICS_INTERVAL"`
 
-	CommonEndpoint  string `yaml:"-" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
-	MetricsEndpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+	CommonEndpoint  string `yaml:"-" env:"OTEL_EXPORTER_OTLP_ENDPOINT" secret:"userinfo"`
+	MetricsEndpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT" secret:"userinfo"`
 
 	Protocol        Protocol `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"`
 	MetricsProtocol Protocol `yaml:"-" env:"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"`
@@ -78,6 +78,10 @@ type MetricsConfig struct {
 	// Features of metrics that are can be exported. Accepted values are "application" and "network".
 	Features []string `yaml:"features" env:"BEYLA_OTEL_METRIC_FEATURES" envSeparator:","`
 
+	// Profiles override the endpoint and headers of the exporter for the services whose discovery
+	// criteria reference them by name
+	Profiles map[string]ExporterProfile `yaml:"profiles"`
+
 	// Grafana configuration needs to be explicitly set up before building the graph
 	Grafana *GrafanaOTLP `yaml:"-"`
 }
@@ -92,6 +96,17 @@ func (m *MetricsConfig) GetProtocol() Protocol {
 	return m.GuessProtocol()
 }
 
+// forProfile returns a copy of the configuration whose endpoint is overridden by the profile, if defined
+func (m *MetricsConfig) forProfile(profile *ExporterProfile) *MetricsConfig {
+	cfg := *m
+	if profile.Endpoint != "" {
+		cfg.MetricsEndpoint = profile.Endpoint
+		// the Grafana Cloud credentials must not be sent to other endpoints
+		cfg.Grafana = nil
+	}
+	return &cfg
+}
+
 func (m *MetricsConfig) GuessProtocol() Protocol {
 	// If no explicit protocol is set, we guess it it from the metrics enpdoint port
 	// (assuming it uses a standard port or a development-like form like 14317, 24317, 14318...)
@@ -127,8 +142,15 @@ func (m MetricsConfig) Enabled() bool {
 type MetricsReporter struct {
 	ctx       context.Context
 	cfg       *MetricsConfig
+	metrics   imetrics.Reporter
 	exporter  metric.Exporter
 	reporters ReporterPool[*Metrics]
+	// headers selects the captured HTTP headers that are added as metric attributes
+	headers *request.MetricHeaders
+
+	// exporters of the exporter profiles, lazily instantiated the first time that a
+	// service references them
+	profileExporters map[string]metric.Exporter
 }
 
 // Metrics is a set of metrics associated to a given OTEL MeterProvider.
@@ -161,8 +183,11 @@ func ReportMetrics(
 func newMetricsReporter(ctx context.Context, cfg *MetricsConfig, ctxInfo *global.ContextInfo) (*MetricsReporter, error) {
 	log := mlog()
 	mr := MetricsReporter{
-		ctx: ctx,
-		cfg: cfg,
+		ctx:              ctx,
+		cfg:              cfg,
+		metrics:          ctxInfo.Metrics,
+		headers:          ctxInfo.MetricHeaders,
+		profileExporters: map[string]metric.Exporter{},
 	}
 	mr.reporters = NewReporterPool[*Metrics](cfg.ReportersCacheLen,
 		func(id svc.UID, v *Metrics) {
@@ -184,16 +209,41 @@ func newMetricsReporter(ctx context.Context, cfg *MetricsConfig, ctxInfo *global
 	return &mr, nil
 }
 
+// exporterFor returns the exporter of the given exporter profile, or the default
+// exporter if the profile is not defined for the metrics exporter
+func (mr *MetricsReporter) exporterFor(profileName string) (metric.Exporter, error) {
+	profile, ok := mr.cfg.Profiles[profileName]
+	if !ok {
+		return mr.exporter, nil
+	}
+	if exporter, ok := mr.profileExporters[profileName]; ok {
+		return exporter, nil
+	}
+	log := mlog().With("profile", profileName)
+	log.Debug("instantiating metrics exporter for profile")
+	exporter, err := instantiateMetricsExporter(mr.ctx, mr.cfg.forProfile(&profile), profile.exportHeaders(), log)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", profileName, err)
+	}
+	exporter = instrumentMetricsExporter(mr.metrics, exporter)
+	mr.profileExporters[profileName] = exporter
+	return exporter, nil
+}
+
 func (mr *MetricsReporter) newMetricSet(service svc.ID) (*Metrics, error) {
 	mlog := mlog().With("service", service)
 	mlog.Debug("creating new Metrics reporter")
 	useExponentialHistograms := isExponentialAggregation(mr.cfg, mlog)
+	exporter, err := mr.exporterFor(service.ExporterProfile)
+	if err != nil {
+		return nil, err
+	}
 	resources := Resource(service)
 	m := Metrics{
 		ctx: mr.ctx,
 		provider: metric.NewMeterProvider(
 			metric.WithResource(resources),
-			metric.WithReader(metric.NewPeriodicReader(mr.exporter,
+			metric.WithReader(metric.NewPeriodicReader(exporter,
 				metric.WithInterval(mr.cfg.Interval))),
 			metric.WithView(otelHistogramConfig(HTTPServerDuration, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
 			metric.WithView(otelHistogramConfig(HTTPClientDuration, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
@@ -207,7 +257,6 @@ func (mr *MetricsReporter) newMetricSet(service svc.ID) (*Metrics, error) {
 	// time units for HTTP and GRPC durations are in seconds, according to the OTEL specification:
 	// https://github.com/open-telemetry/opentelemetry-specification/tree/main/specification/metrics/semantic_conventions
 	// TODO: set ExplicitBucketBoundaries here and in prometheus from the previous specification
-	var err error
 	meter := m.provider.Meter(reporterName)
 	m.httpDuration, err = meter.Float64Histogram(HTTPServerDuration, instrument.WithUnit("s"))
 	if err != nil {
@@ -256,17 +305,23 @@ func isExponentialAggregation(mc *MetricsConfig, mlog *slog.Logger) bool {
 
 // TODO: restore as private
 func InstantiateMetricsExporter(ctx context.Context, cfg *MetricsConfig, log *slog.Logger) (metric.Exporter, error) {
+	return instantiateMetricsExporter(ctx, cfg, nil, log)
+}
+
+// instantiateMetricsExporter instantiates the OTLP HTTP or GRPC metrics exporter. The provided headers
+// are added to the exported requests.
+func instantiateMetricsExporter(ctx context.Context, cfg *MetricsConfig, headers map[string]string, log *slog.Logger) (metric.Exporter, error) {
 	var err error
 	var exporter metric.Exporter
 	switch proto := cfg.GetProtocol(); proto {
 	case ProtocolHTTPJSON, ProtocolHTTPProtobuf, "": // zero value defaults to HTTP for backwards-compatibility
 		log.Debug("instantiating HTTP MetricsReporter", "protocol", proto)
-		if exporter, err = httpMetricsExporter(ctx, cfg); err != nil {
+		if exporter, err = httpMetricsExporter(ctx, cfg, headers); err != nil {
 			return nil, fmt.Errorf("can't instantiate OTEL HTTP metrics exporter: %w", err)
 		}
 	case ProtocolGRPC:
 		log.Debug("instantiating GRPC MetricsReporter", "protocol", proto)
-		if exporter, err = grpcMetricsExporter(ctx, cfg); err != nil {
+		if exporter, err = grpcMetricsExporter(ctx, cfg, headers); err != nil {
 			return nil, fmt.Errorf("can't instantiate OTEL GRPC metrics exporter: %w", err)
 		}
 	default:
@@ -276,11 +331,12 @@ func InstantiateMetricsExporter(ctx context.Context, cfg *MetricsConfig, log *sl
 	return exporter, nil
 }
 
-func httpMetricsExporter(ctx context.Context, cfg *MetricsConfig) (metric.Exporter, error) {
+func httpMetricsExporter(ctx context.Context, cfg *MetricsConfig, headers map[string]string) (metric.Exporter, error) {
 	opts, err := getHTTPMetricEndpointOptions(cfg)
 	if err != nil {
 		return nil, err
 	}
+	opts.addHeaders(headers)
 	mexp, err := otlpmetrichttp.New(ctx, opts.AsMetricHTTP()...)
 	if err != nil {
 		return nil, fmt.Errorf("creating HTTP metric exporter: %w", err)
@@ -288,11 +344,12 @@ func httpMetricsExporter(ctx context.Context, cfg *MetricsConfig) (metric.Export
 	return mexp, nil
 }
 
-func grpcMetricsExporter(ctx context.Context, cfg *MetricsConfig) (metric.Exporter, error) {
+func grpcMetricsExporter(ctx context.Context, cfg *MetricsConfig, headers map[string]string) (metric.Exporter, error) {
 	opts, err := getGRPCMetricEndpointOptions(cfg)
 	if err != nil {
 		return nil, err
 	}
+	opts.addHeaders(headers)
 	mexp, err := otlpmetricgrpc.New(ctx, opts.AsMetricGRPC()...)
 	if err != nil {
 		return nil, fmt.Errorf("creating GRPC metric exporter: %w", err)
@@ -304,6 +361,11 @@ func (mr *MetricsReporter) close() {
 	if err := mr.exporter.Shutdown(mr.ctx); err != nil {
 		slog.With("component", "MetricsReporter").Error("closing metrics provider", "error", err)
 	}
+	for name, exporter := range mr.profileExporters {
+		if err := exporter.Shutdown(mr.ctx); err != nil {
+			slog.With("component", "MetricsReporter").Error("closing metrics provider", "profile", name, "error", err)
+		}
+	}
 }
 
 // instrumentMetricsExporter checks whether the context is configured to report internal metrics and,
@@ -380,8 +442,7 @@ func (mr *MetricsReporter) httpServerAttributes(span *request.Span) []attribute.
 	if span.Route != "" {
 		attrs = append(attrs, semconv.HTTPRoute(span.Route))
 	}
-
-	return attrs
+	return appendMetricHeaders(attrs, mr.headers, span)
 }
 
 func (mr *MetricsReporter) httpClientAttributes(span *request.Span) []attribute.KeyValue {
@@ -396,8 +457,7 @@ func (mr *MetricsReporter) httpClientAttributes(span *request.Span) []attribute.
 	if span.Route != "" {
 		attrs = append(attrs, semconv.HTTPRoute(span.Route))
 	}
-
-	return attrs
+	return appendMetricHeaders(attrs, mr.headers, span)
 }
 
 func (mr *MetricsReporter) metricAttributes(span *request.Span) attribute.Set {
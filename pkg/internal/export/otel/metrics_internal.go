@@ -0,0 +1,232 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	instrument "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
+
+	"github.com/grafana/beyla/pkg/buildinfo"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+const internalReporterName = "github.com/grafana/beyla/internal"
+
+// pipelineBufferLengths buckets for the ebpf.tracer.flushes histogram. They are the same as
+// the buckets of the equivalent metric in the Prometheus internal metrics reporter
+var pipelineBufferLengths = []float64{0, 10, 20, 40, 80, 160, 320}
+
+func ilog() *slog.Logger {
+	return slog.With("component", "otel.InternalMetricsReporter")
+}
+
+// InternalMetricsReporter is an internal metrics Reporter that pushes the internal metrics of Beyla
+// to the endpoint of the OTEL metrics exporter
+type InternalMetricsReporter struct {
+	provider *metric.MeterProvider
+
+	tracerFlushes        instrument.Int64Histogram
+	otelMetricExports    instrument.Int64Counter
+	otelMetricExportErrs instrument.Int64Counter
+	otelTraceExports     instrument.Int64Counter
+	otelTraceExportErrs  instrument.Int64Counter
+	prometheusRequests   instrument.Int64Counter
+	ringbufReserveFails  instrument.Int64Counter
+	filteredSpans        instrument.Int64Counter
+	tracerRecordErrors   instrument.Int64Counter
+
+	// the values of the gauges are stored until they are observed by the metrics reader
+	gaugesMux         sync.Mutex
+	tracerChannelFill map[string]float64
+	bpfMapEntries     map[string]int
+	bpfMapMaxEntries  map[string]int
+}
+
+// NewInternalMetricsReporter instantiates an OTEL internal metrics reporter that exports the metrics
+// with the endpoint, protocol and interval of the provided OTEL metrics exporter configuration,
+// unless the interval is overridden in the internal metrics configuration.
+func NewInternalMetricsReporter(ctx context.Context, cfg *MetricsConfig, icfg *imetrics.OTELConfig) (*InternalMetricsReporter, error) {
+	exporter, err := instantiateMetricsExporter(ctx, cfg, nil, ilog())
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.Interval
+	if icfg.Interval != 0 {
+		interval = icfg.Interval
+	}
+	ir := &InternalMetricsReporter{
+		provider: metric.NewMeterProvider(
+			metric.WithResource(internalResource()),
+			metric.WithReader(metric.NewPeriodicReader(exporter, metric.WithInterval(interval))),
+		),
+		tracerChannelFill: map[string]float64{},
+		bpfMapEntries:     map[string]int{},
+		bpfMapMaxEntries:  map[string]int{},
+	}
+	if err := ir.createInstruments(); err != nil {
+		return nil, fmt.Errorf("creating internal metrics instruments: %w", err)
+	}
+	return ir, nil
+}
+
+// internalResource identifies the Beyla instance that reports the internal metrics
+func internalResource() *resource.Resource {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName("beyla"),
+		semconv.ServiceVersion(buildinfo.Version),
+		semconv.TelemetrySDKNameKey.String("beyla"),
+		attribute.String("beyla.revision", buildinfo.Revision),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostName(hostname))
+	} else {
+		ilog().Debug("can't get hostname. Not adding it to the resource attributes", "error", err)
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
+
+//nolint:cyclop
+func (ir *InternalMetricsReporter) createInstruments() error {
+	meter := ir.provider.Meter(internalReporterName)
+	var err error
+	if ir.tracerFlushes, err = meter.Int64Histogram("ebpf.tracer.flushes",
+		instrument.WithDescription("length of the groups of traces flushed from the eBPF tracer to the next pipeline stage"),
+		instrument.WithExplicitBucketBoundaries(pipelineBufferLengths...)); err != nil {
+		return err
+	}
+	if ir.otelMetricExports, err = meter.Int64Counter("otel.metric.exports",
+		instrument.WithDescription("length of the metric batches submitted to the remote OTEL collector")); err != nil {
+		return err
+	}
+	if ir.otelMetricExportErrs, err = meter.Int64Counter("otel.metric.export.errors",
+		instrument.WithDescription("error count on each failed OTEL metric export")); err != nil {
+		return err
+	}
+	if ir.otelTraceExports, err = meter.Int64Counter("otel.trace.exports",
+		instrument.WithDescription("length of the trace batches submitted to the remote OTEL collector")); err != nil {
+		return err
+	}
+	if ir.otelTraceExportErrs, err = meter.Int64Counter("otel.trace.export.errors",
+		instrument.WithDescription("error count on each failed OTEL trace export")); err != nil {
+		return err
+	}
+	if ir.prometheusRequests, err = meter.Int64Counter("prometheus.http.requests",
+		instrument.WithDescription("requests towards the Prometheus Scrape endpoint")); err != nil {
+		return err
+	}
+	if ir.ringbufReserveFails, err = meter.Int64Counter("ebpf.ringbuf.reserve.failures",
+		instrument.WithDescription("events discarded by the eBPF programs because there wasn't space left in the ring buffer")); err != nil {
+		return err
+	}
+	if ir.filteredSpans, err = meter.Int64Counter("ebpf.tracer.filtered_spans",
+		instrument.WithDescription("spans discarded by the eBPF tracer because their process doesn't match the discovery criteria")); err != nil {
+		return err
+	}
+	if ir.tracerRecordErrors, err = meter.Int64Counter("ebpf.tracer.record_errors",
+		instrument.WithDescription("ring buffer records that couldn't be parsed by the eBPF tracer")); err != nil {
+		return err
+	}
+	if _, err = meter.Float64ObservableGauge("ebpf.tracer.channel.fill_ratio",
+		instrument.WithDescription("fill ratio (from 0 to 1) of the channel between the eBPF tracer and the next pipeline stage"),
+		instrument.WithFloat64Callback(func(_ context.Context, o instrument.Float64Observer) error {
+			ir.gaugesMux.Lock()
+			defer ir.gaugesMux.Unlock()
+			for tracer, ratio := range ir.tracerChannelFill {
+				o.Observe(ratio, instrument.WithAttributes(attribute.String("tracer", tracer)))
+			}
+			return nil
+		})); err != nil {
+		return err
+	}
+	if _, err = meter.Int64ObservableGauge("ebpf.map.entries",
+		instrument.WithDescription("number of entries of the pinned eBPF maps"),
+		instrument.WithInt64Callback(ir.observeMaps(ir.bpfMapEntries))); err != nil {
+		return err
+	}
+	if _, err = meter.Int64ObservableGauge("ebpf.map.max_entries",
+		instrument.WithDescription("maximum number of entries of the pinned eBPF maps"),
+		instrument.WithInt64Callback(ir.observeMaps(ir.bpfMapMaxEntries))); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ir *InternalMetricsReporter) observeMaps(values map[string]int) instrument.Int64Callback {
+	return func(_ context.Context, o instrument.Int64Observer) error {
+		ir.gaugesMux.Lock()
+		defer ir.gaugesMux.Unlock()
+		for name, value := range values {
+			o.Observe(int64(value), instrument.WithAttributes(attribute.String("map", name)))
+		}
+		return nil
+	}
+}
+
+// Start waits until the context is done, to flush and stop the metrics provider
+func (ir *InternalMetricsReporter) Start(ctx context.Context) {
+	<-ctx.Done()
+	// the parent context is already cancelled, so we can't use it to flush the pending metrics
+	if err := ir.provider.Shutdown(context.Background()); err != nil {
+		ilog().Debug("error shutting down the internal metrics provider", "error", err)
+	}
+}
+
+func (ir *InternalMetricsReporter) TracerFlush(len int) {
+	ir.tracerFlushes.Record(context.Background(), int64(len))
+}
+
+func (ir *InternalMetricsReporter) OTELMetricExport(len int) {
+	ir.otelMetricExports.Add(context.Background(), int64(len))
+}
+
+func (ir *InternalMetricsReporter) OTELMetricExportError(err error) {
+	ir.otelMetricExportErrs.Add(context.Background(), 1, instrument.WithAttributes(attribute.String("error", err.Error())))
+}
+
+func (ir *InternalMetricsReporter) OTELTraceExport(len int) {
+	ir.otelTraceExports.Add(context.Background(), int64(len))
+}
+
+func (ir *InternalMetricsReporter) OTELTraceExportError(err error) {
+	ir.otelTraceExportErrs.Add(context.Background(), 1, instrument.WithAttributes(attribute.String("error", err.Error())))
+}
+
+func (ir *InternalMetricsReporter) PrometheusRequest(port, path string) {
+	ir.prometheusRequests.Add(context.Background(), 1,
+		instrument.WithAttributes(attribute.String("port", port), attribute.String("path", path)))
+}
+
+func (ir *InternalMetricsReporter) RingbufReserveFailures(count int) {
+	ir.ringbufReserveFails.Add(context.Background(), int64(count))
+}
+
+func (ir *InternalMetricsReporter) FilteredSpans(count int) {
+	ir.filteredSpans.Add(context.Background(), int64(count))
+}
+
+func (ir *InternalMetricsReporter) TracerRecordError() {
+	ir.tracerRecordErrors.Add(context.Background(), 1)
+}
+
+func (ir *InternalMetricsReporter) TracerChannelFill(tracer string, length, capacity int) {
+	if capacity == 0 {
+		return
+	}
+	ir.gaugesMux.Lock()
+	defer ir.gaugesMux.Unlock()
+	ir.tracerChannelFill[tracer] = float64(length) / float64(capacity)
+}
+
+func (ir *InternalMetricsReporter) BPFMapEntries(mapName string, entries, maxEntries int) {
+	ir.gaugesMux.Lock()
+	defer ir.gaugesMux.Unlock()
+	ir.bpfMapEntries[mapName] = entries
+	ir.bpfMapMaxEntries[mapName] = maxEntries
+}
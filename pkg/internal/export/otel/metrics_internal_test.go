@@ -0,0 +1,91 @@
+package otel
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/beyla/pkg/buildinfo"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+func TestInternalMetricsReporter(t *testing.T) {
+	defer restoreEnvAfterExecution()()
+	// fake OTEL collector, recording the resource attributes and the names of the received metrics
+	var mt sync.Mutex
+	resourceAttrs := map[string]string{}
+	metricNames := map[string]bool{}
+	coll := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		export := pmetricotlp.NewExportRequest()
+		if err := export.UnmarshalProto(body); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mt.Lock()
+		defer mt.Unlock()
+		rms := export.Metrics().ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			rms.At(i).Resource().Attributes().Range(func(k string, v pcommon.Value) bool {
+				resourceAttrs[k] = v.AsString()
+				return true
+			})
+			sms := rms.At(i).ScopeMetrics()
+			for j := 0; j < sms.Len(); j++ {
+				ms := sms.At(j).Metrics()
+				for k := 0; k < ms.Len(); k++ {
+					metricNames[ms.At(k).Name()] = true
+				}
+			}
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer coll.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reporter, err := NewInternalMetricsReporter(ctx,
+		&MetricsConfig{CommonEndpoint: coll.URL, Protocol: ProtocolHTTPProtobuf, Interval: time.Hour},
+		&imetrics.OTELConfig{Enabled: true, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	go reporter.Start(ctx)
+
+	reporter.TracerFlush(3)
+	reporter.RingbufReserveFailures(2)
+	reporter.TracerChannelFill("shared", 5, 10)
+	reporter.BPFMapEntries("ongoing_server_requests", 3, 100)
+
+	// the received values are copied before being checked, to not block the fake collector
+	// while the assertions are failing
+	received := func() (map[string]string, map[string]bool) {
+		mt.Lock()
+		defer mt.Unlock()
+		return maps.Clone(resourceAttrs), maps.Clone(metricNames)
+	}
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		resourceAttrs, metricNames := received()
+		assert.Equal(t, "beyla", resourceAttrs["service.name"])
+		assert.Equal(t, buildinfo.Version, resourceAttrs["service.version"])
+		assert.NotEmpty(t, resourceAttrs["host.name"])
+		assert.True(t, metricNames["ebpf.tracer.flushes"])
+		assert.True(t, metricNames["ebpf.ringbuf.reserve.failures"])
+		assert.True(t, metricNames["ebpf.tracer.channel.fill_ratio"])
+		assert.True(t, metricNames["ebpf.map.entries"])
+		assert.True(t, metricNames["ebpf.map.max_entries"])
+	}, test.Interval(10*time.Millisecond))
+}
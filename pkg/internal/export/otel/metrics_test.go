@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -13,10 +14,12 @@ import (
 	"github.com/mariomac/pipes/pkg/node"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 const timeout = 5 * time.Second
@@ -196,6 +199,75 @@ func TestMetrics_InternalInstrumentation(t *testing.T) {
 	})
 }
 
+func TestMetrics_ExporterProfiles(t *testing.T) {
+	defer restoreEnvAfterExecution()()
+	// the headers of the OTEL SDK variables must not be sent to the endpoints of the profiles
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=secret")
+	// fake OTEL collectors, recording the tenant and authorization headers of the received requests
+	var defaultTenants, profileTenants, profileAuths, noHeadersAuths sync.Map
+	defaultColl := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		defaultTenants.Store(req.Header.Get("X-Scope-OrgID"), true)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer defaultColl.Close()
+	profileColl := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		profileTenants.Store(req.Header.Get("X-Scope-OrgID"), true)
+		profileAuths.Store(req.Header.Get("Authorization"), true)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer profileColl.Close()
+	noHeadersColl := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		noHeadersAuths.Store(req.Header.Get("Authorization"), true)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer noHeadersColl.Close()
+
+	exporter, err := ReportMetrics(context.Background(),
+		&MetricsConfig{
+			CommonEndpoint:    defaultColl.URL,
+			Interval:          10 * time.Millisecond,
+			ReportersCacheLen: 16,
+			Profiles: map[string]ExporterProfile{
+				"team-a": {Endpoint: profileColl.URL + "/v1/metrics", Headers: map[string]string{"X-Scope-OrgID": "team-a"}},
+				"team-b": {Headers: map[string]string{"X-Scope-OrgID": "team-b"}},
+				"team-d": {Endpoint: noHeadersColl.URL + "/v1/metrics"},
+			},
+		},
+		&global.ContextInfo{Metrics: imetrics.NoopReporter{}})
+	require.NoError(t, err)
+	spans := make(chan []request.Span, 10)
+	go exporter(spans)
+	defer close(spans)
+
+	spans <- []request.Span{
+		{Type: request.EventTypeHTTP, ServiceID: svc.ID{UID: "default", Name: "default"}},
+		{Type: request.EventTypeHTTP, ServiceID: svc.ID{UID: "a", Name: "a", ExporterProfile: "team-a"}},
+		{Type: request.EventTypeHTTP, ServiceID: svc.ID{UID: "b", Name: "b", ExporterProfile: "team-b"}},
+		// undefined profiles fall back to the default exporter
+		{Type: request.EventTypeHTTP, ServiceID: svc.ID{UID: "c", Name: "c", ExporterProfile: "team-c"}},
+		{Type: request.EventTypeHTTP, ServiceID: svc.ID{UID: "d", Name: "d", ExporterProfile: "team-d"}},
+	}
+
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		_, ok := defaultTenants.Load("")
+		assert.True(t, ok, "default exporter should send metrics without tenant header")
+		_, ok = defaultTenants.Load("team-b")
+		assert.True(t, ok, "team-b profile should send metrics to the default endpoint")
+		_, ok = profileTenants.Load("team-a")
+		assert.True(t, ok, "team-a profile should send metrics to its own endpoint")
+		_, ok = noHeadersAuths.Load("")
+		assert.True(t, ok, "team-d profile should send metrics without the SDK headers")
+	})
+	_, ok := defaultTenants.Load("team-a")
+	assert.False(t, ok)
+	_, ok = profileTenants.Load("")
+	assert.False(t, ok)
+	_, ok = profileAuths.Load("secret")
+	assert.False(t, ok)
+	_, ok = noHeadersAuths.Load("secret")
+	assert.False(t, ok)
+}
+
 type fakeInternalMetrics struct {
 	imetrics.NoopReporter
 	sum  atomic.Int32
@@ -338,6 +410,43 @@ func TestMetricsConfig_Disabled(t *testing.T) {
 	assert.False(t, MetricsConfig{Grafana: &GrafanaOTLP{Submit: []string{"traces", "metrics"}, InstanceID: "33221"}}.Enabled())
 }
 
+func TestMetrics_HeaderAttributes(t *testing.T) {
+	mr := MetricsReporter{
+		cfg:     &MetricsConfig{},
+		headers: request.NewMetricHeaders([]string{"x-tenant-id"}, []string{"content-type"}, 1),
+	}
+	span := &request.Span{
+		Type: request.EventTypeHTTP, Method: "GET", Status: 200,
+		RequestHeaders:  map[string]string{"x-tenant-id": "tenant1", "user-agent": "curl"},
+		ResponseHeaders: map[string]string{"content-type": "text/plain"},
+	}
+	assert.Equal(t, []attribute.KeyValue{
+		HTTPRequestMethod("GET"),
+		HTTPResponseStatusCode(200),
+		HTTPRequestHeader("x-tenant-id", "tenant1"),
+		HTTPResponseHeader("content-type", "text/plain"),
+	}, mr.httpServerAttributes(span))
+
+	// values over the limit are reported as "other", and missing headers as empty values
+	span = &request.Span{
+		Type: request.EventTypeHTTPClient, Method: "GET", Status: 200,
+		RequestHeaders: map[string]string{"x-tenant-id": "tenant2"},
+	}
+	assert.Equal(t, []attribute.KeyValue{
+		HTTPRequestMethod("GET"),
+		HTTPResponseStatusCode(200),
+		HTTPRequestHeader("x-tenant-id", "other"),
+		HTTPResponseHeader("content-type", ""),
+	}, mr.httpClientAttributes(span))
+
+	// no headers are added by default
+	mr.headers = nil
+	assert.Equal(t, []attribute.KeyValue{
+		HTTPRequestMethod("GET"),
+		HTTPResponseStatusCode(200),
+	}, mr.httpClientAttributes(span))
+}
+
 func (f *fakeInternalMetrics) OTELMetricExport(len int) {
 	f.cnt.Add(1)
 	f.sum.Add(int32(len))
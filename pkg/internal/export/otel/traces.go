@@ -32,8 +32,8 @@ func tlog() *slog.Logger {
 const reporterName = "github.com/grafana/beyla"
 
 type TracesConfig struct {
-	CommonEndpoint string `yaml:"-" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
-	TracesEndpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	CommonEndpoint string `yaml:"-" env:"OTEL_EXPORTER_OTLP_ENDPOINT" secret:"userinfo"`
+	TracesEndpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT" secret:"userinfo"`
 
 	Protocol       Protocol `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"`
 	TracesProtocol Protocol `yaml:"-" env:"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"`
@@ -43,6 +43,10 @@ type TracesConfig struct {
 
 	Sampler Sampler `yaml:"sampler"`
 
+	// Profiles override the endpoint and headers of the exporter for the services whose discovery
+	// criteria reference them by name
+	Profiles map[string]ExporterProfile `yaml:"profiles"`
+
 	// Configuration options below this line will remain undocumented at the moment,
 	// but can be useful for performance-tuning of some customers.
 	MaxExportBatchSize int           `yaml:"max_export_batch_size" env:"BEYLA_OTLP_TRACES_MAX_EXPORT_BATCH_SIZE"`
@@ -77,6 +81,17 @@ func (m *TracesConfig) GetProtocol() Protocol {
 	return m.GuessProtocol()
 }
 
+// forProfile returns a copy of the configuration whose endpoint is overridden by the profile, if defined
+func (m *TracesConfig) forProfile(profile *ExporterProfile) *TracesConfig {
+	cfg := *m
+	if profile.Endpoint != "" {
+		cfg.TracesEndpoint = profile.Endpoint
+		// the Grafana Cloud credentials must not be sent to other endpoints
+		cfg.Grafana = nil
+	}
+	return &cfg
+}
+
 func (m *TracesConfig) GuessProtocol() Protocol {
 	// If no explicit protocol is set, we guess it it from the metrics enpdoint port
 	// (assuming it uses a standard port or a development-like form like 14317, 24317, 14318...)
@@ -98,9 +113,15 @@ func (m *TracesConfig) GuessProtocol() Protocol {
 type TracesReporter struct {
 	ctx           context.Context
 	cfg           *TracesConfig
+	metrics       imetrics.Reporter
 	traceExporter trace.SpanExporter
+	bspOpts       []trace.BatchSpanProcessorOption
 	bsp           trace.SpanProcessor
 	reporters     ReporterPool[*Tracers]
+
+	// span processors of the exporter profiles, lazily instantiated
+	// the first time that a service references them
+	profileProcessors map[string]trace.SpanProcessor
 }
 
 // Tracers handles the OTEL traces providers and exporters.
@@ -125,7 +146,12 @@ func ReportTraces(ctx context.Context, cfg *TracesConfig, ctxInfo *global.Contex
 
 func newTracesReporter(ctx context.Context, cfg *TracesConfig, ctxInfo *global.ContextInfo) (*TracesReporter, error) {
 	log := tlog()
-	r := TracesReporter{ctx: ctx, cfg: cfg}
+	r := TracesReporter{
+		ctx:               ctx,
+		cfg:               cfg,
+		metrics:           ctxInfo.Metrics,
+		profileProcessors: map[string]trace.SpanProcessor{},
+	}
 	r.reporters = NewReporterPool[*Tracers](cfg.ReportersCacheLen,
 		func(k svc.UID, v *Tracers) {
 			llog := log.With("service", k)
@@ -137,49 +163,60 @@ func newTracesReporter(ctx context.Context, cfg *TracesConfig, ctxInfo *global.C
 			}()
 		}, r.newTracers)
 	// Instantiate the OTLP HTTP or GRPC traceExporter
-	var err error
-	var exporter trace.SpanExporter
-	switch proto := cfg.GetProtocol(); proto {
-	case ProtocolHTTPJSON, ProtocolHTTPProtobuf, "": // zero value defaults to HTTP for backwards-compatibility
-		log.Debug("instantiating HTTP TracesReporter", "protocol", proto)
-		if exporter, err = httpTracer(ctx, cfg); err != nil {
-			return nil, fmt.Errorf("can't instantiate OTEL HTTP traces exporter: %w", err)
-		}
-	case ProtocolGRPC:
-		log.Debug("instantiating GRPC TracesReporter", "protocol", proto)
-		if exporter, err = grpcTracer(ctx, cfg); err != nil {
-			return nil, fmt.Errorf("can't instantiate OTEL GRPC traces exporter: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("invalid protocol value: %q. Accepted values are: %s, %s, %s",
-			proto, ProtocolGRPC, ProtocolHTTPJSON, ProtocolHTTPProtobuf)
+	exporter, err := tracesExporter(ctx, cfg, nil)
+	if err != nil {
+		return nil, err
 	}
-
 	r.traceExporter = instrumentTraceExporter(exporter, ctxInfo.Metrics)
 
-	var opts []trace.BatchSpanProcessorOption
 	if cfg.MaxExportBatchSize > 0 {
-		opts = append(opts, trace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+		r.bspOpts = append(r.bspOpts, trace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
 	}
 	if cfg.MaxQueueSize > 0 {
-		opts = append(opts, trace.WithMaxQueueSize(cfg.MaxQueueSize))
+		r.bspOpts = append(r.bspOpts, trace.WithMaxQueueSize(cfg.MaxQueueSize))
 	}
 	if cfg.BatchTimeout > 0 {
-		opts = append(opts, trace.WithBatchTimeout(cfg.BatchTimeout))
+		r.bspOpts = append(r.bspOpts, trace.WithBatchTimeout(cfg.BatchTimeout))
 	}
 	if cfg.ExportTimeout > 0 {
-		opts = append(opts, trace.WithExportTimeout(cfg.ExportTimeout))
+		r.bspOpts = append(r.bspOpts, trace.WithExportTimeout(cfg.ExportTimeout))
 	}
 
-	r.bsp = trace.NewBatchSpanProcessor(r.traceExporter, opts...)
+	r.bsp = trace.NewBatchSpanProcessor(r.traceExporter, r.bspOpts...)
 	return &r, nil
 }
 
-func httpTracer(ctx context.Context, cfg *TracesConfig) (*otlptrace.Exporter, error) {
+// tracesExporter instantiates the OTLP HTTP or GRPC traces exporter. The provided headers
+// are added to the exported requests.
+func tracesExporter(ctx context.Context, cfg *TracesConfig, headers map[string]string) (trace.SpanExporter, error) {
+	log := tlog()
+	switch proto := cfg.GetProtocol(); proto {
+	case ProtocolHTTPJSON, ProtocolHTTPProtobuf, "": // zero value defaults to HTTP for backwards-compatibility
+		log.Debug("instantiating HTTP TracesReporter", "protocol", proto)
+		exporter, err := httpTracer(ctx, cfg, headers)
+		if err != nil {
+			return nil, fmt.Errorf("can't instantiate OTEL HTTP traces exporter: %w", err)
+		}
+		return exporter, nil
+	case ProtocolGRPC:
+		log.Debug("instantiating GRPC TracesReporter", "protocol", proto)
+		exporter, err := grpcTracer(ctx, cfg, headers)
+		if err != nil {
+			return nil, fmt.Errorf("can't instantiate OTEL GRPC traces exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("invalid protocol value: %q. Accepted values are: %s, %s, %s",
+			proto, ProtocolGRPC, ProtocolHTTPJSON, ProtocolHTTPProtobuf)
+	}
+}
+
+func httpTracer(ctx context.Context, cfg *TracesConfig, headers map[string]string) (*otlptrace.Exporter, error) {
 	topts, err := getHTTPTracesEndpointOptions(cfg)
 	if err != nil {
 		return nil, err
 	}
+	topts.addHeaders(headers)
 	texp, err := otlptracehttp.New(ctx, topts.AsTraceHTTP()...)
 	if err != nil {
 		return nil, fmt.Errorf("creating HTTP trace exporter: %w", err)
@@ -187,11 +224,12 @@ func httpTracer(ctx context.Context, cfg *TracesConfig) (*otlptrace.Exporter, er
 	return texp, nil
 }
 
-func grpcTracer(ctx context.Context, cfg *TracesConfig) (*otlptrace.Exporter, error) {
+func grpcTracer(ctx context.Context, cfg *TracesConfig, headers map[string]string) (*otlptrace.Exporter, error) {
 	topts, err := getGRPCTracesEndpointOptions(cfg)
 	if err != nil {
 		return nil, err
 	}
+	topts.addHeaders(headers)
 	texp, err := otlptracegrpc.New(ctx, topts.AsTraceGRPC()...)
 	if err != nil {
 		return nil, fmt.Errorf("creating GRPC trace exporter: %w", err)
@@ -227,6 +265,12 @@ func (r *TracesReporter) close() {
 	if err := r.traceExporter.Shutdown(r.ctx); err != nil {
 		log.Error("closing traces exporter", "error", err)
 	}
+	// shutting down the span processors flushes the pending spans before shutting down their exporters
+	for name, bsp := range r.profileProcessors {
+		if err := bsp.Shutdown(r.ctx); err != nil {
+			log.Error("closing traces exporter", "profile", name, "error", err)
+		}
+	}
 }
 
 // https://opentelemetry.io/docs/specs/otel/trace/semantic_conventions/http/#status
@@ -299,6 +343,7 @@ func TraceAttributes(span *request.Span) []attribute.KeyValue {
 		if span.Route != "" {
 			attrs = append(attrs, semconv.HTTPRoute(span.Route))
 		}
+		attrs = append(attrs, httpHeaders(span)...)
 	case request.EventTypeGRPC:
 		attrs = []attribute.KeyValue{
 			semconv.RPCMethod(span.Path),
@@ -317,6 +362,7 @@ func TraceAttributes(span *request.Span) []attribute.KeyValue {
 			ServerPort(span.HostPort),
 			HTTPRequestBodySize(int(span.ContentLength)),
 		}
+		attrs = append(attrs, httpHeaders(span)...)
 	case request.EventTypeGRPCClient:
 		attrs = []attribute.KeyValue{
 			semconv.RPCMethod(span.Path),
@@ -474,11 +520,15 @@ func (r *TracesReporter) reportTraces(input <-chan []request.Span) {
 
 func (r *TracesReporter) newTracers(service svc.ID) (*Tracers, error) {
 	tlog().Debug("creating new Tracers reporter", "service", service)
+	bsp, err := r.spanProcessor(service.ExporterProfile)
+	if err != nil {
+		return nil, err
+	}
 	tracers := Tracers{
 		ctx: r.ctx,
 		provider: trace.NewTracerProvider(
 			trace.WithResource(Resource(service)),
-			trace.WithSpanProcessor(r.bsp),
+			trace.WithSpanProcessor(bsp),
 			trace.WithSampler(r.cfg.Sampler.Implementation()),
 			trace.WithIDGenerator(&BeylaIDGenerator{}),
 		),
@@ -487,6 +537,27 @@ func (r *TracesReporter) newTracers(service svc.ID) (*Tracers, error) {
 	return &tracers, nil
 }
 
+// spanProcessor returns the span processor of the given exporter profile, or the default
+// span processor if the profile is not defined for the traces exporter
+func (r *TracesReporter) spanProcessor(profileName string) (trace.SpanProcessor, error) {
+	profile, ok := r.cfg.Profiles[profileName]
+	if !ok {
+		return r.bsp, nil
+	}
+	if bsp, ok := r.profileProcessors[profileName]; ok {
+		return bsp, nil
+	}
+	tlog().Debug("instantiating traces exporter for profile", "profile", profileName)
+	exporter, err := tracesExporter(r.ctx, r.cfg.forProfile(&profile), profile.exportHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", profileName, err)
+	}
+	exporter = instrumentTraceExporter(exporter, r.metrics)
+	bsp := trace.NewBatchSpanProcessor(exporter, r.bspOpts...)
+	r.profileProcessors[profileName] = bsp
+	return bsp, nil
+}
+
 // the HTTP path will be defined from one of the following sources, from highest to lowest priority
 // - OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, if defined
 // - OTEL_EXPORTER_OTLP_ENDPOINT, if defined
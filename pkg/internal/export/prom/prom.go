@@ -4,6 +4,7 @@ import (
 	"context"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mariomac/pipes/pkg/node"
@@ -48,6 +49,9 @@ const (
 	rpcSystemGRPC        = "rpc_system"
 	DBOperationKey       = "db_operation"
 
+	httpRequestHeaderPrefix  = "http_request_header_"
+	httpResponseHeaderPrefix = "http_response_header_"
+
 	k8sNamespaceName   = "k8s_namespace_name"
 	k8sPodName         = "k8s_pod_name"
 	k8sDeploymentName  = "k8s_deployment_name"
@@ -331,7 +335,7 @@ func labelNamesHTTPClient(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []
 	if ctxInfo.ReportRoutes {
 		names = append(names, httpRouteKey)
 	}
-	return names
+	return appendHeaderLabelNames(names, ctxInfo)
 }
 
 // labelValuesHTTPClient must return the label names in the same order as would be returned
@@ -349,7 +353,7 @@ func (r *metricsReporter) labelValuesHTTPClient(span *request.Span) []string {
 	if r.ctxInfo.ReportRoutes {
 		values = append(values, span.Route) // httpRouteKey
 	}
-	return values
+	return r.appendHeaderLabelValues(values, span)
 }
 
 // labelNamesHTTP must return the label names in the same order as would be returned
@@ -368,7 +372,7 @@ func labelNamesHTTP(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string
 	if ctxInfo.K8sEnabled {
 		names = appendK8sLabelNames(names)
 	}
-	return names
+	return appendHeaderLabelNames(names, ctxInfo)
 }
 
 // labelValuesGRPC must return the label names in the same order as would be returned
@@ -388,6 +392,41 @@ func (r *metricsReporter) labelValuesHTTP(span *request.Span) []string {
 	if r.ctxInfo.K8sEnabled {
 		values = appendK8sLabelValues(values, span)
 	}
+	return r.appendHeaderLabelValues(values, span)
+}
+
+// appendHeaderLabelNames appends a label for each captured HTTP header that has to be reported
+// in the metrics. E.g. x-tenant-id request header --> http_request_header_x_tenant_id
+func appendHeaderLabelNames(names []string, ctxInfo *global.ContextInfo) []string {
+	for _, header := range ctxInfo.MetricHeaders.Request() {
+		names = append(names, httpRequestHeaderPrefix+headerLabelName(header))
+	}
+	for _, header := range ctxInfo.MetricHeaders.Response() {
+		names = append(names, httpResponseHeaderPrefix+headerLabelName(header))
+	}
+	return names
+}
+
+// headerLabelName replaces all the characters of the header name that are not valid in a label name
+func headerLabelName(header string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, header)
+}
+
+// appendHeaderLabelValues must return the label values in the same order as would be returned
+// by appendHeaderLabelNames
+func (r *metricsReporter) appendHeaderLabelValues(values []string, span *request.Span) []string {
+	headers := r.ctxInfo.MetricHeaders
+	for _, header := range headers.Request() {
+		values = append(values, headers.RequestValue(span, header))
+	}
+	for _, header := range headers.Response() {
+		values = append(values, headers.ResponseValue(span, header))
+	}
 	return values
 }
 
@@ -51,14 +51,16 @@ var structMembers = map[string]structInfo{
 	"net/http.response": {
 		lib: "go",
 		fields: map[string]string{
-			"status": "status_ptr_pos",
-			"req":    "resp_req_pos",
+			"status":        "status_ptr_pos",
+			"req":           "resp_req_pos",
+			"handlerHeader": "resp_handler_header_ptr_pos",
 		},
 	},
 	"net/http.Response": {
 		lib: "go",
 		fields: map[string]string{
 			"StatusCode": "status_code_ptr_pos",
+			"Header":     "resp_header_ptr_pos",
 		},
 	},
 	"google.golang.org/grpc/internal/transport.Stream": {
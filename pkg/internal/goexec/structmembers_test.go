@@ -69,15 +69,17 @@ func TestGoOffsetsFromDwarf(t *testing.T) {
 	offsets, _ := structMemberOffsetsFromDwarf(debugData)
 	// this test might fail if a future Go version updates the internal structure of the used structs.
 	mustMatch(t, FieldOffsets{
-		"url_ptr_pos":           uint64(16),
-		"path_ptr_pos":          uint64(56),
-		"remoteaddr_ptr_pos":    uint64(176),
-		"host_ptr_pos":          uint64(128),
-		"method_ptr_pos":        uint64(0),
-		"status_ptr_pos":        uint64(120),
-		"tcp_addr_ip_ptr_pos":   uint64(0),
-		"tcp_addr_port_ptr_pos": uint64(24),
-		"resp_req_pos":          uint64(8),
+		"url_ptr_pos":                 uint64(16),
+		"path_ptr_pos":                uint64(56),
+		"remoteaddr_ptr_pos":          uint64(176),
+		"host_ptr_pos":                uint64(128),
+		"method_ptr_pos":              uint64(0),
+		"status_ptr_pos":              uint64(120),
+		"tcp_addr_ip_ptr_pos":         uint64(0),
+		"tcp_addr_port_ptr_pos":       uint64(24),
+		"resp_req_pos":                uint64(8),
+		"resp_handler_header_ptr_pos": uint64(88),
+		"resp_header_ptr_pos":         uint64(56),
 	}, offsets)
 }
 
@@ -101,12 +103,14 @@ func TestGoOffsetsWithoutDwarf(t *testing.T) {
 	require.NoError(t, err)
 	// this test might fail if a future Go version updates the internal structure of the used structs.
 	mustMatch(t, FieldOffsets{
-		"url_ptr_pos":        uint64(16),
-		"path_ptr_pos":       uint64(56),
-		"remoteaddr_ptr_pos": uint64(176),
-		"host_ptr_pos":       uint64(128),
-		"method_ptr_pos":     uint64(0),
-		"status_ptr_pos":     uint64(120),
+		"url_ptr_pos":                 uint64(16),
+		"path_ptr_pos":                uint64(56),
+		"remoteaddr_ptr_pos":          uint64(176),
+		"host_ptr_pos":                uint64(128),
+		"method_ptr_pos":              uint64(0),
+		"status_ptr_pos":              uint64(120),
+		"resp_handler_header_ptr_pos": uint64(88),
+		"resp_header_ptr_pos":         uint64(56),
 	}, offsets)
 }
 
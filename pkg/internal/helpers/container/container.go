@@ -36,6 +36,12 @@ var dockerCgroup = regexp.MustCompile(`^\d+:.*:.*/.*-([\da-fA-F]+)\.scope`)
 // Containerd: /kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod7260904bbd08e72e4dff95d9fccd2ee8.slice/cri-containerd-d36686f9785534531160dc936aec9d711a26eb37f4fc7752a2ae27d0a24345c1.scope
 var k8sCgroup = regexp.MustCompile(`^\d+:.*:/kubepods.*([0-9a-f]{64})`)
 
+// A cgroup entry of a container that is managed by the cgroupfs driver is a string like:
+// cgroup v1: 4:pids:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
+// cgroup v2: 0::/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
+// where the parent folder is the container runtime (docker) or the containerd namespace (e.g. default)
+var runtimeCgroup = regexp.MustCompile(`^\d+:[^:]*:/[^/]+/([\da-fA-F]{64})$`)
+
 // InfoForPID returns the container ID and PID namespace for the given PID.
 func InfoForPID(pid uint32) (Info, error) {
 	ns, err := namespaceFinder(int32(pid))
@@ -47,21 +53,16 @@ func InfoForPID(pid uint32) (Info, error) {
 	if err != nil {
 		return Info{}, fmt.Errorf("reading %s: %w", cgroupFile, err)
 	}
-	// We look for the docker cgroup entry first, as it's the most common
-	for _, cgroupEntry := range bytes.Split(cgroupBytes, []byte{'\n'}) {
-		submatches := dockerCgroup.FindSubmatch(cgroupEntry)
-		if len(submatches) < 2 {
-			continue
-		}
-		return Info{PIDNamespace: ns, ContainerID: string(submatches[1])}, nil
-	}
-	// If we didn't find a docker entry, we look for a k8s entry
-	for _, cgroupEntry := range bytes.Split(cgroupBytes, []byte{'\n'}) {
-		submatches := k8sCgroup.FindSubmatch(cgroupEntry)
-		if len(submatches) < 2 {
-			continue
+	// We look for the docker cgroup entry first, as it's the most common. If we didn't find a docker
+	// entry, we look for a k8s entry and, last, for a container that is managed by the cgroupfs driver
+	for _, cgroup := range []*regexp.Regexp{dockerCgroup, k8sCgroup, runtimeCgroup} {
+		for _, cgroupEntry := range bytes.Split(cgroupBytes, []byte{'\n'}) {
+			submatches := cgroup.FindSubmatch(cgroupEntry)
+			if len(submatches) < 2 {
+				continue
+			}
+			return Info{PIDNamespace: ns, ContainerID: string(submatches[1])}, nil
 		}
-		return Info{PIDNamespace: ns, ContainerID: string(submatches[1])}, nil
 	}
 	return Info{}, fmt.Errorf("%s: couldn't find any docker entry for process with PID %d", cgroupFile, pid)
 }
@@ -42,19 +42,11 @@ var fixturesWithContainer = map[uint32]string{
 }
 
 var fixturesWithoutContainer = map[uint32]string{
-	1011: `12:rdma:/
+	1011: `0::/system.slice/containerd.service`,
+	1012: `12:rdma:/
 11:perf_event:
-10:freezer:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-9:memory:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-8:cpuset:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-7:devices:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-6:net_cls,net_prio:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-5:hugetlb:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-4:pids:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-3:cpu,cpuacct:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-2:blkio:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-1:name=systemd:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
-0::/system.slice/containerd.service`,
+1:name=systemd:/init.scope
+0::/init.scope`,
 }
 
 func mountFixtures(t *testing.T) string {
@@ -91,5 +83,44 @@ func TestContainerID(t *testing.T) {
 
 	_, err := InfoForPID(12345)
 	require.Error(t, err)
+}
 
+func TestContainerID_CgroupFS(t *testing.T) {
+	namespaceFinder = func(_ int32) (uint32, error) { return 0, nil }
+	for _, tc := range []struct {
+		name        string
+		cgroup      string
+		containerID string
+	}{{
+		name: "docker cgroup v1",
+		cgroup: `12:rdma:/
+11:perf_event:
+10:freezer:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
+9:memory:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
+1:name=systemd:/docker/a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81
+0::/system.slice/containerd.service`,
+		containerID: "a2ffe0e97ac22657a2a023ad628e9df837c38a03b1ebc904d3f6d644eb1a1a81",
+	}, {
+		name:        "docker cgroup v2",
+		cgroup:      `0::/docker/8afe480d66074930353da456a1344caca810fe31c1e31f6e08c95a66887235d6`,
+		containerID: "8afe480d66074930353da456a1344caca810fe31c1e31f6e08c95a66887235d6",
+	}, {
+		name:        "containerd namespace",
+		cgroup:      `0::/default/40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9`,
+		containerID: "40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9",
+	}, {
+		name:        "docker systemd driver",
+		cgroup:      `0::/system.slice/docker-40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9.scope`,
+		containerID: "40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			procRoot = t.TempDir() + "/"
+			require.NoError(t, os.Mkdir(procRoot+"1", 0777))
+			require.NoError(t, os.WriteFile(procRoot+"1/cgroup", []byte(tc.cgroup), 0666))
+
+			info, err := InfoForPID(1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.containerID, info.ContainerID)
+		})
+	}
 }
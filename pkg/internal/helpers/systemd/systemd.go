@@ -0,0 +1,60 @@
+// Package systemd provides helper tools to inspect the systemd units of the processes
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	serviceSuffix     = ".service"
+	userManagerPrefix = "user@"
+)
+
+// injectable value for testing
+var procRoot = "/proc/"
+
+// UnitForPID returns the name of the systemd service unit (e.g. nginx.service) that
+// contains the given PID, according to its cgroup. It returns an empty string if the
+// process does not belong to any systemd service unit.
+func UnitForPID(pid uint32) (string, error) {
+	cgroupFile := procRoot + strconv.Itoa(int(pid)) + "/cgroup"
+	cgroupBytes, err := os.ReadFile(cgroupFile)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", cgroupFile, err)
+	}
+	return unitFromCgroup(cgroupBytes), nil
+}
+
+// unitFromCgroup looks for the deepest *.service folder in the systemd hierarchy of the
+// cgroup file, which is either the unified (cgroup v2) hierarchy or the name=systemd
+// hierarchy in cgroup v1. For example:
+// 0::/system.slice/nginx.service
+// 1:name=systemd:/user.slice/user-1000.slice/user@1000.service/app.slice/backend.service
+func unitFromCgroup(cgroup []byte) string {
+	for _, entry := range bytes.Split(cgroup, []byte{'\n'}) {
+		// format: hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(string(entry), ":", 3)
+		if len(fields) < 3 || (fields[1] != "" && fields[1] != "name=systemd") {
+			continue
+		}
+		folders := strings.Split(fields[2], "/")
+		for i := len(folders) - 1; i >= 0; i-- {
+			// the user@<uid>.service units contain all the processes of the user manager, so they
+			// are not considered as the service unit of the process
+			if len(folders[i]) > len(serviceSuffix) && strings.HasSuffix(folders[i], serviceSuffix) &&
+				!strings.HasPrefix(folders[i], userManagerPrefix) {
+				return folders[i]
+			}
+		}
+	}
+	return ""
+}
+
+// ServiceName returns the unit name without the .service suffix
+func ServiceName(unit string) string {
+	return strings.TrimSuffix(unit, serviceSuffix)
+}
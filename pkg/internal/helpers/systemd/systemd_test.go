@@ -0,0 +1,63 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitFromCgroup(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cgroup string
+		unit   string
+	}{{
+		name:   "cgroup v2",
+		cgroup: "0::/system.slice/nginx.service\n",
+		unit:   "nginx.service",
+	}, {
+		name:   "user service",
+		cgroup: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/backend.service",
+		unit:   "backend.service",
+	}, {
+		name: "cgroup v1",
+		cgroup: `12:pids:/system.slice/other.service
+11:memory:/system.slice/other.service
+1:name=systemd:/system.slice/postgresql.service`,
+		unit: "postgresql.service",
+	}, {
+		name:   "docker container",
+		cgroup: "0::/system.slice/docker-40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9.scope",
+		unit:   "",
+	}, {
+		name:   "application scope in user manager",
+		cgroup: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-firefox.scope",
+		unit:   "",
+	}, {
+		name:   "user session",
+		cgroup: "0::/user.slice/user-1000.slice/session-2.scope",
+		unit:   "",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.unit, unitFromCgroup([]byte(tc.cgroup)))
+		})
+	}
+}
+
+func TestUnitForPID(t *testing.T) {
+	procRoot = t.TempDir() + "/"
+	require.NoError(t, os.Mkdir(filepath.Join(procRoot, "123"), 0o777))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "123", "cgroup"),
+		[]byte("0::/system.slice/nginx.service"), 0o666))
+
+	unit, err := UnitForPID(123)
+	require.NoError(t, err)
+	assert.Equal(t, "nginx.service", unit)
+	assert.Equal(t, "nginx", ServiceName(unit))
+
+	_, err = UnitForPID(456)
+	assert.Error(t, err)
+}
@@ -3,11 +3,25 @@ package imetrics
 
 import (
 	"context"
+	"time"
 )
 
 // Config options for the different metrics exporters
 type Config struct {
 	Prometheus PrometheusConfig `yaml:"prometheus,omitempty"`
+	OTEL       OTELConfig       `yaml:"otel,omitempty"`
+}
+
+// OTELConfig enables pushing the internal metrics to the endpoint of the OTEL metrics exporter
+type OTELConfig struct {
+	Enabled bool `yaml:"enabled" env:"BEYLA_INTERNAL_METRICS_OTEL_ENABLED"`
+	// Interval between exports. If unset, the interval of the OTEL metrics exporter is used
+	Interval time.Duration `yaml:"interval" env:"BEYLA_INTERNAL_METRICS_OTEL_INTERVAL"`
+}
+
+// Enabled returns whether any internal metrics exporter is enabled
+func (c *Config) Enabled() bool {
+	return c.Prometheus.Port != 0 || c.OTEL.Enabled
 }
 
 // Reporter of internal metrics
@@ -28,15 +42,34 @@ type Reporter interface {
 	OTELTraceExportError(err error)
 	// PrometheusRequest is invoked every time the Prometheus exporter is invoked, for a given port and path
 	PrometheusRequest(port, path string)
+	// RingbufReserveFailures is invoked periodically with the number of events that the eBPF programs
+	// discarded since the last invocation, because there wasn't space left in the ring buffer.
+	RingbufReserveFailures(count int)
+	// FilteredSpans is invoked every time the eBPF tracer discards spans from processes that don't match
+	// the discovery criteria. It accounts the number of discarded spans.
+	FilteredSpans(count int)
+	// TracerRecordError is invoked every time the eBPF tracer can't parse a record from the ring buffer
+	TracerRecordError()
+	// TracerChannelFill is invoked every time an eBPF ring buffer forwarder flushes a group of traces, with
+	// the name of the forwarder and the length and the capacity of its channel towards the next pipeline stage
+	TracerChannelFill(tracer string, length, capacity int)
+	// BPFMapEntries is invoked periodically with the number of entries of a pinned eBPF map, as well as its
+	// maximum number of entries
+	BPFMapEntries(mapName string, entries, maxEntries int)
 }
 
 // NoopReporter is a metrics Reporter that just does nothing
 type NoopReporter struct{}
 
-func (n NoopReporter) Start(_ context.Context)       {}
-func (n NoopReporter) TracerFlush(_ int)             {}
-func (n NoopReporter) OTELMetricExport(_ int)        {}
-func (n NoopReporter) OTELMetricExportError(_ error) {}
-func (n NoopReporter) OTELTraceExport(_ int)         {}
-func (n NoopReporter) OTELTraceExportError(_ error)  {}
-func (n NoopReporter) PrometheusRequest(_, _ string) {}
+func (n NoopReporter) Start(_ context.Context)              {}
+func (n NoopReporter) TracerFlush(_ int)                    {}
+func (n NoopReporter) OTELMetricExport(_ int)               {}
+func (n NoopReporter) OTELMetricExportError(_ error)        {}
+func (n NoopReporter) OTELTraceExport(_ int)                {}
+func (n NoopReporter) OTELTraceExportError(_ error)         {}
+func (n NoopReporter) PrometheusRequest(_, _ string)        {}
+func (n NoopReporter) RingbufReserveFailures(_ int)         {}
+func (n NoopReporter) FilteredSpans(_ int)                  {}
+func (n NoopReporter) TracerRecordError()                   {}
+func (n NoopReporter) TracerChannelFill(_ string, _, _ int) {}
+func (n NoopReporter) BPFMapEntries(_ string, _, _ int)     {}
@@ -28,6 +28,12 @@ type PrometheusReporter struct {
 	otelTraceExports     prometheus.Counter
 	otelTraceExportErrs  *prometheus.CounterVec
 	prometheusRequests   *prometheus.CounterVec
+	ringbufReserveFails  prometheus.Counter
+	filteredSpans        prometheus.Counter
+	tracerRecordErrors   prometheus.Counter
+	tracerChannelFill    *prometheus.GaugeVec
+	bpfMapEntries        *prometheus.GaugeVec
+	bpfMapMaxEntries     *prometheus.GaugeVec
 }
 
 func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusManager) *PrometheusReporter {
@@ -61,6 +67,30 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 			Name: "prometheus_http_requests",
 			Help: "requests towards the Prometheus Scrape endpoint",
 		}, []string{"port", "path"}),
+		ringbufReserveFails: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ebpf_ringbuf_reserve_failures",
+			Help: "events discarded by the eBPF programs because there wasn't space left in the ring buffer",
+		}),
+		filteredSpans: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ebpf_tracer_filtered_spans",
+			Help: "spans discarded by the eBPF tracer because their process doesn't match the discovery criteria",
+		}),
+		tracerRecordErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ebpf_tracer_record_errors",
+			Help: "ring buffer records that couldn't be parsed by the eBPF tracer",
+		}),
+		tracerChannelFill: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ebpf_tracer_channel_fill_ratio",
+			Help: "fill ratio (from 0 to 1) of the channel between the eBPF tracer and the next pipeline stage",
+		}, []string{"tracer"}),
+		bpfMapEntries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ebpf_map_entries",
+			Help: "number of entries of the pinned eBPF maps",
+		}, []string{"map"}),
+		bpfMapMaxEntries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ebpf_map_max_entries",
+			Help: "maximum number of entries of the pinned eBPF maps",
+		}, []string{"map"}),
 	}
 	manager.Register(cfg.Port, cfg.Path,
 		pr.tracerFlushes,
@@ -68,7 +98,13 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 		pr.otelMetricExportErrs,
 		pr.otelTraceExports,
 		pr.otelTraceExportErrs,
-		pr.prometheusRequests)
+		pr.prometheusRequests,
+		pr.ringbufReserveFails,
+		pr.filteredSpans,
+		pr.tracerRecordErrors,
+		pr.tracerChannelFill,
+		pr.bpfMapEntries,
+		pr.bpfMapMaxEntries)
 
 	return pr
 }
@@ -100,3 +136,27 @@ func (p *PrometheusReporter) OTELTraceExportError(err error) {
 func (p *PrometheusReporter) PrometheusRequest(port, path string) {
 	p.prometheusRequests.WithLabelValues(port, path).Inc()
 }
+
+func (p *PrometheusReporter) RingbufReserveFailures(count int) {
+	p.ringbufReserveFails.Add(float64(count))
+}
+
+func (p *PrometheusReporter) FilteredSpans(count int) {
+	p.filteredSpans.Add(float64(count))
+}
+
+func (p *PrometheusReporter) TracerRecordError() {
+	p.tracerRecordErrors.Inc()
+}
+
+func (p *PrometheusReporter) TracerChannelFill(tracer string, length, capacity int) {
+	if capacity == 0 {
+		return
+	}
+	p.tracerChannelFill.WithLabelValues(tracer).Set(float64(length) / float64(capacity))
+}
+
+func (p *PrometheusReporter) BPFMapEntries(mapName string, entries, maxEntries int) {
+	p.bpfMapEntries.WithLabelValues(mapName).Set(float64(entries))
+	p.bpfMapMaxEntries.WithLabelValues(mapName).Set(float64(maxEntries))
+}
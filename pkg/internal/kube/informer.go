@@ -24,6 +24,10 @@ const (
 	syncTime               = 10 * time.Minute
 	IndexPodByContainerIDs = "idx_pod_by_container"
 	IndexReplicaSetNames   = "idx_rs"
+
+	// AnnotationPrefix is the prefix of the Pod annotations that are kept in the informer's cache,
+	// as they are used to drive the Beyla service discovery
+	AnnotationPrefix = "beyla.grafana.com/"
 )
 
 func klog() *slog.Logger {
@@ -148,6 +152,8 @@ func (k *Metadata) initPodInformer(informerFactory informers.SharedInformerFacto
 				Namespace: pod.Namespace,
 				UID:       pod.UID,
 				Labels:    pod.Labels,
+				// to save memory, we don't store annotations that are not relevant to Beyla
+				Annotations: beylaAnnotations(pod.Annotations),
 			},
 			Owner:        owner,
 			NodeName:     pod.Spec.NodeName,
@@ -165,6 +171,19 @@ func (k *Metadata) initPodInformer(informerFactory informers.SharedInformerFacto
 	return nil
 }
 
+func beylaAnnotations(annotations map[string]string) map[string]string {
+	var filtered map[string]string
+	for k, v := range annotations {
+		if strings.HasPrefix(k, AnnotationPrefix) {
+			if filtered == nil {
+				filtered = map[string]string{}
+			}
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
 // initContainerListeners listens for deletions of pods, to forward them to the ContainerEventHandler subscribers.
 func (k *Metadata) initContainerListeners(log *slog.Logger, pods cache.SharedIndexInformer) {
 	if _, err := pods.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -306,6 +325,12 @@ func (k *Metadata) initInformers(client kubernetes.Interface, timeout time.Durat
 	}
 }
 
+// HasSynced returns whether the Pods and ReplicaSets informers have synced their caches
+func (k *Metadata) HasSynced() bool {
+	return k.pods != nil && k.pods.HasSynced() &&
+		k.replicaSets != nil && k.replicaSets.HasSynced()
+}
+
 // FetchPodOwnerInfo updates the pod owner with the Deployment information, if it exists.
 // Pod Info might include a ReplicaSet as owner, and ReplicaSet info
 // usually has a Deployment as owner reference, which is the one that we'd really like
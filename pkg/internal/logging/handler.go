@@ -0,0 +1,204 @@
+// Package logging provides the slog.Handler that is used by Beyla, allowing to choose the output
+// format and to override the log level of each component.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ComponentKey is the attribute that Beyla loggers use to identify the component that
+// emits the logs (e.g. slog.With("component", "discover.ProcessWatcher"))
+const ComponentKey = "component"
+
+// Handler is a slog.Handler that filters the records according to the level of the component
+// that emits them, and forwards them to a text or JSON handler.
+// The format and the levels can be changed at runtime, and the changes are visible from all the
+// loggers that were derived from the same Handler.
+type Handler struct {
+	state *state
+	// component of the logger, as provided in the "component" attribute
+	component string
+	// attributes and groups that have been added to this handler, to be replayed over the
+	// formatting handler each time its format changes
+	ops    []func(slog.Handler) slog.Handler
+	groups int
+	cache  atomic.Pointer[formatted]
+}
+
+// state that is shared by a Handler and all its derived handlers
+type state struct {
+	out    io.Writer
+	format atomic.Pointer[formatter]
+	levels atomic.Pointer[levels]
+}
+
+type formatter struct {
+	handler slog.Handler
+}
+
+// formatted caches the formatting handler of a Handler, with its attributes and groups applied
+type formatted struct {
+	from    *formatter
+	handler slog.Handler
+}
+
+type levels struct {
+	global     slog.Level
+	components map[string]slog.Level
+}
+
+// New returns a Handler writing to the provided output, in text format and INFO level
+func New(out io.Writer) *Handler {
+	h := &Handler{state: &state{out: out}}
+	_ = h.SetFormat(FormatText)
+	h.SetLevels(slog.LevelInfo, nil)
+	return h
+}
+
+// Default returns the Handler of the default slog logger, or nil if the default logger
+// doesn't use a Handler
+func Default() *Handler {
+	h, _ := slog.Default().Handler().(*Handler)
+	return h
+}
+
+// SetFormat changes the output format: "text" or "json"
+func (h *Handler) SetFormat(format string) error {
+	// the level filtering is done by the Handler, so the formatting handler accepts everything
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+	var fh slog.Handler
+	switch format {
+	case FormatText:
+		fh = slog.NewTextHandler(h.state.out, opts)
+	case FormatJSON:
+		fh = slog.NewJSONHandler(h.state.out, opts)
+	default:
+		return fmt.Errorf("unknown log format %q. Valid values are %q and %q", format, FormatText, FormatJSON)
+	}
+	h.state.format.Store(&formatter{handler: fh})
+	return nil
+}
+
+// SetLevels changes the global log level as well as the levels of the components. A component
+// level applies to the component with the same name and to its sub-components, whose name
+// starts with the component name followed by a dot. For example, "discover" applies to
+// "discover.ProcessWatcher" unless "discover.ProcessWatcher" has its own level.
+func (h *Handler) SetLevels(global slog.Level, components map[string]slog.Level) {
+	cl := make(map[string]slog.Level, len(components))
+	for c, l := range components {
+		cl[c] = l
+	}
+	h.state.levels.Store(&levels{global: global, components: cl})
+}
+
+// Levels returns the global log level and a copy of the levels of the components
+func (h *Handler) Levels() (slog.Level, map[string]slog.Level) {
+	lv := h.state.levels.Load()
+	cl := make(map[string]slog.Level, len(lv.components))
+	for c, l := range lv.components {
+		cl[c] = l
+	}
+	return lv.global, cl
+}
+
+func (l *levels) of(component string) slog.Level {
+	for component != "" {
+		if lvl, ok := l.components[component]; ok {
+			return lvl
+		}
+		dot := strings.LastIndexByte(component, '.')
+		if dot < 0 {
+			break
+		}
+		component = component[:dot]
+	}
+	return l.global
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.state.levels.Load().of(h.component)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	return h.formatHandler().Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	dh := h.derive(func(fh slog.Handler) slog.Handler { return fh.WithAttrs(attrs) })
+	// attributes inside a group don't identify the component
+	if h.groups == 0 {
+		for _, a := range attrs {
+			if a.Key == ComponentKey {
+				dh.component = a.Value.String()
+			}
+		}
+	}
+	return dh
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	dh := h.derive(func(fh slog.Handler) slog.Handler { return fh.WithGroup(name) })
+	dh.groups++
+	return dh
+}
+
+func (h *Handler) derive(op func(slog.Handler) slog.Handler) *Handler {
+	ops := make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1)
+	ops = append(ops, h.ops...)
+	return &Handler{
+		state:     h.state,
+		component: h.component,
+		ops:       append(ops, op),
+		groups:    h.groups,
+	}
+}
+
+// formatHandler returns the formatting handler with the attributes and groups of this Handler,
+// rebuilding it if the format changed since the last invocation
+func (h *Handler) formatHandler() slog.Handler {
+	from := h.state.format.Load()
+	if c := h.cache.Load(); c != nil && c.from == from {
+		return c.handler
+	}
+	fh := from.handler
+	for _, op := range h.ops {
+		fh = op(fh)
+	}
+	h.cache.Store(&formatted{from: from, handler: fh})
+	return fh
+}
+
+// ParseLevels parses the global log level and the levels of the components
+func ParseLevels(global string, components map[string]string) (slog.Level, map[string]slog.Level, error) {
+	var gl slog.Level
+	if err := gl.UnmarshalText([]byte(global)); err != nil {
+		return 0, nil, fmt.Errorf("invalid log level %q. Valid values are DEBUG, INFO, WARN and ERROR", global)
+	}
+	cl := make(map[string]slog.Level, len(components))
+	for c, l := range components {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(l)); err != nil {
+			return 0, nil, fmt.Errorf("invalid log level %q for component %q."+
+				" Valid values are DEBUG, INFO, WARN and ERROR", l, c)
+		}
+		cl[c] = lvl
+	}
+	return gl, cl, nil
+}
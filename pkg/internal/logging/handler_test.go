@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ComponentLevels(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := New(out)
+	handler.SetLevels(slog.LevelWarn, map[string]slog.Level{
+		"discover":                slog.LevelInfo,
+		"discover.ProcessWatcher": slog.LevelDebug,
+	})
+	root := slog.New(handler)
+	watcher := root.With("component", "discover.ProcessWatcher")
+	matcher := root.With("component", "discover.CriteriaMatcher")
+	other := root.With("component", "otel.MetricsReporter")
+	// a "component" attribute inside a group doesn't identify the component
+	grouped := root.WithGroup("grp").With("component", "discover.ProcessWatcher")
+
+	for _, l := range []*slog.Logger{root, watcher, matcher, other, grouped} {
+		l.Debug("debug")
+		l.Info("info")
+		l.Warn("warn")
+	}
+	assert.Equal(t, []string{
+		"level=WARN msg=warn",
+		"level=DEBUG msg=debug component=discover.ProcessWatcher",
+		"level=INFO msg=info component=discover.ProcessWatcher",
+		"level=WARN msg=warn component=discover.ProcessWatcher",
+		"level=INFO msg=info component=discover.CriteriaMatcher",
+		"level=WARN msg=warn component=discover.CriteriaMatcher",
+		"level=WARN msg=warn component=otel.MetricsReporter",
+		"level=WARN msg=warn grp.component=discover.ProcessWatcher",
+	}, withoutTime(out.String()))
+
+	// changing the levels affects the already existing loggers
+	out.Reset()
+	handler.SetLevels(slog.LevelDebug, nil)
+	other.Debug("debug")
+	assert.Equal(t, []string{"level=DEBUG msg=debug component=otel.MetricsReporter"}, withoutTime(out.String()))
+}
+
+func TestHandler_Format(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := New(out)
+	watcher := slog.New(handler).With("component", "discover.ProcessWatcher").WithGroup("proc")
+
+	require.Error(t, handler.SetFormat("logfmt"))
+	// changing the format affects the already existing loggers
+	require.NoError(t, handler.SetFormat(FormatJSON))
+	watcher.Info("new process", "pid", 123)
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "new process", record["msg"])
+	assert.Equal(t, "discover.ProcessWatcher", record["component"])
+	assert.Equal(t, map[string]any{"pid": float64(123)}, record["proc"])
+}
+
+func TestParseLevels(t *testing.T) {
+	global, components, err := ParseLevels("warn", map[string]string{"discover": "DEBUG"})
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, global)
+	assert.Equal(t, map[string]slog.Level{"discover": slog.LevelDebug}, components)
+
+	_, _, err = ParseLevels("verbose", nil)
+	assert.Error(t, err)
+	_, _, err = ParseLevels("INFO", map[string]string{"discover": "trace"})
+	assert.Error(t, err)
+}
+
+// withoutTime returns the text-formatted log lines without the time attribute
+func withoutTime(logs string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		if _, rest, ok := strings.Cut(line, " "); ok && strings.HasPrefix(line, "time=") {
+			line = rest
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/mariomac/pipes/pkg/graph"
 	"github.com/mariomac/pipes/pkg/node"
 
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/export/debug"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
 	"github.com/grafana/beyla/pkg/internal/netolly/export"
 	"github.com/grafana/beyla/pkg/internal/netolly/flow"
@@ -27,7 +29,7 @@ type FlowsPipeline struct {
 	Decorator  `sendTo:"Exporter,Printer"`
 
 	Exporter export.MetricsConfig
-	Printer  export.FlowPrinterEnabled
+	Printer  export.FlowPrinterConfig
 }
 
 type MapTracer struct{}
@@ -95,6 +97,15 @@ func (f *Flows) buildAndStartPipeline(ctx context.Context) (graph.Graph, error)
 			Metrics:           &f.cfg.Metrics,
 			AllowedAttributes: f.cfg.NetworkFlows.AllowedAttributes,
 		},
-		Printer: export.FlowPrinterEnabled(f.cfg.NetworkFlows.Print),
+		Printer: flowPrinterConfig(f.cfg),
 	})
 }
+
+// flowPrinterConfig allows enabling the flows printer at runtime if the admin HTTP API is enabled
+func flowPrinterConfig(cfg *beyla.Config) export.FlowPrinterConfig {
+	pc := export.FlowPrinterConfig{Print: cfg.NetworkFlows.Print}
+	if cfg.Admin.Enabled() {
+		pc.Toggle = debug.FlowsPrinter
+	}
+	return pc
+}
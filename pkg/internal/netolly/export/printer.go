@@ -6,18 +6,33 @@ import (
 
 	"github.com/mariomac/pipes/pkg/node"
 
+	"github.com/grafana/beyla/pkg/internal/export/debug"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
 )
 
-type FlowPrinterEnabled bool
+// FlowPrinterConfig of the node that prints the network flows into the standard output
+type FlowPrinterConfig struct {
+	Print bool
+	// Toggle, if not nil, allows enabling and disabling the printing at runtime. Its initial
+	// value is taken from Print, and the node is instantiated even if Print is false.
+	Toggle *debug.Toggle
+}
 
-func (fpe FlowPrinterEnabled) Enabled() bool {
-	return bool(fpe)
+func (fpc FlowPrinterConfig) Enabled() bool {
+	return fpc.Print || fpc.Toggle != nil
 }
 
-func FlowPrinterProvider(_ FlowPrinterEnabled) (node.TerminalFunc[[]*ebpf.Record], error) {
+func FlowPrinterProvider(cfg FlowPrinterConfig) (node.TerminalFunc[[]*ebpf.Record], error) {
+	toggle := cfg.Toggle
+	if toggle == nil {
+		toggle = &debug.Toggle{}
+	}
+	toggle.Set(cfg.Print)
 	return func(in <-chan []*ebpf.Record) {
 		for flows := range in {
+			if !toggle.On() {
+				continue
+			}
 			for _, flow := range flows {
 				printFlow(flow)
 			}
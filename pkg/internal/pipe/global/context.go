@@ -1,9 +1,11 @@
 package global
 
 import (
+	"github.com/grafana/beyla/pkg/internal/admin"
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	kube2 "github.com/grafana/beyla/pkg/internal/kube"
+	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/transform/kube"
 )
 
@@ -22,4 +24,10 @@ type ContextInfo struct {
 	Metrics imetrics.Reporter
 	// Prometheus connection manager to coordinate metrics exposition from diverse nodes
 	Prometheus *connector.PrometheusManager
+	// AdminRegistry keeps track of the instrumented processes for the admin HTTP API.
+	// It is nil if the admin HTTP API is disabled.
+	AdminRegistry *admin.Registry
+	// MetricHeaders selects the captured HTTP headers that are added as metric labels.
+	// It is nil if no header is added to the metrics.
+	MetricHeaders *request.MetricHeaders
 }
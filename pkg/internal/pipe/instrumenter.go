@@ -21,19 +21,23 @@ import (
 // nodesMap provides the architecture of the whole processing pipeline:
 // each node and which nodes are they connected to
 type nodesMap struct {
-	TracesReader traces.ReadDecorator `sendTo:"Routes"`
+	TracesReader traces.ReadDecorator `sendTo:"Kubernetes"`
+
+	// Kubernetes is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
+	// It runs before the Routes node, as the per-service routes are selected by the final service name.
+	Kubernetes transform.KubernetesDecorator `forwardTo:"Routes"`
 
 	// Routes is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
-	Routes *transform.RoutesConfig `forwardTo:"Kubernetes"`
+	Routes *transform.RoutesConfig `forwardTo:"Filters"`
 
-	// Kubernetes is an optional node. If not set, data will be bypassed to the exporters.
-	Kubernetes transform.KubernetesDecorator `forwardTo:"Metrics,Traces,Prometheus,Printer,Noop,AgentTraces"`
+	// Filters is an optional node. If not set, data will be bypassed to the exporters.
+	Filters transform.FiltersConfig `forwardTo:"Metrics,Traces,Prometheus,Printer,Noop,AgentTraces"`
 
 	AgentTraces beyla.TracesReceiverConfig
 	Metrics     otel.MetricsConfig
 	Traces      otel.TracesConfig
 	Prometheus  prom.PrometheusConfig
-	Printer     debug.PrintEnabled
+	Printer     debug.PrinterConfig
 	Noop        debug.NoopEnabled
 }
 
@@ -42,15 +46,25 @@ func configToNodesMap(cfg *beyla.Config) *nodesMap {
 		TracesReader: traces.ReadDecorator{InstanceID: cfg.Attributes.InstanceID},
 		Routes:       cfg.Routes,
 		Kubernetes:   cfg.Attributes.Kubernetes,
+		Filters:      cfg.Filters,
 		Metrics:      cfg.Metrics,
 		Traces:       cfg.Traces,
 		Prometheus:   cfg.Prometheus,
-		Printer:      cfg.Printer,
+		Printer:      printerConfig(cfg),
 		Noop:         cfg.Noop,
 		AgentTraces:  cfg.TracesReceiver,
 	}
 }
 
+// printerConfig allows enabling the traces printer at runtime if the admin HTTP API is enabled
+func printerConfig(cfg *beyla.Config) debug.PrinterConfig {
+	pc := debug.PrinterConfig{Print: cfg.Printer}
+	if cfg.Admin.Enabled() {
+		pc.Toggle = debug.TracesPrinter
+	}
+	return pc
+}
+
 // builder with injectable instantiators for unit testing
 type graphFunctions struct {
 	ctx context.Context
@@ -90,8 +104,9 @@ func newGraphBuilder(ctx context.Context, config *beyla.Config, ctxInfo *global.
 	// type of each of the "nodesMap" struct fields, and returns the function that represents
 	// each node. Each function will have input and/or output channels.
 	graph.RegisterStart(gnb, gb.readDecoratorProvider)
-	graph.RegisterMiddle(gnb, transform.RoutesProvider)
+	graph.RegisterMiddle(gnb, transform.RoutesProvider(ctxInfo))
 	graph.RegisterMiddle(gnb, transform.KubeDecoratorProvider(ctxInfo))
+	graph.RegisterMiddle(gnb, transform.FiltersProvider)
 	graph.RegisterTerminal(gnb, gb.metricsReporterProvider)
 	graph.RegisterTerminal(gnb, gb.tracesReporterProvider)
 	graph.RegisterTerminal(gnb, gb.prometheusProvider)
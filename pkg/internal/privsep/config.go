@@ -0,0 +1,62 @@
+// Package privsep splits Beyla in two processes with different privileges: a loader process, which
+// discovers the instrumentable processes and loads the eBPF programs, and an exporter process, which
+// reads and exports the eBPF events without requiring any eBPF capability.
+// The loader hands over the file descriptor of the events ring buffer, as well as the PIDs whose events
+// are allowed, to the exporter through a unix socket.
+package privsep
+
+import (
+	"fmt"
+	"path"
+)
+
+const (
+	// ModeDisabled runs all the Beyla components in a single process
+	ModeDisabled = ""
+	// ModeLoader runs the privileged part of Beyla, which loads the eBPF programs
+	ModeLoader = "loader"
+	// ModeExporter runs the unprivileged part of Beyla, which reads and exports the eBPF events
+	ModeExporter = "exporter"
+)
+
+// DefaultSocketName is the name of the unix socket, in the BPF base directory, if the socket
+// path is not set
+const DefaultSocketName = "beyla.sock"
+
+// Config for the privilege separation
+type Config struct {
+	// Mode of the Beyla process: "loader", "exporter" or empty if the privilege separation is disabled.
+	Mode string `yaml:"mode" env:"BEYLA_PRIVILEGE_SEPARATION_MODE"`
+	// SocketPath of the unix socket where the loader hands over the eBPF events to the exporter.
+	// If empty, it is placed into the BPF base directory.
+	SocketPath string `yaml:"socket_path" env:"BEYLA_PRIVILEGE_SEPARATION_SOCKET_PATH"`
+	// SocketOwner is the user ID that the loader sets as owner of the unix socket, which must be
+	// the user ID of the exporter process. If zero, the socket is owned by the user of the loader.
+	SocketOwner int `yaml:"socket_owner" env:"BEYLA_PRIVILEGE_SEPARATION_SOCKET_OWNER"`
+}
+
+// Enabled returns whether the process is either a loader or an exporter
+func (c *Config) Enabled() bool {
+	return c.Mode != ModeDisabled
+}
+
+// Validate the configuration
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeDisabled, ModeLoader, ModeExporter:
+	default:
+		return fmt.Errorf("invalid mode %q. Valid values are %q and %q", c.Mode, ModeLoader, ModeExporter)
+	}
+	if c.SocketOwner < 0 {
+		return fmt.Errorf("invalid socket owner %d", c.SocketOwner)
+	}
+	return nil
+}
+
+// Socket returns the path of the unix socket, given the BPF base directory
+func (c *Config) Socket(bpfBaseDir string) string {
+	if c.SocketPath != "" {
+		return c.SocketPath
+	}
+	return path.Join(bpfBaseDir, DefaultSocketName)
+}
@@ -0,0 +1,130 @@
+package privsep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+)
+
+// connectRetryPeriod is the time between the connection attempts to the loader process
+var connectRetryPeriod = time.Second
+
+// Handover of the events ring buffer from the loader process
+type Handover struct {
+	// RingbufFD is the file descriptor of the events ring buffer. The receiver of the handover
+	// owns it, so it must close it when it is not needed anymore.
+	RingbufFD   int
+	RingbufSize int
+
+	log     *slog.Logger
+	conn    *net.UnixConn
+	decoder *json.Decoder
+}
+
+// Connect to the unix socket of the loader process, and receive the handover of the events ring
+// buffer. If the loader isn't listening yet, it retries until the context is done.
+func Connect(ctx context.Context, socketPath string) (*Handover, error) {
+	log := slog.With("component", "privsep.Exporter")
+	addr := &net.UnixAddr{Name: socketPath, Net: "unix"}
+	var conn *net.UnixConn
+	for waiting := false; ; waiting = true {
+		var err error
+		if conn, err = net.DialUnix("unix", nil, addr); err == nil {
+			break
+		}
+		if !waiting {
+			log.Info("waiting for the loader process", "socket", socketPath, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(connectRetryPeriod):
+		}
+	}
+	h, err := receiveHandover(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	h.log = log
+	log.Info("connected to the loader process", "socket", socketPath)
+	return h, nil
+}
+
+func receiveHandover(conn *net.UnixConn) (*Handover, error) {
+	// the ring buffer file descriptor is received along with the first bytes of the handover
+	buf := make([]byte, 4096)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, fmt.Errorf("receiving handover: %w", err)
+	}
+	fd, err := parseFD(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	// the rest of the handover, as well as the next messages, are decoded from the stream
+	decoder := json.NewDecoder(io.MultiReader(bytes.NewReader(buf[:n]), conn))
+	var msg handover
+	if err := decoder.Decode(&msg); err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("decoding handover: %w", err)
+	}
+	return &Handover{RingbufFD: fd, RingbufSize: msg.RingbufSize, conn: conn, decoder: decoder}, nil
+}
+
+func parseFD(oob []byte) (int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return -1, fmt.Errorf("parsing handover control message: %w", err)
+	}
+	if len(msgs) != 1 {
+		return -1, errors.New("the handover doesn't contain the ring buffer file descriptor")
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return -1, fmt.Errorf("parsing handover file descriptor: %w", err)
+	}
+	if len(fds) != 1 {
+		for _, fd := range fds {
+			_ = syscall.Close(fd)
+		}
+		return -1, fmt.Errorf("expected one file descriptor in the handover. Got %d", len(fds))
+	}
+	return fds[0], nil
+}
+
+// ForwardPIDs receives the PIDs that are allowed by the loader process, and sets them into the
+// provided filter. It returns an error if the connection with the loader is lost, and nil when
+// the context is done.
+func (h *Handover) ForwardPIDs(ctx context.Context, pids *ebpfcommon.PIDsFilter) error {
+	go func() {
+		<-ctx.Done()
+		_ = h.conn.Close()
+	}()
+	for {
+		var msg pidsUpdate
+		if err := h.decoder.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("receiving PIDs from the loader process: %w", err)
+		}
+		h.log.Debug("received allowed PIDs", "len", len(msg.PIDs))
+		pids.SetEntries(msg.PIDs)
+	}
+}
+
+// Close the connection with the loader process. It doesn't close the ring buffer file descriptor.
+func (h *Handover) Close() error {
+	return h.conn.Close()
+}
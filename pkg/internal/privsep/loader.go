@@ -0,0 +1,124 @@
+package privsep
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"syscall"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+)
+
+// handover is the first message that the loader sends to the exporter, along with the file
+// descriptor of the events ring buffer as ancillary data
+type handover struct {
+	RingbufSize int `json:"ringbuf_size"`
+}
+
+// pidsUpdate is sent by the loader after the handover and each time that the allowed PIDs change
+type pidsUpdate struct {
+	PIDs []ebpfcommon.PIDEntry `json:"pids"`
+}
+
+// RingBuffer whose file descriptor is handed over. It is implemented by *ebpf.Map.
+type RingBuffer interface {
+	FD() int
+	MaxEntries() uint32
+}
+
+// Loader hands over the events ring buffer and the allowed PIDs to the exporter process
+// that connects to its unix socket
+type Loader struct {
+	log     *slog.Logger
+	ringbuf RingBuffer
+	pids    *ebpfcommon.PIDsFilter
+}
+
+// NewLoader returns a Loader that hands over the provided ring buffer, as well as the
+// PIDs that are allowed by the provided filter
+func NewLoader(ringbuf RingBuffer, pids *ebpfcommon.PIDsFilter) *Loader {
+	return &Loader{
+		log:     slog.With("component", "privsep.Loader"),
+		ringbuf: ringbuf,
+		pids:    pids,
+	}
+}
+
+// Serve the exporter processes that connect to the unix socket in the provided path, until the
+// context is done. The socket is only accessible by its owner, which is changed to the provided
+// user ID if it is not zero. Only one exporter is served at a time, as the events can't be read
+// concurrently.
+func (l *Loader) Serve(ctx context.Context, socketPath string, owner int) error {
+	// removing the socket of a former loader that didn't exit cleanly
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing former socket: %w", err)
+	}
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return fmt.Errorf("listening on unix socket: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("changing socket permissions: %w", err)
+	}
+	if owner != 0 {
+		if err := os.Chown(socketPath, owner, -1); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("changing socket owner: %w", err)
+		}
+	}
+	l.log.Info("waiting for the exporter process", "socket", socketPath)
+	for {
+		conn, err := listener.AcceptUnix()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accepting connection: %w", err)
+		}
+		l.log.Info("exporter process connected")
+		if err := l.serve(ctx, conn); err != nil {
+			l.log.Warn("exporter process disconnected", "error", err)
+		}
+	}
+}
+
+func (l *Loader) serve(ctx context.Context, conn *net.UnixConn) error {
+	defer conn.Close()
+	msg, err := json.Marshal(handover{RingbufSize: int(l.ringbuf.MaxEntries())})
+	if err != nil {
+		return fmt.Errorf("encoding handover: %w", err)
+	}
+	if _, _, err := conn.WriteMsgUnix(append(msg, '\n'), syscall.UnixRights(l.ringbuf.FD()), nil); err != nil {
+		return fmt.Errorf("sending handover: %w", err)
+	}
+
+	// the exporter doesn't send anything, so reading only returns when the connection is closed
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+	encoder := json.NewEncoder(conn)
+	for {
+		if err := encoder.Encode(pidsUpdate{PIDs: l.pids.Entries()}); err != nil {
+			return fmt.Errorf("sending PIDs: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-closed:
+			return errors.New("connection closed")
+		case <-l.pids.Updated():
+		}
+	}
+}
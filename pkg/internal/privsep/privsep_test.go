@@ -0,0 +1,135 @@
+package privsep
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+const timeout = 5 * time.Second
+
+// fakeRingBuffer hands over the read side of a pipe
+type fakeRingBuffer struct {
+	r *os.File
+}
+
+func (f *fakeRingBuffer) FD() int            { return int(f.r.Fd()) }
+func (f *fakeRingBuffer) MaxEntries() uint32 { return 4096 }
+
+func TestHandover(t *testing.T) {
+	connectRetryPeriod = 10 * time.Millisecond
+	socket := path.Join(t.TempDir(), DefaultSocketName)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	loaderPIDs := ebpfcommon.NewPIDsFilter(slog.Default())
+	loaderPIDs.SetEntries([]ebpfcommon.PIDEntry{
+		{Namespace: 1, PID: 10, Type: ebpfcommon.PIDTypeKProbes, Service: svc.ID{Name: "foo"}},
+	})
+
+	// the exporter waits until the loader starts listening
+	exporterCtx, cancelExporter := context.WithCancel(context.Background())
+	defer cancelExporter()
+	handovers := make(chan *Handover, 1)
+	go func() {
+		h, err := Connect(exporterCtx, socket)
+		assert.NoError(t, err)
+		handovers <- h
+	}()
+
+	loaderCtx, cancelLoader := context.WithCancel(context.Background())
+	defer cancelLoader()
+	go func() {
+		assert.NoError(t, NewLoader(&fakeRingBuffer{r: r}, loaderPIDs).Serve(loaderCtx, socket, 0))
+	}()
+
+	var h *Handover
+	select {
+	case h = <-handovers:
+	case <-time.After(timeout):
+		t.Fatal("timeout while waiting for the handover")
+	}
+	require.NotNil(t, h)
+	assert.Equal(t, 4096, h.RingbufSize)
+
+	// the socket is only accessible by its owner
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the received file descriptor refers to the same pipe
+	received := os.NewFile(uintptr(h.RingbufFD), "received")
+	defer received.Close()
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = received.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	// the allowed PIDs are forwarded since the beginning and after each change
+	exporterPIDs := ebpfcommon.NewPIDsFilter(slog.Default())
+	forwardErr := make(chan error, 1)
+	go func() {
+		forwardErr <- h.ForwardPIDs(exporterCtx, exporterPIDs)
+	}()
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(loaderPIDs.Entries(), exporterPIDs.Entries())
+	}, timeout, 10*time.Millisecond)
+
+	loaderPIDs.SetEntries([]ebpfcommon.PIDEntry{
+		{Namespace: 1, PID: 10, Type: ebpfcommon.PIDTypeKProbes, Service: svc.ID{Name: "foo"}},
+		{Namespace: 2, PID: 20, Type: ebpfcommon.PIDTypeGo, Service: svc.ID{Name: "bar", Namespace: "ns"}},
+	})
+	assert.Eventually(t, func() bool {
+		return len(exporterPIDs.Entries()) == 2
+	}, timeout, 10*time.Millisecond)
+	assert.ElementsMatch(t, loaderPIDs.Entries(), exporterPIDs.Entries())
+
+	// the exporter is notified when the loader exits
+	cancelLoader()
+	select {
+	case err := <-forwardErr:
+		require.Error(t, err)
+	case <-time.After(timeout):
+		t.Fatal("timeout while waiting for the loader disconnection")
+	}
+}
+
+func TestConnect_Cancel(t *testing.T) {
+	connectRetryPeriod = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err := Connect(ctx, path.Join(t.TempDir(), "missing.sock"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConfig(t *testing.T) {
+	cfg := Config{}
+	assert.False(t, cfg.Enabled())
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "/var/run/beyla/beyla.sock", cfg.Socket("/var/run/beyla"))
+
+	cfg = Config{Mode: ModeExporter, SocketPath: "/tmp/foo.sock"}
+	assert.True(t, cfg.Enabled())
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "/tmp/foo.sock", cfg.Socket("/var/run/beyla"))
+
+	cfg = Config{Mode: "privileged"}
+	require.Error(t, cfg.Validate())
+}
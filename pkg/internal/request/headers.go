@@ -0,0 +1,85 @@
+package request
+
+import "sync"
+
+// OtherHeaderValue replaces the values of the headers that exceed the maximum number
+// of distinct values in the metric labels
+const OtherHeaderValue = "other"
+
+// MetricHeaders selects the captured HTTP headers that are added as metric labels, and bounds the
+// number of distinct values that each header reports, to avoid a cardinality explosion.
+// It is shared by all the metrics exporters, so they report the same label values.
+// A nil MetricHeaders doesn't add any header.
+type MetricHeaders struct {
+	request   []string
+	response  []string
+	maxValues int
+
+	mt sync.Mutex
+	// distinct values, accounted separately for each request and response header
+	requestValues  map[string]map[string]struct{}
+	responseValues map[string]map[string]struct{}
+}
+
+// NewMetricHeaders returns nil if there aren't request nor response headers to report.
+func NewMetricHeaders(request, response []string, maxValues int) *MetricHeaders {
+	if len(request) == 0 && len(response) == 0 {
+		return nil
+	}
+	return &MetricHeaders{
+		request:        request,
+		response:       response,
+		maxValues:      maxValues,
+		requestValues:  map[string]map[string]struct{}{},
+		responseValues: map[string]map[string]struct{}{},
+	}
+}
+
+// Request returns the lowercase names of the request headers that are added as metric labels
+func (m *MetricHeaders) Request() []string {
+	if m == nil {
+		return nil
+	}
+	return m.request
+}
+
+// Response returns the lowercase names of the response headers that are added as metric labels
+func (m *MetricHeaders) Response() []string {
+	if m == nil {
+		return nil
+	}
+	return m.response
+}
+
+// RequestValue returns the metric label value of the given request header in the span.
+// Missing headers are reported as an empty value.
+func (m *MetricHeaders) RequestValue(span *Span, name string) string {
+	return m.boundedValue(m.requestValues, name, span.RequestHeaders[name])
+}
+
+// ResponseValue returns the metric label value of the given response header in the span.
+// Missing headers are reported as an empty value.
+func (m *MetricHeaders) ResponseValue(span *Span, name string) string {
+	return m.boundedValue(m.responseValues, name, span.ResponseHeaders[name])
+}
+
+func (m *MetricHeaders) boundedValue(seen map[string]map[string]struct{}, name, value string) string {
+	if value == "" {
+		return ""
+	}
+	m.mt.Lock()
+	defer m.mt.Unlock()
+	values, ok := seen[name]
+	if !ok {
+		values = map[string]struct{}{}
+		seen[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= m.maxValues {
+		return OtherHeaderValue
+	}
+	values[value] = struct{}{}
+	return value
+}
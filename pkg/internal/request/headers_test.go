@@ -0,0 +1,32 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricHeaders(t *testing.T) {
+	mh := NewMetricHeaders([]string{"x-tenant-id"}, []string{"x-tenant-id"}, 2)
+	assert.Equal(t, []string{"x-tenant-id"}, mh.Request())
+	assert.Equal(t, []string{"x-tenant-id"}, mh.Response())
+
+	span := func(tenant string) *Span {
+		return &Span{RequestHeaders: map[string]string{"x-tenant-id": tenant}}
+	}
+	assert.Equal(t, "t1", mh.RequestValue(span("t1"), "x-tenant-id"))
+	assert.Equal(t, "t2", mh.RequestValue(span("t2"), "x-tenant-id"))
+	assert.Equal(t, OtherHeaderValue, mh.RequestValue(span("t3"), "x-tenant-id"))
+	// already reported values are kept
+	assert.Equal(t, "t1", mh.RequestValue(span("t1"), "x-tenant-id"))
+	// missing headers don't count towards the limit
+	assert.Equal(t, "", mh.RequestValue(&Span{}, "x-tenant-id"))
+
+	// the response headers values are accounted separately
+	assert.Equal(t, "t3", mh.ResponseValue(&Span{ResponseHeaders: map[string]string{"x-tenant-id": "t3"}}, "x-tenant-id"))
+
+	// nil MetricHeaders doesn't report any header
+	assert.Nil(t, NewMetricHeaders(nil, nil, 2))
+	assert.Empty(t, (*MetricHeaders)(nil).Request())
+	assert.Empty(t, (*MetricHeaders)(nil).Response())
+}
@@ -69,6 +69,10 @@ type Span struct {
 	ParentSpanID  trace2.SpanID
 	Flags         uint8
 	Pid           PidInfo
+	// RequestHeaders contains the captured HTTP request headers, keyed by their lowercase name
+	RequestHeaders map[string]string
+	// ResponseHeaders contains the captured HTTP response headers, keyed by their lowercase name
+	ResponseHeaders map[string]string
 }
 
 func (s *Span) Inside(parent *Span) bool {
@@ -54,12 +54,30 @@ type ID struct {
 	// AutoName is true if the Name has been automatically set by Beyla (e.g. executable name when
 	// the Name is empty). This will allow later refinement of the Name value (e.g. to override it
 	// again with Kubernetes metadata).
-	AutoName    bool
-	Namespace   string
-	SDKLanguage InstrumentableType
-	Instance    string
+	AutoName  bool
+	Namespace string
+	// AutoNamespace is true if the Namespace has been automatically set by Beyla with a value that
+	// can be overridden by the Kubernetes metadata
+	AutoNamespace bool
+	SDKLanguage   InstrumentableType
+	Instance      string
 
 	Metadata map[string]string
+
+	// RouteHints optionally override the routes configuration for this service instance.
+	// Nil if no hints were provided (e.g. by the Kubernetes annotations of its Pod).
+	RouteHints *RouteHints
+
+	// ExporterProfile is the name of the exporter profile that overrides the endpoint and headers
+	// of the OTEL exporters for this service. Empty if the default exporters are used.
+	ExporterProfile string
+}
+
+// RouteHints allow overriding the route patterns and ignored patterns of the global
+// routes configuration for a given service instance
+type RouteHints struct {
+	Patterns       []string
+	IgnorePatterns []string
 }
 
 func (i *ID) String() string {
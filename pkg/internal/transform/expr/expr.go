@@ -0,0 +1,227 @@
+// Package expr provides a small boolean expression language to select request.Span
+// instances by the value of their fields. For example:
+//
+//	type == "http_client" && host == "metadata.google.internal"
+//	type == "grpc" && path == "grpc.health.v1.Health/Check"
+//	status == 404 && (path == "/favicon.ico" || path =~ "^/static/")
+//
+// Supported comparison operators are ==, !=, <, <=, >, >= (for both strings and numbers)
+// and =~, !~ (regular expression matching, only for strings). Comparisons can be combined
+// with the && (and), || (or) and ! (not) logical operators, as well as grouped by parentheses.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// Expr is a compiled expression that can be evaluated against spans
+type Expr struct {
+	src  string
+	eval func(s *request.Span) bool
+}
+
+// Compile parses the provided expression and returns an Expr that can be used to
+// match spans, or an error if the expression is not valid.
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	p := parser{tokens: tokens}
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	if p.peek().typ != tokEOF {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %s", src, p.peek())
+	}
+	return &Expr{src: src, eval: eval}, nil
+}
+
+// Match returns true if the span fulfills the expression
+func (e *Expr) Match(s *request.Span) bool {
+	return e.eval(s)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type evalFunc = func(s *request.Span) bool
+
+// parser implements a recursive descent parser with the following grammar:
+//
+//	or         := and ( "||" and )*
+//	and        := unary ( "&&" unary )*
+//	unary      := "!" unary | primary
+//	primary    := "(" or ")" | comparison
+//	comparison := IDENT OPERATOR ( STRING | NUMBER )
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.typ == tokOperator && t.val == op
+}
+
+func (p *parser) parseOr() (evalFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s *request.Span) bool { return l(s) || right(s) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (evalFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s *request.Span) bool { return l(s) && right(s) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (evalFunc, error) {
+	if p.isOperator("!") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(s *request.Span) bool { return !inner(s) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (evalFunc, error) {
+	if p.peek().typ == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.typ != tokRParen {
+			return nil, fmt.Errorf("expecting ')' but found %s", t)
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (evalFunc, error) {
+	ident := p.next()
+	if ident.typ != tokIdent {
+		return nil, fmt.Errorf("expecting a field name but found %s", ident)
+	}
+	fld, ok := fields[ident.val]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s", ident)
+	}
+	op := p.next()
+	if op.typ != tokOperator || op.val == "&&" || op.val == "||" || op.val == "!" {
+		return nil, fmt.Errorf("expecting a comparison operator but found %s", op)
+	}
+	lit := p.next()
+	switch lit.typ {
+	case tokString:
+		if fld.kind != kindString {
+			return nil, fmt.Errorf("field %s is a %s and can't be compared with a string", ident, fld.kind)
+		}
+		val, err := strconv.Unquote(lit.val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %w", lit, err)
+		}
+		return stringComparison(fld.str, op, val)
+	case tokNumber:
+		if fld.kind != kindInt {
+			return nil, fmt.Errorf("field %s is a %s and can't be compared with a number", ident, fld.kind)
+		}
+		val, err := strconv.ParseInt(lit.val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s: %w", lit, err)
+		}
+		return intComparison(fld.num, op, val)
+	default:
+		return nil, fmt.Errorf("expecting a string or a number but found %s", lit)
+	}
+}
+
+func stringComparison(get func(*request.Span) string, op token, val string) (evalFunc, error) {
+	switch op.val {
+	case "==":
+		return func(s *request.Span) bool { return get(s) == val }, nil
+	case "!=":
+		return func(s *request.Span) bool { return get(s) != val }, nil
+	case "<":
+		return func(s *request.Span) bool { return get(s) < val }, nil
+	case "<=":
+		return func(s *request.Span) bool { return get(s) <= val }, nil
+	case ">":
+		return func(s *request.Span) bool { return get(s) > val }, nil
+	case ">=":
+		return func(s *request.Span) bool { return get(s) >= val }, nil
+	case "=~", "!~":
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", val, err)
+		}
+		if op.val == "!~" {
+			return func(s *request.Span) bool { return !re.MatchString(get(s)) }, nil
+		}
+		return func(s *request.Span) bool { return re.MatchString(get(s)) }, nil
+	}
+	return nil, fmt.Errorf("operator %s can't be applied to strings", op)
+}
+
+func intComparison(get func(*request.Span) int64, op token, val int64) (evalFunc, error) {
+	switch op.val {
+	case "==":
+		return func(s *request.Span) bool { return get(s) == val }, nil
+	case "!=":
+		return func(s *request.Span) bool { return get(s) != val }, nil
+	case "<":
+		return func(s *request.Span) bool { return get(s) < val }, nil
+	case "<=":
+		return func(s *request.Span) bool { return get(s) <= val }, nil
+	case ">":
+		return func(s *request.Span) bool { return get(s) > val }, nil
+	case ">=":
+		return func(s *request.Span) bool { return get(s) >= val }, nil
+	}
+	return nil, fmt.Errorf("operator %s can't be applied to numbers", op)
+}
@@ -0,0 +1,88 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestMatch(t *testing.T) {
+	metadataCall := request.Span{Type: request.EventTypeHTTPClient, Method: "GET",
+		Host: "metadata.google.internal", HostPort: 80, Path: "/computeMetadata/v1", Status: 200}
+	healthCheck := request.Span{Type: request.EventTypeGRPC, Path: "grpc.health.v1.Health/Check",
+		ServiceID: svc.ID{Name: "frontend", Namespace: "shop"}}
+	favicon := request.Span{Type: request.EventTypeHTTP, Method: "GET", Path: "/favicon.ico", Status: 404,
+		RequestStart: 1_000_000, End: 31_000_000}
+
+	type testCase struct {
+		expr    string
+		matches []*request.Span
+	}
+	for _, tc := range []testCase{
+		{expr: `type == "http_client" && host == "metadata.google.internal"`,
+			matches: []*request.Span{&metadataCall}},
+		{expr: `type == "grpc" && path == "grpc.health.v1.Health/Check"`,
+			matches: []*request.Span{&healthCheck}},
+		{expr: `status == 404 && path == "/favicon.ico"`,
+			matches: []*request.Span{&favicon}},
+		{expr: `path =~ "^/(favicon|computeMetadata)"`,
+			matches: []*request.Span{&metadataCall, &favicon}},
+		{expr: `path !~ "^/(favicon|computeMetadata)"`,
+			matches: []*request.Span{&healthCheck}},
+		{expr: `!(type == "http" || type == "http_client")`,
+			matches: []*request.Span{&healthCheck}},
+		{expr: `status >= 200 && status < 300`,
+			matches: []*request.Span{&metadataCall}},
+		{expr: `service.name == "frontend" && service.namespace != "default"`,
+			matches: []*request.Span{&healthCheck}},
+		{expr: `duration_ms > 20`,
+			matches: []*request.Span{&favicon}},
+		{expr: `type == "http" || type == "grpc" && port == 80`,
+			matches: []*request.Span{&favicon}},
+		{expr: `(type == "http" || type == "grpc") && port == 80`},
+		{expr: `method == "GET" && !!(port == 80)`,
+			matches: []*request.Span{&metadataCall}},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Compile(tc.expr)
+			require.NoError(t, err)
+			var matches []*request.Span
+			for _, s := range []*request.Span{&metadataCall, &healthCheck, &favicon} {
+				if e.Match(s) {
+					matches = append(matches, s)
+				}
+			}
+			assert.Equal(t, tc.matches, matches)
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`path`,
+		`path ==`,
+		`path == "/foo`,
+		`unknown == "foo"`,
+		`path == 404`,
+		`status == "404"`,
+		`status =~ "40."`,
+		`path =~ "(unclosed"`,
+		`path == "/foo" &&`,
+		`(path == "/foo"`,
+		`path == "/foo")`,
+		`path == "/foo" path == "/bar"`,
+		`path && "/foo"`,
+		`status == -`,
+		`path # "/foo"`,
+	} {
+		t.Run(src, func(t *testing.T) {
+			_, err := Compile(src)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package expr
+
+import (
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+type fieldKind int
+
+const (
+	kindString = fieldKind(iota)
+	kindInt
+)
+
+func (k fieldKind) String() string {
+	if k == kindInt {
+		return "number"
+	}
+	return "string"
+}
+
+// field describes a request.Span property that can be used in the left side of
+// a comparison. Only one of the str or num accessors is defined, according to the kind
+type field struct {
+	kind fieldKind
+	str  func(s *request.Span) string
+	num  func(s *request.Span) int64
+}
+
+// fields that can be queried from an expression
+var fields = map[string]field{
+	"type":              {kind: kindString, str: func(s *request.Span) string { return EventTypeName(s.Type) }},
+	"method":            {kind: kindString, str: func(s *request.Span) string { return s.Method }},
+	"path":              {kind: kindString, str: func(s *request.Span) string { return s.Path }},
+	"route":             {kind: kindString, str: func(s *request.Span) string { return s.Route }},
+	"peer":              {kind: kindString, str: func(s *request.Span) string { return s.Peer }},
+	"host":              {kind: kindString, str: func(s *request.Span) string { return s.Host }},
+	"service.name":      {kind: kindString, str: func(s *request.Span) string { return s.ServiceID.Name }},
+	"service.namespace": {kind: kindString, str: func(s *request.Span) string { return s.ServiceID.Namespace }},
+	"port":              {kind: kindInt, num: func(s *request.Span) int64 { return int64(s.HostPort) }},
+	"status":            {kind: kindInt, num: func(s *request.Span) int64 { return int64(s.Status) }},
+	"content_length":    {kind: kindInt, num: func(s *request.Span) int64 { return s.ContentLength }},
+	"duration_ms": {kind: kindInt, num: func(s *request.Span) int64 {
+		return time.Duration(s.End - s.RequestStart).Milliseconds()
+	}},
+}
+
+// EventTypeName returns the name of the span type, as it has to be written in the
+// expressions that filter by the "type" field.
+func EventTypeName(t request.EventType) string {
+	switch t {
+	case request.EventTypeHTTP:
+		return "http"
+	case request.EventTypeHTTPClient:
+		return "http_client"
+	case request.EventTypeGRPC:
+		return "grpc"
+	case request.EventTypeGRPCClient:
+		return "grpc_client"
+	case request.EventTypeSQLClient:
+		return "sql_client"
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokEOF = tokenType(iota)
+	tokIdent
+	tokString
+	tokNumber
+	tokOperator
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at position %d", t.val, t.pos)
+}
+
+// operators, sorted so the longest ones are checked first
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!"}
+
+// tokenize splits the expression source into a list of tokens, always terminated
+// by a tokEOF token
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{typ: tokLParen, val: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokRParen, val: ")", pos: i})
+			i++
+		case c == '"':
+			end, err := stringEnd(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokString, val: src[i:end], pos: i})
+			i = end
+		case c >= '0' && c <= '9' || c == '-':
+			end := i + 1
+			for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokNumber, val: src[i:end], pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(src) && isIdentPart(rune(src[end])) {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokIdent, val: src[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{typ: tokOperator, val: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{typ: tokEOF, pos: len(src)}), nil
+}
+
+// stringEnd returns the position after the closing quote of the string literal
+// starting at the given position
+func stringEnd(src string, start int) (int, error) {
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isIdentPart(c rune) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,88 @@
+package transform
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mariomac/pipes/pkg/node"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/transform/expr"
+)
+
+// SpanFilter discards the spans matching a given expression
+type SpanFilter struct {
+	// Match is an expression selecting the spans to discard. Check the expr package
+	// documentation for the syntax and the available fields.
+	Match string `yaml:"match"`
+	// IgnoredEvents specifies whether the matching spans are discarded from metrics, traces or both
+	IgnoredEvents IgnoreMode `yaml:"ignore_mode"`
+}
+
+// FiltersConfig is an optional node that discards spans matching any of its filters.
+type FiltersConfig []SpanFilter
+
+func (fc FiltersConfig) Enabled() bool {
+	return len(fc) > 0
+}
+
+type compiledFilter struct {
+	expr *expr.Expr
+	mode IgnoreMode
+}
+
+func FiltersProvider(fc FiltersConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	filters := make([]compiledFilter, 0, len(fc))
+	for i := range fc {
+		e, err := expr.Compile(fc[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("filter[%d]: %w", i, err)
+		}
+		mode := fc[i].IgnoredEvents
+		switch mode {
+		case "":
+			mode = IgnoreDefault
+		case IgnoreAll, IgnoreMetrics, IgnoreTraces:
+		default:
+			return nil, fmt.Errorf("filter[%d]: invalid ignore_mode %q", i, mode)
+		}
+		filters = append(filters, compiledFilter{expr: e, mode: mode})
+	}
+	log := slog.With("component", "transform.Filters")
+
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		log.Debug("starting span filters node", "filters", len(filters))
+		for spans := range in {
+			filtered := make([]request.Span, 0, len(spans))
+			for i := range spans {
+				if !applyFilters(filters, &spans[i]) {
+					filtered = append(filtered, spans[i])
+				}
+			}
+			if len(filtered) > 0 {
+				out <- filtered
+			}
+		}
+	}, nil
+}
+
+// applyFilters marks the span as ignored for metrics or traces according to the
+// matching filters, and returns true if the span has to be discarded completely
+func applyFilters(filters []compiledFilter, s *request.Span) bool {
+	for i := range filters {
+		if !filters[i].expr.Match(s) {
+			continue
+		}
+		if filters[i].mode == IgnoreAll {
+			return true
+		}
+		previous := s.IgnoreSpan
+		setSpanIgnoreMode(filters[i].mode, s)
+		// a span that was already ignored for the other kind of events (e.g. by the
+		// ignored routes or a previous filter) has nowhere to go
+		if previous != 0 && previous != s.IgnoreSpan {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+func TestFilters(t *testing.T) {
+	filter, err := FiltersProvider(FiltersConfig{
+		{Match: `type == "http_client" && host == "metadata.google.internal"`},
+		{Match: `type == "grpc" && path == "grpc.health.v1.Health/Check"`, IgnoredEvents: IgnoreTraces},
+		{Match: `status == 404 && path == "/favicon.ico"`, IgnoredEvents: IgnoreMetrics},
+		{Match: `path == "/favicon.ico"`, IgnoredEvents: IgnoreTraces},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go filter(in, out)
+
+	in <- []request.Span{
+		{Type: request.EventTypeHTTPClient, Host: "metadata.google.internal", Path: "/computeMetadata/v1"},
+		{Type: request.EventTypeHTTPClient, Host: "backend", Path: "/computeMetadata/v1"},
+		{Type: request.EventTypeGRPC, Path: "grpc.health.v1.Health/Check"},
+		{Type: request.EventTypeHTTP, Path: "/favicon.ico", Status: 404},
+		{Type: request.EventTypeHTTP, Path: "/favicon.ico", Status: 200},
+	}
+	assert.Equal(t, []request.Span{
+		{Type: request.EventTypeHTTPClient, Host: "backend", Path: "/computeMetadata/v1"},
+		{Type: request.EventTypeGRPC, Path: "grpc.health.v1.Health/Check", IgnoreSpan: request.IgnoreTraces},
+		{Type: request.EventTypeHTTP, Path: "/favicon.ico", Status: 200, IgnoreSpan: request.IgnoreTraces},
+	}, testutil.ReadChannel(t, out, testTimeout))
+
+	// batches whose spans are all discarded are not forwarded
+	in <- []request.Span{{Type: request.EventTypeHTTPClient, Host: "metadata.google.internal"}}
+	in <- []request.Span{{Type: request.EventTypeHTTP, Path: "/other"}}
+	assert.Equal(t, []request.Span{{Type: request.EventTypeHTTP, Path: "/other"}},
+		testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestFilters_PreviouslyIgnored(t *testing.T) {
+	filter, err := FiltersProvider(FiltersConfig{{Match: `path == "/health"`, IgnoredEvents: IgnoreMetrics}})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go filter(in, out)
+
+	// the span was previously ignored for traces by the routes node
+	in <- []request.Span{
+		{Path: "/health", IgnoreSpan: request.IgnoreTraces},
+		{Path: "/health", IgnoreSpan: request.IgnoreMetrics},
+	}
+	assert.Equal(t, []request.Span{{Path: "/health", IgnoreSpan: request.IgnoreMetrics}},
+		testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestFilters_Errors(t *testing.T) {
+	_, err := FiltersProvider(FiltersConfig{{Match: `path = "/foo"`}})
+	assert.Error(t, err)
+	_, err = FiltersProvider(FiltersConfig{{Match: `path == "/foo"`, IgnoredEvents: "logs"}})
+	assert.Error(t, err)
+}
@@ -99,7 +99,7 @@ func (md *metadataDecorator) nodeLoop(in <-chan []request.Span, out chan<- []req
 func (md *metadataDecorator) do(span *request.Span) {
 	if podInfo, ok := md.db.OwnerPodInfo(span.Pid.Namespace); ok {
 		appendMetadata(span, podInfo)
-	} else {
+	} else if span.ServiceID.Metadata == nil {
 		// do not leave the service attributes map as nil
 		span.ServiceID.Metadata = map[string]string{}
 	}
@@ -121,23 +121,26 @@ func appendMetadata(span *request.Span, info *kube.PodInfo) {
 			span.ServiceID.Name = info.Name
 		}
 	}
-	if span.ServiceID.Namespace == "" {
+	if span.ServiceID.Namespace == "" || span.ServiceID.AutoNamespace {
 		span.ServiceID.Namespace = info.Namespace
 	}
 	span.ServiceID.UID = svc.UID(info.UID)
 
-	// if, in the future, other pipeline steps modify the service metadata, we should
-	// replace the map literal by individual entry insertions
-	span.ServiceID.Metadata = map[string]string{
-		kube.NamespaceName: info.Namespace,
-		kube.PodName:       info.Name,
-		kube.NodeName:      info.NodeName,
-		kube.PodUID:        string(info.UID),
-		kube.PodStartTime:  info.StartTimeStr,
+	// the original metadata map (e.g. resource attributes from the process environment)
+	// is shared by all the spans of the same service, so we create a new one
+	metadata := make(map[string]string, len(span.ServiceID.Metadata)+9)
+	for k, v := range span.ServiceID.Metadata {
+		metadata[k] = v
 	}
+	metadata[kube.NamespaceName] = info.Namespace
+	metadata[kube.PodName] = info.Name
+	metadata[kube.NodeName] = info.NodeName
+	metadata[kube.PodUID] = string(info.UID)
+	metadata[kube.PodStartTime] = info.StartTimeStr
 	owner := info.Owner
 	for owner != nil {
-		span.ServiceID.Metadata[owner.Type.LabelName()] = owner.Name
+		metadata[owner.Type.LabelName()] = owner.Name
 		owner = owner.Owner
 	}
+	span.ServiceID.Metadata = metadata
 }
@@ -124,6 +124,29 @@ func TestDecoration(t *testing.T) {
 			"k8s.pod.start_time":  "2020-01-02 12:12:56",
 		}, deco[0].ServiceID.Metadata)
 	})
+	t.Run("automatically set namespaces and previous metadata are kept", func(t *testing.T) {
+		envAttrs := map[string]string{"deployment.environment": "prod"}
+		inputCh <- []request.Span{{
+			Pid: request.PidInfo{Namespace: 12},
+			ServiceID: svc.ID{Name: "from-env", Namespace: "env-ns", AutoName: true, AutoNamespace: true,
+				Metadata: envAttrs},
+		}}
+		deco := testutil.ReadChannel(t, outputhCh, timeout)
+		require.Len(t, deco, 1)
+		assert.Equal(t, "the-ns", deco[0].ServiceID.Namespace)
+		assert.Equal(t, "deployment-12", deco[0].ServiceID.Name)
+		assert.Equal(t, map[string]string{
+			"deployment.environment": "prod",
+			"k8s.node.name":          "the-node",
+			"k8s.namespace.name":     "the-ns",
+			"k8s.pod.name":           "pod-12",
+			"k8s.pod.uid":            "uid-12",
+			"k8s.deployment.name":    "deployment-12",
+			"k8s.pod.start_time":     "2020-01-02 12:12:56",
+		}, deco[0].ServiceID.Metadata)
+		// the original metadata map is not modified
+		assert.Equal(t, map[string]string{"deployment.environment": "prod"}, envAttrs)
+	})
 }
 
 type fakeDatabase map[uint32]*kube.PodInfo
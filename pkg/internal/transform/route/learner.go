@@ -0,0 +1,257 @@
+package route
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultMaxSegmentCardinality is the default number of different values that a path folder
+	// can take, for a given position and prefix, before being collapsed into a wildcard
+	DefaultMaxSegmentCardinality = 10
+	// DefaultMaxRoutes is the default number of different routes that can be learned for a service
+	DefaultMaxRoutes = 100
+)
+
+// Kind of the spans whose routes are learned. The routes of the requests that a service
+// serves and the requests that a service sends to other services are learned separately.
+type Kind string
+
+const (
+	KindServer = Kind("server")
+	KindClient = Kind("client")
+)
+
+// Learner clusters URL paths into routes according to the observed traffic. For each
+// service and span kind, it tracks the different values that each path folder takes, given its
+// position and preceding folders. When a position exceeds the maximum cardinality,
+// all its values are collapsed into a '*' wildcard. The first path folder is never
+// collapsed, as it usually identifies the API or resource.
+// The number of different routes that are learned for each service is capped. Once the cap
+// is reached, any path not matching a previously learned route won't be clustered.
+type Learner struct {
+	maxCardinality int
+	maxRoutes      int
+
+	mt       sync.Mutex
+	services map[serviceKind]*learnedService
+}
+
+type serviceKind struct {
+	service string
+	kind    Kind
+}
+
+// LearnedRoutes of a service, for a given span kind
+type LearnedRoutes struct {
+	Service  string   `json:"service"`
+	Kind     Kind     `json:"kind"`
+	Patterns []string `json:"patterns"`
+}
+
+type learnedService struct {
+	root   *learnNode
+	routes int
+}
+
+// learnNode represents a path folder in the tree of learned routes
+type learnNode struct {
+	// children by folder name. Nil if the folder position has been collapsed
+	children map[string]*learnNode
+	// wildcard is set when all the folders in this position have been collapsed
+	wildcard *learnNode
+	// terminal is true if a route ends in this node
+	terminal bool
+}
+
+func newLearnNode() *learnNode {
+	return &learnNode{children: map[string]*learnNode{}}
+}
+
+// NewLearner creates a Learner with the provided maximum cardinality per folder
+// position and maximum routes per service. Zero values are replaced by defaults.
+func NewLearner(maxCardinality, maxRoutes int) *Learner {
+	if maxCardinality <= 0 {
+		maxCardinality = DefaultMaxSegmentCardinality
+	}
+	if maxRoutes <= 0 {
+		maxRoutes = DefaultMaxRoutes
+	}
+	return &Learner{
+		maxCardinality: maxCardinality,
+		maxRoutes:      maxRoutes,
+		services:       map[serviceKind]*learnedService{},
+	}
+}
+
+// Learn updates the learned routes of a service and span kind with the provided path, and returns
+// the route that the path belongs to. It returns an empty string if the path does not match any
+// learned route and it can't be learned because the service reached the maximum number of routes.
+func (l *Learner) Learn(service string, kind Kind, path string) string {
+	folders := tokenize(path)
+	if len(folders) > maxSegments {
+		folders = folders[:maxSegments]
+	}
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	key := serviceKind{service: service, kind: kind}
+	ls, ok := l.services[key]
+	if !ok {
+		ls = &learnedService{root: newLearnNode()}
+		l.services[key] = ls
+	}
+	if r, ok := resolve(ls.root, folders); ok {
+		return r
+	}
+	if ls.routes >= l.maxRoutes {
+		return ""
+	}
+	route, collapsed := l.insert(ls.root, folders)
+	if collapsed {
+		ls.routes = countRoutes(ls.root)
+	} else {
+		ls.routes++
+	}
+	return route
+}
+
+// resolve the route of a path without modifying the tree of learned routes
+func resolve(n *learnNode, folders []string) (string, bool) {
+	route := strings.Builder{}
+	for _, f := range folders {
+		if n.wildcard != nil {
+			n = n.wildcard
+			f = "*"
+		} else if child, ok := n.children[f]; ok {
+			n = child
+		} else {
+			return "", false
+		}
+		route.WriteByte('/')
+		route.WriteString(f)
+	}
+	if !n.terminal {
+		return "", false
+	}
+	if route.Len() == 0 {
+		return "/", true
+	}
+	return route.String(), true
+}
+
+// insert the path into the tree of learned routes, collapsing the folder positions
+// that exceed the maximum cardinality, but the first one. It returns whether any position was collapsed.
+func (l *Learner) insert(n *learnNode, folders []string) (string, bool) {
+	route := strings.Builder{}
+	collapsed := false
+	for i, f := range folders {
+		switch {
+		case n.wildcard != nil:
+			n = n.wildcard
+			f = "*"
+		case n.children[f] != nil:
+			n = n.children[f]
+		case i > 0 && len(n.children) >= l.maxCardinality:
+			// the new folder would exceed the cardinality of this position: collapsing it
+			n.wildcard = newLearnNode()
+			for _, child := range n.children {
+				merge(n.wildcard, child)
+			}
+			n.children = nil
+			n = n.wildcard
+			f = "*"
+			collapsed = true
+		default:
+			child := newLearnNode()
+			n.children[f] = child
+			n = child
+		}
+		route.WriteByte('/')
+		route.WriteString(f)
+	}
+	n.terminal = true
+	if route.Len() == 0 {
+		return "/", collapsed
+	}
+	return route.String(), collapsed
+}
+
+// merge the src subtree into the dst subtree
+func merge(dst, src *learnNode) {
+	dst.terminal = dst.terminal || src.terminal
+	if src.wildcard != nil {
+		if dst.wildcard == nil {
+			dst.wildcard = newLearnNode()
+			for _, child := range dst.children {
+				merge(dst.wildcard, child)
+			}
+			dst.children = nil
+		}
+		merge(dst.wildcard, src.wildcard)
+		return
+	}
+	for name, srcChild := range src.children {
+		if dst.wildcard != nil {
+			merge(dst.wildcard, srcChild)
+			continue
+		}
+		dstChild, ok := dst.children[name]
+		if !ok {
+			dstChild = newLearnNode()
+			dst.children[name] = dstChild
+		}
+		merge(dstChild, srcChild)
+	}
+}
+
+func countRoutes(n *learnNode) int {
+	count := 0
+	if n.terminal {
+		count++
+	}
+	if n.wildcard != nil {
+		count += countRoutes(n.wildcard)
+	}
+	for _, child := range n.children {
+		count += countRoutes(child)
+	}
+	return count
+}
+
+// Patterns returns, for each service and span kind, the sorted list of learned route patterns.
+// The result is sorted by service and kind.
+func (l *Learner) Patterns() []LearnedRoutes {
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	patterns := make([]LearnedRoutes, 0, len(l.services))
+	for key, ls := range l.services {
+		var routes []string
+		collectRoutes(ls.root, "", &routes)
+		sort.Strings(routes)
+		patterns = append(patterns, LearnedRoutes{Service: key.service, Kind: key.kind, Patterns: routes})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Service != patterns[j].Service {
+			return patterns[i].Service < patterns[j].Service
+		}
+		return patterns[i].Kind < patterns[j].Kind
+	})
+	return patterns
+}
+
+func collectRoutes(n *learnNode, prefix string, routes *[]string) {
+	if n.terminal {
+		if prefix == "" {
+			*routes = append(*routes, "/")
+		} else {
+			*routes = append(*routes, prefix)
+		}
+	}
+	if n.wildcard != nil {
+		collectRoutes(n.wildcard, prefix+"/*", routes)
+	}
+	for name, child := range n.children {
+		collectRoutes(child, prefix+"/"+name, routes)
+	}
+}
@@ -0,0 +1,100 @@
+package route
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLearner_CollapsesHighCardinality(t *testing.T) {
+	l := NewLearner(3, 100)
+
+	assert.Equal(t, "/users/a1", l.Learn("svc", KindServer, "/users/a1"))
+	assert.Equal(t, "/users/a1/orders", l.Learn("svc", KindServer, "/users/a1/orders"))
+	assert.Equal(t, "/users/bob", l.Learn("svc", KindServer, "/users/bob"))
+	assert.Equal(t, "/users/x/orders", l.Learn("svc", KindServer, "/users/x/orders"))
+	// the 4th different value in the same position collapses it
+	assert.Equal(t, "/users/*", l.Learn("svc", KindServer, "/users/my-slug"))
+	assert.Equal(t, "/users/*/orders", l.Learn("svc", KindServer, "/users/other-slug/orders"))
+	assert.Equal(t, "/users/*", l.Learn("svc", KindServer, "/users/a1"))
+	// low-cardinality positions are kept
+	assert.Equal(t, "/products", l.Learn("svc", KindServer, "/products"))
+	assert.Equal(t, "/", l.Learn("svc", KindServer, "/"))
+
+	// cardinality is tracked per service
+	assert.Equal(t, "/users/a1", l.Learn("other", KindServer, "/users/a1"))
+
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "other", Kind: KindServer, Patterns: []string{"/users/a1"}},
+		{Service: "svc", Kind: KindServer, Patterns: []string{"/", "/products", "/users/*", "/users/*/orders"}},
+	}, l.Patterns())
+}
+
+func TestLearner_MaxRoutes(t *testing.T) {
+	l := NewLearner(100, 3)
+	assert.Equal(t, "/a", l.Learn("svc", KindServer, "/a"))
+	assert.Equal(t, "/b", l.Learn("svc", KindServer, "/b"))
+	assert.Equal(t, "/c/d", l.Learn("svc", KindServer, "/c/d"))
+	assert.Empty(t, l.Learn("svc", KindServer, "/e"))
+	assert.Empty(t, l.Learn("svc", KindServer, "/c"))
+	// already learned routes are still resolved
+	assert.Equal(t, "/b", l.Learn("svc", KindServer, "/b"))
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "svc", Kind: KindServer, Patterns: []string{"/a", "/b", "/c/d"}},
+	}, l.Patterns())
+}
+
+func TestLearner_CollapseFreesRoutes(t *testing.T) {
+	l := NewLearner(5, 6)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, fmt.Sprintf("/item/%d", i), l.Learn("svc", KindServer, fmt.Sprintf("/item/%d", i)))
+	}
+	assert.Equal(t, "/item/*", l.Learn("svc", KindServer, "/item/5"))
+	// after collapsing, there is only one route, so we can keep learning
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, fmt.Sprintf("/other/%d", i), l.Learn("svc", KindServer, fmt.Sprintf("/other/%d", i)))
+	}
+	assert.Empty(t, l.Learn("svc", KindServer, "/another"))
+}
+
+func TestLearner_MergesSubtrees(t *testing.T) {
+	l := NewLearner(2, 100)
+	l.Learn("svc", KindServer, "/u/1/a/x")
+	l.Learn("svc", KindServer, "/u/2/b")
+	assert.Equal(t, "/u/*/a", l.Learn("svc", KindServer, "/u/3/a"))
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "svc", Kind: KindServer, Patterns: []string{"/u/*/a", "/u/*/a/x", "/u/*/b"}},
+	}, l.Patterns())
+	assert.Equal(t, "/u/*/a/x", l.Learn("svc", KindServer, "/u/47/a/x"))
+}
+
+func TestLearner_NeverCollapsesFirstFolder(t *testing.T) {
+	l := NewLearner(2, 100)
+	assert.Equal(t, "/users", l.Learn("svc", KindServer, "/users"))
+	assert.Equal(t, "/orders", l.Learn("svc", KindServer, "/orders"))
+	assert.Equal(t, "/products/1", l.Learn("svc", KindServer, "/products/1"))
+	assert.Equal(t, "/health", l.Learn("svc", KindServer, "/health"))
+	// the following positions are still collapsed
+	assert.Equal(t, "/products/2", l.Learn("svc", KindServer, "/products/2"))
+	assert.Equal(t, "/products/*", l.Learn("svc", KindServer, "/products/3"))
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "svc", Kind: KindServer, Patterns: []string{"/health", "/orders", "/products/*", "/users"}},
+	}, l.Patterns())
+}
+
+func TestLearner_SeparatesSpanKinds(t *testing.T) {
+	l := NewLearner(2, 100)
+	// the requests towards other services don't increase the cardinality of the served routes
+	assert.Equal(t, "/users/1", l.Learn("svc", KindServer, "/users/1"))
+	assert.Equal(t, "/users/2", l.Learn("svc", KindServer, "/users/2"))
+	assert.Equal(t, "/users/3", l.Learn("svc", KindClient, "/users/3"))
+	assert.Equal(t, "/users/4", l.Learn("svc", KindClient, "/users/4"))
+	assert.Equal(t, "/users/1", l.Learn("svc", KindServer, "/users/1"))
+	assert.Equal(t, "/users/*", l.Learn("svc", KindClient, "/users/5"))
+	assert.Equal(t, "/users/2", l.Learn("svc", KindServer, "/users/2"))
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "svc", Kind: KindClient, Patterns: []string{"/users/*"}},
+		{Service: "svc", Kind: KindServer, Patterns: []string{"/users/1", "/users/2"}},
+	}, l.Patterns())
+}
@@ -2,14 +2,20 @@ package route
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // wildcard format. By now, we will suppport wildcards in the form:
 // - /user/:userId/details (Gin)
-// - /user/{userId}/details (Gorilla)
+// - /user/{userId}/details (Gorilla, OpenAPI)
 // More formats will be appended at some point
-var wildcard = regexp.MustCompile(`^((:\w*)|(\{\w*}))$`)
+var wildcard = regexp.MustCompile(`^((:\w*)|(\{[^/{}]*}))$`)
+
+// partialWildcard matches the OpenAPI path templates where the parameters are only a part of the
+// path folder, e.g. /files/{name}.json or /reports/{year}-{month}. The text around the parameters
+// must match literally, so /files/{name}.json matches /files/report.json but not /files/report.csv.
+var partialWildcard = regexp.MustCompile(`\{[^/{}]*}`)
 
 // Matcher allows matching a given URL path towards a set of framework-like provided
 // patterns.
@@ -31,6 +37,38 @@ type node struct {
 
 	// AnyPath node is a node identified by '*', which terminates the search matching what's found
 	AnyPath *node
+
+	// Partial child subtrees match the path folders that match their template. They are sorted
+	// from the longest to the shortest literal text, so the most specific templates are looked up first.
+	Partial []*partialNode
+}
+
+// partialNode is a child subtree for a path folder where the wildcards are only a part of it
+type partialNode struct {
+	// Template of the path folder, e.g. {name}.json
+	Template string
+	// literal is the length of the text of the template that is not a wildcard
+	literal int
+	folder  *regexp.Regexp
+	*node
+}
+
+func newPartialNode(template string) *partialNode {
+	pn := &partialNode{Template: template, node: &node{Child: map[string]*node{}}}
+	sb := strings.Builder{}
+	sb.WriteByte('^')
+	last := 0
+	for _, loc := range partialWildcard.FindAllStringIndex(template, -1) {
+		sb.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		sb.WriteString("[^/]+")
+		pn.literal += loc[0] - last
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(template[last:]))
+	sb.WriteByte('$')
+	pn.literal += len(template) - last
+	pn.folder = regexp.MustCompile(sb.String())
+	return pn
 }
 
 // NewMatcher creates a new Matcher that would allow validating given URL paths towards
@@ -60,6 +98,15 @@ func find(path []string, pathNode *node) string {
 	if child, ok := pathNode.Child[path[0]]; ok {
 		return find(path[1:], child)
 	}
+	// partial wildcards are more specific than the full-folder wildcards, so they are
+	// looked up first
+	for _, partial := range pathNode.Partial {
+		if partial.folder.MatchString(path[0]) {
+			if route := find(path[1:], partial.node); route != "" {
+				return route
+			}
+		}
+	}
 	if pathNode.AnyPath != nil {
 		return pathNode.FullRoute
 	}
@@ -86,6 +133,10 @@ func appendRoute(fullRoute string, path []string, pathNode *node) {
 		appendRoute(fullRoute, path[1:], pathNode.Wildcard)
 		return
 	}
+	if partialWildcard.MatchString(currentName) {
+		appendRoute(fullRoute, path[1:], pathNode.partialChild(currentName))
+		return
+	}
 
 	if currentName == "*" {
 		pathNode.FullRoute = fullRoute
@@ -103,6 +154,22 @@ func appendRoute(fullRoute string, path []string, pathNode *node) {
 	appendRoute(fullRoute, path[1:], child)
 }
 
+// partialChild returns the partial wildcard subtree for the given path folder template,
+// adding it if it does not yet exist
+func (n *node) partialChild(template string) *node {
+	for _, partial := range n.Partial {
+		if partial.Template == template {
+			return partial.node
+		}
+	}
+	partial := newPartialNode(template)
+	n.Partial = append(n.Partial, partial)
+	sort.SliceStable(n.Partial, func(i, j int) bool {
+		return n.Partial[i].literal > n.Partial[j].literal
+	})
+	return partial.node
+}
+
 // tokenizes and normalizes the resulting slice, so we make sure
 // that neither the first nor last tokens are empty tokens
 func tokenize(path string) []string {
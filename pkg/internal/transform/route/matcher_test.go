@@ -36,3 +36,33 @@ func TestFind(t *testing.T) {
 	assert.Equal(t, "/snow/mobile/*", m.Find("/snow/mobile"))
 	assert.Equal(t, "/snow/mobile/*", m.Find("/snow/mobile/long"))
 }
+
+func TestFind_PathTemplates(t *testing.T) {
+	m := NewMatcher([]string{
+		"/users/{user-id}/orders/{order.id}",
+		"/files/{name}.json",
+		"/files/{name}.xml",
+		"/files/list",
+		"/reports/report-{id}",
+		"/reports/report-{id}.pdf",
+		"/reports/{year}-{month}/summary",
+	})
+
+	assert.Equal(t, "/users/{user-id}/orders/{order.id}", m.Find("/users/1234/orders/5678"))
+	assert.Equal(t, "/files/{name}.json", m.Find("/files/report.json"))
+	assert.Equal(t, "/files/{name}.xml", m.Find("/files/report.xml"))
+	assert.Equal(t, "/files/list", m.Find("/files/list"))
+	// the most specific partial template wins
+	assert.Equal(t, "/reports/report-{id}.pdf", m.Find("/reports/report-12.pdf"))
+	assert.Equal(t, "/reports/report-{id}", m.Find("/reports/report-12.csv"))
+	assert.Equal(t, "/reports/{year}-{month}/summary", m.Find("/reports/2024-01/summary"))
+
+	assert.Empty(t, m.Find("/users/1234/orders"))
+	// the literal parts of the path folder must match
+	assert.Empty(t, m.Find("/files/report.csv"))
+	assert.Empty(t, m.Find("/files/report.json.gz"))
+	assert.Empty(t, m.Find("/reports/summary-12.pdf"))
+	assert.Empty(t, m.Find("/reports/202401/summary"))
+	// the parameter can't be empty
+	assert.Empty(t, m.Find("/files/.json"))
+}
@@ -0,0 +1,86 @@
+package route
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDocument contains the subset of the OpenAPI v2 (Swagger) and v3 documents
+// that are required to extract the route patterns
+type openAPIDocument struct {
+	// Swagger is only set in OpenAPI v2 documents
+	Swagger string `yaml:"swagger"`
+	// OpenAPI is only set in OpenAPI v3 documents
+	OpenAPI string `yaml:"openapi"`
+	// BasePath is only set in OpenAPI v2 documents
+	BasePath string `yaml:"basePath"`
+	// Servers is only set in OpenAPI v3 documents
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]any `yaml:"paths"`
+}
+
+// PatternsFromOpenAPI reads an OpenAPI v2 (Swagger) or v3 document, in YAML or JSON format,
+// and returns the path templates of the documented operations (e.g. /users/{id}),
+// prefixed by the document base path, as route patterns that can be passed to NewMatcher.
+func PatternsFromOpenAPI(file string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading OpenAPI document: %w", err)
+	}
+	patterns, err := parseOpenAPI(content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI document %s: %w", file, err)
+	}
+	return patterns, nil
+}
+
+func parseOpenAPI(content []byte) ([]string, error) {
+	doc := openAPIDocument{}
+	// as YAML is a superset of JSON, the YAML parser is able to parse both formats
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	var basePath string
+	switch {
+	case strings.HasPrefix(doc.Swagger, "2."):
+		basePath = doc.BasePath
+	case strings.HasPrefix(doc.OpenAPI, "3."):
+		basePath = serverBasePath(doc)
+	default:
+		return nil, fmt.Errorf("unsupported document version. Only OpenAPI v2 (swagger: 2.x) and v3 (openapi: 3.x) are supported")
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	patterns := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		// OpenAPI v3 extensions (x-something) can also be defined at the paths level
+		if !strings.HasPrefix(path, "/") {
+			continue
+		}
+		patterns = append(patterns, basePath+path)
+	}
+	// sorting provides a deterministic output, as the YAML unmarshalling into a map doesn't keep the order
+	sort.Strings(patterns)
+	return patterns, nil
+}
+
+// serverBasePath returns the path section of the first server URL in the OpenAPI v3 document.
+// Server URLs can be absolute (http://api.example.com/v1) or relative to the document (/v1).
+// Server URLs with variables are ignored, as we can't know which value the variable takes.
+func serverBasePath(doc openAPIDocument) string {
+	if len(doc.Servers) == 0 || strings.Contains(doc.Servers[0].URL, "{") {
+		return ""
+	}
+	u, err := url.Parse(doc.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
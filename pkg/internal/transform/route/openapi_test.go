@@ -0,0 +1,52 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternsFromOpenAPI_V2(t *testing.T) {
+	patterns, err := PatternsFromOpenAPI("testdata/swagger.json")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/v2/pet", "/v2/pet/{petId}", "/v2/store/order/{orderId}"}, patterns)
+
+	m := NewMatcher(patterns)
+	assert.Equal(t, "/v2/pet/{petId}", m.Find("/v2/pet/123"))
+	assert.Equal(t, "/v2/store/order/{orderId}", m.Find("/v2/store/order/abc"))
+}
+
+func TestPatternsFromOpenAPI_V3(t *testing.T) {
+	patterns, err := PatternsFromOpenAPI("testdata/openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/api/v1/orders",
+		"/api/v1/orders/{id}",
+		"/api/v1/orders/{id}/items/{item-id}",
+	}, patterns)
+
+	m := NewMatcher(patterns)
+	assert.Equal(t, "/api/v1/orders/{id}/items/{item-id}", m.Find("/api/v1/orders/12/items/34"))
+}
+
+func TestParseOpenAPI_ServerVariables(t *testing.T) {
+	patterns, err := parseOpenAPI([]byte(`
+openapi: 3.1.0
+servers:
+  - url: https://{region}.example.com/{version}
+paths:
+  /items: {}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/items"}, patterns)
+}
+
+func TestParseOpenAPI_Errors(t *testing.T) {
+	_, err := PatternsFromOpenAPI("testdata/unexisting.yaml")
+	assert.Error(t, err)
+	_, err = parseOpenAPI([]byte(`{"openapi": "1.0", "paths": {"/foo": {}}}`))
+	assert.Error(t, err)
+	_, err = parseOpenAPI([]byte(`{"openapi":`))
+	assert.Error(t, err)
+}
@@ -2,10 +2,17 @@
 package transform
 
 import (
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/mariomac/pipes/pkg/graph/stage"
 	"github.com/mariomac/pipes/pkg/node"
 
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/transform/route"
 )
@@ -22,6 +29,9 @@ const (
 	UnmatchWildcard = UnmatchType("wildcard")
 	// UnmatchHeuristic detects the route field using a heuristic
 	UnmatchHeuristic = UnmatchType("heuristic")
+	// UnmatchAdaptive detects the route field by learning the cardinality of the path folders
+	// from the observed traffic
+	UnmatchAdaptive = UnmatchType("adaptive")
 
 	UnmatchDefault = UnmatchWildcard
 )
@@ -49,68 +59,311 @@ type RoutesConfig struct {
 	Patterns       []string   `yaml:"patterns"`
 	IgnorePatterns []string   `yaml:"ignored_patterns"`
 	IgnoredEvents  IgnoreMode `yaml:"ignore_mode"`
+	// OpenAPISpecs is a list of OpenAPI v2 (Swagger) or v3 documents. Their path templates
+	// are appended to the Patterns list.
+	OpenAPISpecs []string `yaml:"openapi_specs"`
+	// WarnUnmatched logs a warning for each different HTTP server path that does not match any
+	// of the route patterns. Useful to detect undocumented routes when OpenAPISpecs is set.
+	WarnUnmatched bool `yaml:"warn_unmatched"`
+
+	// Adaptive configures the route learning when Unmatch is set to "adaptive"
+	Adaptive AdaptiveRoutesConfig `yaml:"adaptive"`
+
+	// Services allows overriding the above properties for the spans of a given service.
+	// The spans of the services not listed here will use the above global configuration.
+	Services []ServiceRoutesConfig `yaml:"services"`
+}
+
+// AdaptiveRoutesConfig configures the learning of routes from the observed traffic.
+type AdaptiveRoutesConfig struct {
+	// MaxSegmentCardinality is the number of different values that a path folder can take,
+	// for a given service, position and preceding folders, before being replaced by a '*' wildcard.
+	MaxSegmentCardinality int `yaml:"max_segment_cardinality"`
+	// MaxRoutes is the maximum number of routes that can be learned for each service. When it is reached,
+	// the paths not matching any learned route are reported with the wildcard route.
+	MaxRoutes int `yaml:"max_routes"`
+	// ReportInterval, if set, periodically logs the learned route patterns for each service
+	ReportInterval time.Duration `yaml:"report_interval"`
+}
+
+// ServiceRoutesConfig overrides the global RoutesConfig for the services matching
+// a given name and (optionally) namespace. Any unset property falls back to the global
+// routes configuration.
+type ServiceRoutesConfig struct {
+	// Name of the service, as reported in the metrics and traces
+	Name string `yaml:"name"`
+	// Namespace of the service. If empty, the service is matched by name in any namespace.
+	Namespace string `yaml:"namespace"`
+
+	Unmatch        UnmatchType `yaml:"unmatched"`
+	Patterns       []string    `yaml:"patterns"`
+	IgnorePatterns []string    `yaml:"ignored_patterns"`
+	IgnoredEvents  IgnoreMode  `yaml:"ignore_mode"`
+	OpenAPISpecs   []string    `yaml:"openapi_specs"`
+	WarnUnmatched  bool        `yaml:"warn_unmatched"`
+}
+
+// routeRules contains the route matchers and actions for a given routes configuration
+type routeRules struct {
+	unmatchAction func(span *request.Span)
+	matcher       route.Matcher
+	discarder     route.Matcher
+	routesEnabled bool
+	ignoreEnabled bool
+	ignoreMode    IgnoreMode
+	// unmatched is nil unless the user asked for warning about the unmatched paths
+	unmatched *unmatchedWarner
 }
 
-func RoutesProvider(rc *RoutesConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+func newRouteRules(rc *ServiceRoutesConfig, learner *route.Learner) (*routeRules, error) {
 	// set default value for Unmatch action
-	unmatchAction, err := chooseUnmatchPolicy(rc)
+	unmatchAction, err := chooseUnmatchPolicy(rc.Unmatch, learner)
 	if err != nil {
 		return nil, err
 	}
-	matcher := route.NewMatcher(rc.Patterns)
-	discarder := route.NewMatcher(rc.IgnorePatterns)
-	routesEnabled := len(rc.Patterns) > 0
-	ignoreEnabled := len(rc.IgnorePatterns) > 0
-
 	ignoreMode := rc.IgnoredEvents
 	if ignoreMode == "" {
 		ignoreMode = IgnoreDefault
 	}
+	patterns := rc.Patterns
+	for _, spec := range rc.OpenAPISpecs {
+		specPatterns, err := route.PatternsFromOpenAPI(spec)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(slices.Clip(patterns), specPatterns...)
+	}
+	rules := &routeRules{
+		unmatchAction: unmatchAction,
+		matcher:       route.NewMatcher(patterns),
+		discarder:     route.NewMatcher(rc.IgnorePatterns),
+		routesEnabled: len(patterns) > 0,
+		ignoreEnabled: len(rc.IgnorePatterns) > 0,
+		ignoreMode:    ignoreMode,
+	}
+	if rc.WarnUnmatched {
+		rules.unmatched = newUnmatchedWarner()
+	}
+	return rules, nil
+}
+
+// apply the route rules to the span. It returns false if the span has to be discarded
+func (r *routeRules) apply(s *request.Span) bool {
+	if r.ignoreEnabled {
+		if r.discarder.Find(s.Path) != "" {
+			if r.ignoreMode == IgnoreAll {
+				return false
+			}
+			// we can't discard it here, ignoring is selective (metrics | traces)
+			setSpanIgnoreMode(r.ignoreMode, s)
+		}
+	}
+	if r.routesEnabled {
+		s.Route = r.matcher.Find(s.Path)
+		if s.Route == "" && r.unmatched != nil && s.Type == request.EventTypeHTTP {
+			r.unmatched.warn(s)
+		}
+	}
+	r.unmatchAction(s)
+	return true
+}
+
+// maxHintedRoutes limits the number of different services whose route hints are remembered
+const maxHintedRoutes = 1024
+
+// serviceRoutes selects the route rules for each span according to its service
+type serviceRoutes struct {
+	// learner is shared by all the rules whose unmatch policy is adaptive
+	learner *route.Learner
+	global  *routeRules
+	// per-service rules, keyed by namespace/name. Rules that don't specify a namespace
+	// are keyed by the name only
+	services map[string]*routeRules
+
+	// configurations of the above rules, used as base for the services providing route hints
+	globalConfig   ServiceRoutesConfig
+	servicesConfig map[string]*ServiceRoutesConfig
+	// rules for the services providing route hints, lazily created and keyed by
+	// service and hinted patterns. The least recently used rules are evicted, as the
+	// services (e.g. Kubernetes Pods) come and go.
+	hinted *lru.Cache[string, *routeRules]
+}
+
+func (sr *serviceRoutes) forSpan(s *request.Span) *routeRules {
+	if s.ServiceID.RouteHints != nil {
+		return sr.forHints(s)
+	}
+	return sr.forSpanConfig(s)
+}
+
+// forSpanConfig returns the route rules that are explicitly configured for the service of the span
+func (sr *serviceRoutes) forSpanConfig(s *request.Span) *routeRules {
+	if len(sr.services) == 0 {
+		return sr.global
+	}
+	if rules, ok := sr.services[s.ServiceID.Namespace+"/"+s.ServiceID.Name]; ok {
+		return rules
+	}
+	if rules, ok := sr.services[s.ServiceID.Name]; ok {
+		return rules
+	}
+	return sr.global
+}
+
+// forHints returns the route rules for a service whose route patterns are overridden by
+// the hints of its service ID (e.g. from Kubernetes annotations)
+func (sr *serviceRoutes) forHints(s *request.Span) *routeRules {
+	hints := s.ServiceID.RouteHints
+	key := s.ServiceID.Namespace + "/" + s.ServiceID.Name + "|" +
+		strings.Join(hints.Patterns, ",") + "|" + strings.Join(hints.IgnorePatterns, ",")
+	if rules, ok := sr.hinted.Get(key); ok {
+		return rules
+	}
+	base := &sr.globalConfig
+	if cfg, ok := sr.servicesConfig[s.ServiceID.Namespace+"/"+s.ServiceID.Name]; ok {
+		base = cfg
+	} else if cfg, ok := sr.servicesConfig[s.ServiceID.Name]; ok {
+		base = cfg
+	}
+	merged := *base
+	if len(hints.Patterns) > 0 {
+		merged.Patterns, merged.OpenAPISpecs = hints.Patterns, nil
+	}
+	if len(hints.IgnorePatterns) > 0 {
+		merged.IgnorePatterns = hints.IgnorePatterns
+	}
+	rules, err := newRouteRules(&merged, sr.learner)
+	if err != nil {
+		slog.With("component", "RoutesProvider").
+			Warn("can't apply route hints. Using the configured routes", "service", s.ServiceID.String(), "error", err)
+		rules = sr.forSpanConfig(s)
+	}
+	sr.hinted.Add(key, rules)
+	return rules
+}
+
+func newServiceRoutes(rc *RoutesConfig) (*serviceRoutes, error) {
+	learner := route.NewLearner(rc.Adaptive.MaxSegmentCardinality, rc.Adaptive.MaxRoutes)
+	globalConfig := ServiceRoutesConfig{
+		Unmatch:        rc.Unmatch,
+		Patterns:       rc.Patterns,
+		IgnorePatterns: rc.IgnorePatterns,
+		IgnoredEvents:  rc.IgnoredEvents,
+		OpenAPISpecs:   rc.OpenAPISpecs,
+		WarnUnmatched:  rc.WarnUnmatched,
+	}
+	global, err := newRouteRules(&globalConfig, learner)
+	if err != nil {
+		return nil, err
+	}
+	if len(rc.Patterns) == 0 && len(rc.OpenAPISpecs) == 0 && len(rc.Services) == 0 && (rc.Unmatch == UnmatchWildcard || rc.Unmatch == "") {
+		slog.With("component", "RoutesProvider").
+			Warn("No route match patterns configured. " +
+				"Without route definitions Beyla will not be able to generate a low cardinality " +
+				"route for trace span names. For optimal experience, please define your application " +
+				"HTTP route patterns or enable the route 'heuristic' mode. " +
+				"For more information please see the documentation at: " +
+				"https://grafana.com/docs/beyla/latest/configure/options/#routes-decorator . " +
+				"If your application is only using gRPC you can ignore this warning.")
+	}
+	hinted, _ := lru.New[string, *routeRules](maxHintedRoutes)
+	sr := &serviceRoutes{
+		learner:        learner,
+		global:         global,
+		services:       map[string]*routeRules{},
+		globalConfig:   globalConfig,
+		servicesConfig: map[string]*ServiceRoutesConfig{},
+		hinted:         hinted,
+	}
+	for i := range rc.Services {
+		src := &rc.Services[i]
+		if src.Name == "" {
+			return nil, fmt.Errorf("routes.services[%d]: missing service name", i)
+		}
+		key := src.Name
+		if src.Namespace != "" {
+			key = src.Namespace + "/" + src.Name
+		}
+		if _, ok := sr.services[key]; ok {
+			return nil, fmt.Errorf("routes.services[%d]: service %q is defined multiple times", i, key)
+		}
+		merged := ServiceRoutesConfig{
+			Unmatch:        or(src.Unmatch, rc.Unmatch),
+			Patterns:       src.Patterns,
+			IgnorePatterns: orSlice(src.IgnorePatterns, rc.IgnorePatterns),
+			IgnoredEvents:  or(src.IgnoredEvents, rc.IgnoredEvents),
+			OpenAPISpecs:   src.OpenAPISpecs,
+			WarnUnmatched:  src.WarnUnmatched || rc.WarnUnmatched,
+		}
+		// the patterns and OpenAPI specs of a service replace, together, the global patterns
+		if len(src.Patterns) == 0 && len(src.OpenAPISpecs) == 0 {
+			merged.Patterns, merged.OpenAPISpecs = rc.Patterns, rc.OpenAPISpecs
+		}
+		rules, err := newRouteRules(&merged, learner)
+		if err != nil {
+			return nil, fmt.Errorf("routes.services[%d]: %w", i, err)
+		}
+		sr.services[key] = rules
+		sr.servicesConfig[key] = &merged
+	}
+	return sr, nil
+}
+
+// RoutesProvider returns the routes decorator. The routes that it learns from the observed traffic
+// are listed by the admin HTTP API, if enabled.
+func RoutesProvider(ctxInfo *global.ContextInfo) stage.MiddleProvider[*RoutesConfig, []request.Span, []request.Span] {
+	return func(rc *RoutesConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+		return routesProvider(ctxInfo, rc)
+	}
+}
+
+func routesProvider(ctxInfo *global.ContextInfo, rc *RoutesConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	routes, err := newServiceRoutes(rc)
+	if err != nil {
+		return nil, err
+	}
+	ctxInfo.AdminRegistry.LearnedRoutes(routes.learner.Patterns)
 
 	return func(in <-chan []request.Span, out chan<- []request.Span) {
-		for spans := range in {
-			filtered := make([]request.Span, 0, len(spans))
-			for i := range spans {
-				s := &spans[i]
-				if ignoreEnabled {
-					if discarder.Find(s.Path) != "" {
-						if ignoreMode == IgnoreAll {
-							continue
-						}
-						// we can't discard it here, ignoring is selective (metrics | traces)
-						setSpanIgnoreMode(ignoreMode, s)
+		var report <-chan time.Time
+		if rc.Adaptive.ReportInterval > 0 {
+			ticker := time.NewTicker(rc.Adaptive.ReportInterval)
+			defer ticker.Stop()
+			report = ticker.C
+		}
+		for {
+			select {
+			case spans, ok := <-in:
+				if !ok {
+					return
+				}
+				filtered := make([]request.Span, 0, len(spans))
+				for i := range spans {
+					s := &spans[i]
+					if routes.forSpan(s).apply(s) {
+						filtered = append(filtered, *s)
 					}
 				}
-				if routesEnabled {
-					s.Route = matcher.Find(s.Path)
+				if len(filtered) > 0 {
+					out <- filtered
+				}
+			case <-report:
+				for _, learned := range routes.learner.Patterns() {
+					slog.With("component", "RoutesProvider").
+						Info("learned route patterns", "service", learned.Service, "kind", learned.Kind,
+							"patterns", learned.Patterns)
 				}
-				unmatchAction(s)
-				filtered = append(filtered, *s)
-			}
-			if len(filtered) > 0 {
-				out <- filtered
 			}
 		}
 	}, nil
 }
 
-func chooseUnmatchPolicy(rc *RoutesConfig) (func(span *request.Span), error) {
+func chooseUnmatchPolicy(unmatch UnmatchType, learner *route.Learner) (func(span *request.Span), error) {
 	var unmatchAction func(span *request.Span)
 
-	switch rc.Unmatch {
+	switch unmatch {
 	case UnmatchWildcard, "":
 		unmatchAction = setUnmatchToWildcard
-
-		if len(rc.Patterns) == 0 {
-			slog.With("component", "RoutesProvider").
-				Warn("No route match patterns configured. " +
-					"Without route definitions Beyla will not be able to generate a low cardinality " +
-					"route for trace span names. For optimal experience, please define your application " +
-					"HTTP route patterns or enable the route 'heuristic' mode. " +
-					"For more information please see the documentation at: " +
-					"https://grafana.com/docs/beyla/latest/configure/options/#routes-decorator . " +
-					"If your application is only using gRPC you can ignore this warning.")
-		}
 	case UnmatchUnset:
 		unmatchAction = leaveUnmatchEmpty
 	case UnmatchPath:
@@ -121,16 +374,57 @@ func chooseUnmatchPolicy(rc *RoutesConfig) (func(span *request.Span), error) {
 			return nil, err
 		}
 		unmatchAction = classifyFromPath
+	case UnmatchAdaptive:
+		unmatchAction = learnFromPath(learner)
 	default:
 		slog.With("component", "RoutesProvider").
 			Warn("invalid 'unmatch' value in configuration, defaulting to '"+string(UnmatchDefault)+"'",
-				"value", rc.Unmatch)
+				"value", unmatch)
 		unmatchAction = setUnmatchToWildcard
 	}
 
 	return unmatchAction, nil
 }
 
+// maxUnmatchedWarnings limits the number of different paths that are remembered, to avoid
+// logging the same unmatched path again and again
+const maxUnmatchedWarnings = 1024
+
+type unmatchedWarner struct {
+	log    *slog.Logger
+	warned *lru.Cache[string, struct{}]
+}
+
+func newUnmatchedWarner() *unmatchedWarner {
+	warned, _ := lru.New[string, struct{}](maxUnmatchedWarnings)
+	return &unmatchedWarner{
+		log:    slog.With("component", "RoutesProvider"),
+		warned: warned,
+	}
+}
+
+func (uw *unmatchedWarner) warn(s *request.Span) {
+	if ok, _ := uw.warned.ContainsOrAdd(s.Path, struct{}{}); ok {
+		return
+	}
+	uw.log.Warn("HTTP path does not match any route pattern",
+		"path", s.Path, "method", s.Method, "service", s.ServiceID.String())
+}
+
+func or[T ~string](value, fallback T) T {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func orSlice(value, fallback []string) []string {
+	if len(value) == 0 {
+		return fallback
+	}
+	return value
+}
+
 func leaveUnmatchEmpty(_ *request.Span) {}
 
 func setUnmatchToWildcard(str *request.Span) {
@@ -151,6 +445,20 @@ func classifyFromPath(s *request.Span) {
 	}
 }
 
+func learnFromPath(learner *route.Learner) func(s *request.Span) {
+	return func(s *request.Span) {
+		if s.Route == "" && (s.Type == request.EventTypeHTTP || s.Type == request.EventTypeHTTPClient) {
+			kind := route.KindServer
+			if s.Type == request.EventTypeHTTPClient {
+				kind = route.KindClient
+			}
+			s.Route = learner.Learn(s.ServiceID.String(), kind, s.Path)
+			// the maximum number of learned routes for the service has been reached
+			setUnmatchToWildcard(s)
+		}
+	}
+}
+
 func setSpanIgnoreMode(mode IgnoreMode, s *request.Span) {
 	switch mode {
 	case IgnoreMetrics:
@@ -1,14 +1,19 @@
 package transform
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/beyla/pkg/internal/admin"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 	"github.com/grafana/beyla/pkg/internal/testutil"
+	"github.com/grafana/beyla/pkg/internal/transform/route"
 )
 
 const testTimeout = 5 * time.Second
@@ -16,7 +21,7 @@ const testTimeout = 5 * time.Second
 func TestUnmatchedWildcard(t *testing.T) {
 	for _, tc := range []UnmatchType{"", UnmatchWildcard, "invalid_value"} {
 		t.Run(string(tc), func(t *testing.T) {
-			router, err := RoutesProvider(&RoutesConfig{Unmatch: tc, Patterns: []string{"/user/:id"}})
+			router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: tc, Patterns: []string{"/user/:id"}})
 			require.NoError(t, err)
 			in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
 			defer close(in)
@@ -36,7 +41,7 @@ func TestUnmatchedWildcard(t *testing.T) {
 }
 
 func TestUnmatchedPath(t *testing.T) {
-	router, err := RoutesProvider(&RoutesConfig{Unmatch: UnmatchPath, Patterns: []string{"/user/:id"}})
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: UnmatchPath, Patterns: []string{"/user/:id"}})
 	require.NoError(t, err)
 	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
 	defer close(in)
@@ -54,7 +59,7 @@ func TestUnmatchedPath(t *testing.T) {
 }
 
 func TestUnmatchedEmpty(t *testing.T) {
-	router, err := RoutesProvider(&RoutesConfig{Unmatch: UnmatchUnset, Patterns: []string{"/user/:id"}})
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: UnmatchUnset, Patterns: []string{"/user/:id"}})
 	require.NoError(t, err)
 	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
 	defer close(in)
@@ -73,7 +78,7 @@ func TestUnmatchedEmpty(t *testing.T) {
 func TestUnmatchedAuto(t *testing.T) {
 	for _, tc := range []UnmatchType{UnmatchHeuristic} {
 		t.Run(string(tc), func(t *testing.T) {
-			router, err := RoutesProvider(&RoutesConfig{Unmatch: tc, Patterns: []string{"/user/:id"}})
+			router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: tc, Patterns: []string{"/user/:id"}})
 			require.NoError(t, err)
 			in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
 			defer close(in)
@@ -106,7 +111,7 @@ func TestUnmatchedAuto(t *testing.T) {
 }
 
 func TestIgnoreRoutes(t *testing.T) {
-	router, err := RoutesProvider(&RoutesConfig{Unmatch: UnmatchPath, Patterns: []string{"/user/:id", "/v1/metrics"}, IgnorePatterns: []string{"/v1/metrics/*", "/v1/traces/*", "/exact"}})
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: UnmatchPath, Patterns: []string{"/user/:id", "/v1/metrics"}, IgnorePatterns: []string{"/v1/metrics/*", "/v1/traces/*", "/exact"}})
 	require.NoError(t, err)
 	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
 	defer close(in)
@@ -145,7 +150,7 @@ func BenchmarkRoutesProvider_Heuristic(b *testing.B) {
 }
 
 func benchProvider(b *testing.B, unmatch UnmatchType) {
-	router, err := RoutesProvider(&RoutesConfig{Unmatch: unmatch, Patterns: []string{
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Unmatch: unmatch, Patterns: []string{
 		"/users/{id}",
 		"/users/{id}/product/{pid}",
 	}})
@@ -167,3 +172,179 @@ func benchProvider(b *testing.B, unmatch UnmatchType) {
 		<-outCh
 	}
 }
+
+func TestServiceRoutes(t *testing.T) {
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{
+		Unmatch:  UnmatchPath,
+		Patterns: []string{"/user/:id"},
+		Services: []ServiceRoutesConfig{{
+			Name:           "orders",
+			Patterns:       []string{"/order/:id"},
+			IgnorePatterns: []string{"/health"},
+		}, {
+			Name:      "orders",
+			Namespace: "legacy",
+			Unmatch:   UnmatchWildcard,
+			Patterns:  []string{"/orders/:id/details"},
+		}},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+
+	in <- []request.Span{
+		{Path: "/user/1234"},
+		{Path: "/order/1234"},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "orders"}},
+		{Path: "/order/1234", ServiceID: svc.ID{Name: "orders", Namespace: "prod"}},
+		{Path: "/health", ServiceID: svc.ID{Name: "orders"}},
+		{Path: "/health", ServiceID: svc.ID{Name: "users"}},
+		{Path: "/orders/1234/details", ServiceID: svc.ID{Name: "orders", Namespace: "legacy"}},
+		{Path: "/order/1234", ServiceID: svc.ID{Name: "orders", Namespace: "legacy"}},
+	}
+	assert.Equal(t, []request.Span{
+		{Path: "/user/1234", Route: "/user/:id"},
+		{Path: "/order/1234", Route: "/order/1234"},
+		{Path: "/user/1234", Route: "/user/1234", ServiceID: svc.ID{Name: "orders"}},
+		{Path: "/order/1234", Route: "/order/:id", ServiceID: svc.ID{Name: "orders", Namespace: "prod"}},
+		{Path: "/health", Route: "/health", ServiceID: svc.ID{Name: "users"}},
+		{Path: "/orders/1234/details", Route: "/orders/:id/details", ServiceID: svc.ID{Name: "orders", Namespace: "legacy"}},
+		{Path: "/order/1234", Route: "/**", ServiceID: svc.ID{Name: "orders", Namespace: "legacy"}},
+	}, testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestServiceRoutes_Hints(t *testing.T) {
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{
+		Unmatch:  UnmatchPath,
+		Patterns: []string{"/user/:id"},
+		Services: []ServiceRoutesConfig{{
+			Name:           "orders",
+			IgnorePatterns: []string{"/health"},
+		}},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+
+	itemsHints := &svc.RouteHints{Patterns: []string{"/item/:id"}}
+	ordersHints := &svc.RouteHints{Patterns: []string{"/order/:id"}}
+	in <- []request.Span{
+		{Path: "/item/1234", ServiceID: svc.ID{Name: "items", RouteHints: itemsHints}},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "items", RouteHints: itemsHints}},
+		{Path: "/order/1234", ServiceID: svc.ID{Name: "orders", RouteHints: ordersHints}},
+		{Path: "/health", ServiceID: svc.ID{Name: "orders", RouteHints: ordersHints}},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "users"}},
+	}
+	assert.Equal(t, []request.Span{
+		{Path: "/item/1234", Route: "/item/:id", ServiceID: svc.ID{Name: "items", RouteHints: itemsHints}},
+		{Path: "/user/1234", Route: "/user/1234", ServiceID: svc.ID{Name: "items", RouteHints: itemsHints}},
+		{Path: "/order/1234", Route: "/order/:id", ServiceID: svc.ID{Name: "orders", RouteHints: ordersHints}},
+		{Path: "/user/1234", Route: "/user/:id", ServiceID: svc.ID{Name: "users"}},
+	}, testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestServiceRoutes_HintedRoutesAreBounded(t *testing.T) {
+	sr, err := newServiceRoutes(&RoutesConfig{Unmatch: UnmatchPath})
+	require.NoError(t, err)
+
+	hints := &svc.RouteHints{Patterns: []string{"/item/:id"}}
+	for i := 0; i < maxHintedRoutes+100; i++ {
+		span := request.Span{Path: "/item/1234", ServiceID: svc.ID{Name: fmt.Sprintf("pod-%d", i), RouteHints: hints}}
+		require.NotNil(t, sr.forSpan(&span))
+	}
+	assert.Equal(t, maxHintedRoutes, sr.hinted.Len())
+
+	// evicted rules are created again when the service is seen again
+	span := request.Span{Path: "/item/1234", ServiceID: svc.ID{Name: "pod-0", RouteHints: hints}}
+	assert.Equal(t, "/item/:id", sr.forSpan(&span).matcher.Find(span.Path))
+}
+
+func TestServiceRoutes_Errors(t *testing.T) {
+	_, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Services: []ServiceRoutesConfig{{Patterns: []string{"/foo"}}}})
+	assert.Error(t, err)
+	_, err = RoutesProvider(&global.ContextInfo{})(&RoutesConfig{Services: []ServiceRoutesConfig{
+		{Name: "foo", Patterns: []string{"/foo"}},
+		{Name: "foo", Patterns: []string{"/bar"}},
+	}})
+	assert.Error(t, err)
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	router, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{
+		Unmatch:       UnmatchPath,
+		Patterns:      []string{"/health"},
+		WarnUnmatched: true,
+		Services: []ServiceRoutesConfig{{
+			Name:         "orders",
+			OpenAPISpecs: []string{"route/testdata/openapi.yaml"},
+		}},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+
+	orders := svc.ID{Name: "orders"}
+	in <- []request.Span{
+		{Type: request.EventTypeHTTP, Path: "/api/v1/orders/123", ServiceID: orders},
+		{Type: request.EventTypeHTTP, Path: "/api/v1/undocumented", ServiceID: orders},
+		{Type: request.EventTypeHTTP, Path: "/health"},
+	}
+	assert.Equal(t, []request.Span{
+		{Type: request.EventTypeHTTP, Path: "/api/v1/orders/123", Route: "/api/v1/orders/{id}", ServiceID: orders},
+		{Type: request.EventTypeHTTP, Path: "/api/v1/undocumented", Route: "/api/v1/undocumented", ServiceID: orders},
+		{Type: request.EventTypeHTTP, Path: "/health", Route: "/health"},
+	}, testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestOpenAPIRoutes_Error(t *testing.T) {
+	_, err := RoutesProvider(&global.ContextInfo{})(&RoutesConfig{OpenAPISpecs: []string{"route/testdata/unexisting.yaml"}})
+	assert.Error(t, err)
+}
+
+func TestUnmatchedAdaptive(t *testing.T) {
+	registry := admin.NewRegistry(nil)
+	router, err := RoutesProvider(&global.ContextInfo{AdminRegistry: registry})(&RoutesConfig{
+		Unmatch:  UnmatchAdaptive,
+		Patterns: []string{"/user/:id"},
+		Adaptive: AdaptiveRoutesConfig{MaxSegmentCardinality: 2, MaxRoutes: 3},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+
+	frontend := svc.ID{Name: "frontend"}
+	in <- []request.Span{
+		{Type: request.EventTypeHTTP, Path: "/user/1234", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/intro", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/setup", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/faq", ServiceID: frontend},
+		{Type: request.EventTypeHTTPClient, Path: "/docs/intro", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/a", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/b", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/c", ServiceID: frontend},
+		{Type: request.EventTypeGRPC, Path: "/foo.Bar/Baz", ServiceID: frontend},
+	}
+	assert.Equal(t, []request.Span{
+		{Type: request.EventTypeHTTP, Path: "/user/1234", Route: "/user/:id", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/intro", Route: "/docs/intro", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/setup", Route: "/docs/setup", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/docs/faq", Route: "/docs/*", ServiceID: frontend},
+		// the routes of the client spans are learned separately
+		{Type: request.EventTypeHTTPClient, Path: "/docs/intro", Route: "/docs/intro", ServiceID: frontend},
+		// the first path folder is never collapsed
+		{Type: request.EventTypeHTTP, Path: "/a", Route: "/a", ServiceID: frontend},
+		{Type: request.EventTypeHTTP, Path: "/b", Route: "/b", ServiceID: frontend},
+		// the service reached the maximum number of routes
+		{Type: request.EventTypeHTTP, Path: "/c", Route: "/**", ServiceID: frontend},
+		{Type: request.EventTypeGRPC, Path: "/foo.Bar/Baz", ServiceID: frontend},
+	}, testutil.ReadChannel(t, out, testTimeout))
+
+	assert.Equal(t, []route.LearnedRoutes{
+		{Service: "frontend", Kind: route.KindClient, Patterns: []string{"/docs/intro"}},
+		{Service: "frontend", Kind: route.KindServer, Patterns: []string{"/a", "/b", "/docs/*"}},
+	}, registry.Routes().Learned)
+}
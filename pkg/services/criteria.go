@@ -20,6 +20,11 @@ const (
 	// AttrOwnerName would be a generic search criteria that would
 	// match against deployment, replicaset, daemonset and statefulset names
 	AttrOwnerName = "k8s_owner_name"
+
+	// AttrContainerName and AttrContainerImage match against the metadata of the
+	// Docker container where the process runs
+	AttrContainerName  = "container_name"
+	AttrContainerImage = "container_image"
 )
 
 // any attribute name not in this set will cause an error during the YAML unmarshalling
@@ -31,6 +36,8 @@ var allowedAttributeNames = map[string]struct{}{
 	AttrDaemonSetName:   {},
 	AttrStatefulSetName: {},
 	AttrOwnerName:       {},
+	AttrContainerName:   {},
+	AttrContainerImage:  {},
 }
 
 // ProcessInfo stores some relevant information about a running process
@@ -39,6 +46,16 @@ type ProcessInfo struct {
 	PPid      int32
 	ExePath   string
 	OpenPorts []uint32
+	// CmdLine contains the command-line arguments of the process, separated by spaces
+	CmdLine string
+	// User name of the real user that runs the process
+	User string
+	// UID of the real user that runs the process
+	UID int32
+	// ParentExe is the executable path of the parent process
+	ParentExe string
+	// SystemdUnit is the name of the systemd service unit containing the process (e.g. nginx.service)
+	SystemdUnit string
 }
 
 // DiscoveryConfig for the discover.ProcessFinder pipeline
@@ -47,10 +64,18 @@ type DiscoveryConfig struct {
 	// added to the services definition criteria, with the lowest preference.
 	Services DefinitionCriteria `yaml:"services"`
 
+	// ExcludeServices defines the services that won't be instrumented, even if they match any of the
+	// Services selection criteria, or if SystemWide instrumentation is enabled.
+	ExcludeServices DefinitionCriteria `yaml:"exclude_services"`
+
 	// PollInterval specifies, for the poll service watcher, the interval time between
 	// process inspections
 	PollInterval time.Duration `yaml:"poll_interval" env:"BEYLA_DISCOVERY_POLL_INTERVAL"`
 
+	// ReconcileInterval specifies the interval time between process inspections when the
+	// process creation and deletion are notified by the eBPF process watcher.
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" env:"BEYLA_DISCOVERY_RECONCILE_INTERVAL"`
+
 	// SystemWide allows instrumentation of all HTTP (no gRPC) calls, incoming and outgoing at a system wide scale.
 	// No filtering per application will be done. Using this option may result in reduced quality of information
 	// gathered for certain languages, such as Golang.
@@ -61,26 +86,78 @@ type DiscoveryConfig struct {
 
 	// Debugging only option. Make sure the kernel side doesn't filter any PIDs, force user space filtering.
 	BPFPidFilterOff bool `yaml:"bpf_pid_filter_off" env:"BEYLA_BPF_PID_FILTER_OFF"`
+
+	// K8sAnnotations enables the selection and naming of the services from the beyla.grafana.com/*
+	// annotations and labels of their Pods. It requires the Kubernetes decoration to be enabled.
+	K8sAnnotations bool `yaml:"k8s_annotations" env:"BEYLA_DISCOVERY_K8S_ANNOTATIONS"`
+
+	// SystemdServiceName uses the name of the systemd service unit of the processes (without the .service
+	// suffix) as their service name, when it is not explicitly defined.
+	SystemdServiceName bool `yaml:"systemd_service_name" env:"BEYLA_SYSTEMD_SERVICE_NAME"`
+
+	// ServiceEnvPrecedence specifies the precedence of the OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// environment variables of the instrumented processes, when setting the service name and namespace.
+	// The environment of the processes is not inspected unless a precedence other than "disabled" is set.
+	ServiceEnvPrecedence EnvPrecedence `yaml:"service_env_precedence" env:"BEYLA_SERVICE_ENV_PRECEDENCE"`
 }
 
+// EnvPrecedence specifies the precedence of the service name and namespace defined in the environment
+// variables of the instrumented process, with respect to the service name and namespace defined in the
+// discovery configuration and the Kubernetes metadata.
+type EnvPrecedence string
+
+const (
+	// EnvPrecedenceHighest gives precedence to the environment over the configuration and the Kubernetes metadata
+	EnvPrecedenceHighest = EnvPrecedence("highest")
+	// EnvPrecedenceOverKubernetes gives precedence to the configuration over the environment,
+	// and to the environment over the Kubernetes metadata
+	EnvPrecedenceOverKubernetes = EnvPrecedence("over_kubernetes")
+	// EnvPrecedenceLowest gives precedence to the configuration and the Kubernetes metadata over the environment
+	EnvPrecedenceLowest = EnvPrecedence("lowest")
+	// EnvPrecedenceDisabled won't inspect the environment of the instrumented processes
+	EnvPrecedenceDisabled = EnvPrecedence("disabled")
+)
+
 // DefinitionCriteria allows defining a group of services to be instrumented according to a set
 // of attributes. If a given executable/service matches multiple of the attributes, the
 // earliest defined service will take precedence.
 type DefinitionCriteria []Attributes
 
+// Validate the services selection and exclusion criteria, as well as other discovery properties
+func (d *DiscoveryConfig) Validate() error {
+	if err := d.Services.validate("discovery.services"); err != nil {
+		return err
+	}
+	if err := d.ExcludeServices.validate("discovery.exclude_services"); err != nil {
+		return err
+	}
+	switch d.ServiceEnvPrecedence {
+	case "", EnvPrecedenceHighest, EnvPrecedenceOverKubernetes, EnvPrecedenceLowest, EnvPrecedenceDisabled:
+		return nil
+	default:
+		return fmt.Errorf("invalid discovery.service_env_precedence value: %q. Accepted values: %s, %s, %s, %s",
+			d.ServiceEnvPrecedence, EnvPrecedenceHighest, EnvPrecedenceOverKubernetes, EnvPrecedenceLowest, EnvPrecedenceDisabled)
+	}
+}
+
 func (dc DefinitionCriteria) Validate() error {
+	return dc.validate("discovery.services")
+}
+
+func (dc DefinitionCriteria) validate(property string) error {
 	// an empty definition criteria is valid
 	for i := range dc {
 		if dc[i].OpenPorts.Len() == 0 &&
 			!dc[i].Path.IsSet() &&
 			!dc[i].PathRegexp.IsSet() &&
+			!dc[i].HasProcessAttributes() &&
 			len(dc[i].Metadata) == 0 &&
 			len(dc[i].PodLabels) == 0 {
-			return fmt.Errorf("discovery.services[%d] should define at least one selection criteria", i)
+			return fmt.Errorf("%s[%d] should define at least one selection criteria", property, i)
 		}
 		for k := range dc[i].Metadata {
 			if _, ok := allowedAttributeNames[k]; !ok {
-				return fmt.Errorf("unknown attribute in discovery.services[%d]: %s", i, k)
+				return fmt.Errorf("unknown attribute in %s[%d]: %s", property, i, k)
 			}
 		}
 	}
@@ -105,6 +182,9 @@ type Attributes struct {
 	Name string `yaml:"name"`
 	// Namespace will define a namespace for the matching service. If unset, it will be left empty.
 	Namespace string `yaml:"namespace"`
+	// ExporterProfile is the name of the OTEL exporter profile that overrides the endpoint and headers
+	// of the metrics and traces of the matching service. If unset, the default exporters are used.
+	ExporterProfile string `yaml:"exporter_profile"`
 	// OpenPorts allows defining a group of ports that this service could open. It accepts a comma-separated
 	// list of port numbers (e.g. 80) and port ranges (e.g. 8080-8089)
 	OpenPorts PortEnum `yaml:"open_ports"`
@@ -119,6 +199,26 @@ type Attributes struct {
 
 	// PodLabels allows matching against the labels of a pod
 	PodLabels map[string]*RegexpAttr `yaml:"k8s_pod_labels"`
+
+	// CmdArgs allows defining the regular expression matching the command-line arguments of the
+	// process, separated by spaces (e.g. "java -jar /app/orders.jar")
+	CmdArgs RegexpAttr `yaml:"cmd_args"`
+	// User allows defining the regular expression matching the name of the user running the process
+	User RegexpAttr `yaml:"user"`
+	// UID allows selecting the processes run by the user with the given numeric ID
+	UID *int32 `yaml:"uid"`
+	// ParentExe allows defining the regular expression matching the executable path of the parent process
+	ParentExe RegexpAttr `yaml:"parent_exe"`
+	// SystemdUnit allows defining the regular expression matching the systemd service unit
+	// of the process (e.g. nginx.service)
+	SystemdUnit RegexpAttr `yaml:"systemd_unit"`
+}
+
+// HasProcessAttributes returns whether the selection criteria defines any of the
+// process attributes that complement the executable path and the open ports:
+// command-line arguments, user, parent executable and systemd unit.
+func (a *Attributes) HasProcessAttributes() bool {
+	return a.CmdArgs.IsSet() || a.User.IsSet() || a.UID != nil || a.ParentExe.IsSet() || a.SystemdUnit.IsSet()
 }
 
 // PortEnum defines an enumeration of ports. It allows defining a set of single ports as well a set of
@@ -155,6 +255,11 @@ func (p *PortEnum) UnmarshalYAML(value *yaml.Node) error {
 
 func (p *PortEnum) UnmarshalText(text []byte) error {
 	val := string(text)
+	// an empty enumeration doesn't contain any port
+	if strings.TrimSpace(val) == "" {
+		p.Ranges = nil
+		return nil
+	}
 	if !validPortEnum.MatchString(val) {
 		return fmt.Errorf("invalid port range %q. Must be a comma-separated list of numeric ports or port ranges (e.g. 8000-8999)", val)
 	}
@@ -171,6 +276,19 @@ func (p *PortEnum) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalYAML returns the text representation of the ports enumeration (e.g. 80,443,8000-8999)
+func (p PortEnum) MarshalYAML() (any, error) {
+	entries := make([]string, 0, len(p.Ranges))
+	for _, pr := range p.Ranges {
+		if pr.End == 0 {
+			entries = append(entries, strconv.Itoa(pr.Start))
+		} else {
+			entries = append(entries, fmt.Sprintf("%d-%d", pr.Start, pr.End))
+		}
+	}
+	return strings.Join(entries, ","), nil
+}
+
 func (p *PortEnum) Matches(port int) bool {
 	for _, pr := range p.Ranges {
 		if pr.End == 0 && pr.Start == port ||
@@ -223,6 +341,14 @@ func (p *RegexpAttr) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalYAML returns the text representation of the regular expression
+func (p RegexpAttr) MarshalYAML() (any, error) {
+	if p.re == nil {
+		return "", nil
+	}
+	return p.re.String(), nil
+}
+
 func (p *RegexpAttr) MatchString(input string) bool {
 	// no regexp means "empty regexp", so anything will match it
 	if p.re == nil {
@@ -98,6 +98,20 @@ func TestYAMLParse_PortEnum(t *testing.T) {
 		require.False(t, pe.Matches(1000))
 		require.False(t, pe.Matches(15000))
 	})
+	t.Run("empty", func(t *testing.T) {
+		pe := portEnumYAML(`""`)
+		require.Zero(t, pe.Len())
+	})
+}
+
+func TestYAMLMarshal_PortEnumAndRegexp(t *testing.T) {
+	yf := yamlFile{}
+	require.NoError(t, yaml.Unmarshal([]byte("services:\n  - open_ports: 80, 8000-8999\n    exe_path: ^/usr/bin/\n"), &yf))
+	out, err := yaml.Marshal(yf.Services[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "open_ports: 80,8000-8999\n")
+	assert.Contains(t, string(out), "exe_path: ^/usr/bin/\n")
+	assert.Contains(t, string(out), "cmd_args: \"\"\n")
 }
 
 func TestYAMLParse_PortEnum_Errors(t *testing.T) {